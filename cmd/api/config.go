@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/pkg/password"
 )
 
 func getConfigFromFlags() application.Config {
@@ -49,14 +50,75 @@ func getConfigFromFlags() application.Config {
 	flag.StringVar(&cfg.Sentry.DSN, "sentry-dsn", os.Getenv("SENTRY_DSN"), "DSN for Sentry integrations")
 
 	// JWT
+	flag.StringVar(&cfg.JWT.KeyringFile, "jwt-keyring-file", os.Getenv("JWT_KEYRING_FILE"), "JSON file listing the active and retired keys of access and refresh JWT, overrides the single key flags")
 	flag.StringVar(&cfg.JWT.Access.Secret, "jwt-access-secret", os.Getenv("JWT_ACCESS_SECRET"), "Secret key using to secure access JWT")
+	flag.StringVar(&cfg.JWT.Access.KeyFile, "jwt-access-key-file", os.Getenv("JWT_ACCESS_KEY_FILE"), "PEM RSA or Ed25519 private key file using to sign access JWT instead of the secret")
+	flag.StringVar(&cfg.JWT.Access.KeyID, "jwt-access-key-id", os.Getenv("JWT_ACCESS_KEY_ID"), "Identifier (kid) of the key using to sign access JWT")
 	flag.StringVar(&cfg.JWT.Access.Expiration, "jwt-access-expiration-time", "15m", "Validity time of access JWT")
 	flag.StringVar(&cfg.JWT.Refresh.Secret, "jwt-refresh-secret", os.Getenv("JWT_REFRESH_SECRET"), "Secret key using to secure refresh JWT")
+	flag.StringVar(&cfg.JWT.Refresh.KeyFile, "jwt-refresh-key-file", os.Getenv("JWT_REFRESH_KEY_FILE"), "PEM RSA or Ed25519 private key file using to sign refresh JWT instead of the secret")
+	flag.StringVar(&cfg.JWT.Refresh.KeyID, "jwt-refresh-key-id", os.Getenv("JWT_REFRESH_KEY_ID"), "Identifier (kid) of the key using to sign refresh JWT")
 	flag.StringVar(&cfg.JWT.Refresh.Expiration, "jwt-refresh-expiration-time", "168h", "Validity time of refresh JWT")
 
+	// Two-factor authentication
+	flag.StringVar(&cfg.MFA.Issuer, "mfa-issuer", "golang-base-api", "Issuer name displayed by authenticator applications")
+	flag.StringVar(&cfg.MFA.ChallengeExpiration, "mfa-challenge-expiration-time", "5m", "Validity time of the 2FA challenge returned by login")
+
+	// Password hashing
+	flag.StringVar(&cfg.Password.Algorithm, "password-algorithm", "argon2id", "Algorithm hashing new passwords (argon2id|bcrypt), hashes of another algorithm are upgraded on login")
+	flag.IntVar(&cfg.Password.Argon2id.Memory, "password-argon2id-memory", int(password.DefaultArgon2id.Memory), "Memory used by argon2id, in KiB")
+	flag.IntVar(&cfg.Password.Argon2id.Iterations, "password-argon2id-iterations", int(password.DefaultArgon2id.Iterations), "Number of passes of argon2id over the memory")
+	flag.IntVar(&cfg.Password.Argon2id.Parallelism, "password-argon2id-parallelism", int(password.DefaultArgon2id.Parallelism), "Number of threads used by argon2id")
+	flag.IntVar(&cfg.Password.BcryptCost, "password-bcrypt-cost", 14, "Cost of bcrypt")
+	flag.BoolVar(&cfg.Password.BreachCheck, "password-breach-check", true, "Refuse common passwords, and the passwords of the breached file, as new passwords")
+	flag.StringVar(&cfg.Password.BreachedFile, "password-breached-file", os.Getenv("PASSWORD_BREACHED_FILE"), "File of breached password SHA-1 hashes sorted by hash, like the Have I Been Pwned dump")
+
+	// Mailer
+	flag.StringVar(&cfg.Mailer.From, "mailer-from", os.Getenv("MAILER_FROM"), "Sender address of emails")
+	flag.StringVar(&cfg.Mailer.OutboxFile, "mailer-outbox-file", os.Getenv("MAILER_OUTBOX_FILE"), "File queuing emails as JSON lines, emails are kept in memory if empty, required in prod")
+
+	// Password reset
+	flag.StringVar(&cfg.PasswordReset.Expiration, "password-reset-expiration-time", "30m", "Validity time of password reset tokens")
+	flag.StringVar(&cfg.PasswordReset.URL, "password-reset-url", os.Getenv("PASSWORD_RESET_URL"), "Front-end page receiving the reset token in the \"token\" query parameter")
+
+	// Email verification
+	var emailVerificationMode string
+	flag.StringVar(&emailVerificationMode, "email-verification-mode", "optional", "What unverified accounts can do (optional|restricted|required)")
+	flag.StringVar(&cfg.EmailVerification.Expiration, "email-verification-expiration-time", "48h", "Validity time of email verification tokens")
+	flag.StringVar(&cfg.EmailVerification.URL, "email-verification-url", os.Getenv("EMAIL_VERIFICATION_URL"), "Front-end page receiving the verification token in the \"token\" query parameter")
+
+	// Email change
+	flag.StringVar(&cfg.EmailChange.Expiration, "email-change-expiration-time", "24h", "Validity time of email change confirmation tokens")
+	flag.StringVar(&cfg.EmailChange.URL, "email-change-url", os.Getenv("EMAIL_CHANGE_URL"), "Front-end page receiving the email change token in the \"token\" query parameter")
+
+	// Account deletion
+	flag.StringVar(&cfg.AccountDeletion.GracePeriod, "account-deletion-grace-period", "720h", "Time between a deletion request and the deletion of the account, it can be reactivated meanwhile")
+	flag.StringVar(&cfg.AccountDeletion.PurgeInterval, "account-deletion-purge-interval", "1h", "Interval between two purges of the accounts whose deletion grace period has passed")
+
+	// GraphQL limits
+	flag.IntVar(&cfg.GraphQL.MaxDepth, "graphql-max-depth", 13, "Maximum depth of the fields of a GraphQL query, introspection fields included, 0 disables the limit")
+	flag.IntVar(&cfg.GraphQL.MaxComplexity, "graphql-max-complexity", 5000, "Maximum cost of a GraphQL query, each field costs 1 and lists multiply the cost of their fields by their limit, 0 disables the limit")
+	flag.IntVar(&cfg.GraphQL.PersistedQueryCacheSize, "graphql-persisted-query-cache-size", 1000, "Number of automatic persisted queries kept in memory, the others are read from the database")
+	flag.IntVar(&cfg.GraphQL.PersistedQueryLimit, "graphql-persisted-query-limit", 10000, "Maximum number of automatic persisted queries stored in the database, the queries sent once it's reached are run without being stored")
+	flag.StringVar(&cfg.GraphQL.AllowlistFile, "graphql-allowlist-file", os.Getenv("GRAPHQL_ALLOWLIST_FILE"), "JSON file of the allowed queries by SHA-256 hash, required in prod where only these queries are run")
+
+	// Login lockout
+	flag.IntVar(&cfg.Lockout.AccountThreshold, "lockout-account-threshold", 5, "Failed logins of an account locking its logins, 0 disables the lock")
+	flag.IntVar(&cfg.Lockout.IPThreshold, "lockout-ip-threshold", 20, "Failed logins from an IP address locking its logins, 0 disables the lock")
+	flag.StringVar(&cfg.Lockout.Window, "lockout-window", "24h", "Period over which failed logins are counted")
+	flag.StringVar(&cfg.Lockout.BaseDelay, "lockout-base-delay", "1m", "Duration of the first lock, doubled by each new failed login")
+	flag.StringVar(&cfg.Lockout.MaxDelay, "lockout-max-delay", "1h", "Maximum duration of a lock")
+
 	flag.Parse()
 
 	cfg.CORS.TrustedOrigins = strings.Fields(trustedOrigins)
 
+	switch mode := application.EmailVerificationMode(emailVerificationMode); mode {
+	case application.EmailVerificationOptional, application.EmailVerificationRestricted, application.EmailVerificationRequired:
+		cfg.EmailVerification.Mode = mode
+	default:
+		panic(fmt.Errorf("invalid email verification mode: %s", emailVerificationMode))
+	}
+
 	return cfg
 }
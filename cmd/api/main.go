@@ -40,6 +40,26 @@ func main() {
 		Logger: logger,
 	}
 
+	if err = app.LoadMailer(); err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	if err = app.LoadJWTKeys(); err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	if err = app.LoadPasswordHasher(); err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	if err = app.LoadBreachedPasswords(); err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	if err = app.LoadPersistedQueries(); err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
 	err = sentry.Init(sentry.ClientOptions{
 		Dsn:         app.Config.Sentry.DSN,
 		Environment: app.Config.Env,
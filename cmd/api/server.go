@@ -24,6 +24,17 @@ func serve(app *application.Application) error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	purgeInterval, err := time.ParseDuration(app.Config.AccountDeletion.PurgeInterval)
+	if err != nil {
+		return err
+	}
+
+	// Finish the account deletions in background, stopped with the server.
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+
+	go app.PurgeDeletedUserAccounts(purgeCtx, purgeInterval)
+
 	// Will be used to receive any errors returned by the graceful Shutdown() function.
 	shutdownError := make(chan error)
 
@@ -37,6 +48,8 @@ func serve(app *application.Application) error {
 			"signal": s.String(),
 		})
 
+		stopPurge()
+
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
@@ -49,7 +62,7 @@ func serve(app *application.Application) error {
 		"env":  app.Config.Env,
 	})
 
-	err := srv.ListenAndServe()
+	err = srv.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
@@ -2,12 +2,24 @@ package application
 
 import (
 	"github.com/brice-74/golang-base-api/pkg/jsonlog"
+	"github.com/brice-74/golang-base-api/pkg/mailer"
+	"github.com/brice-74/golang-base-api/pkg/password"
+	"github.com/brice-74/golang-base-api/pkg/validator"
 )
 
 type Application struct {
-	Config Config
-	Models Models
-	Logger jsonlog.Logger
+	Config  Config
+	Models  Models
+	Logger  jsonlog.Logger
+	Mailer  mailer.Mailer
+	JWTKeys JWTKeys
+	// Passwords hashes new passwords, see LoadPasswordHasher.
+	Passwords password.Hasher
+	// BreachedPasswords are refused as new passwords, nil disables the check.
+	BreachedPasswords validator.PasswordList
+	// PersistedQueries stores the queries sent by hash, nil disables them, see
+	// LoadPersistedQueries.
+	PersistedQueries *PersistedQueries
 }
 
 type Config struct {
@@ -30,16 +42,85 @@ type Config struct {
 	Sentry struct {
 		DSN string
 	}
+	MFA struct {
+		Issuer              string
+		ChallengeExpiration string
+	}
+	Password struct {
+		Algorithm string
+		Argon2id  struct {
+			Memory      int
+			Iterations  int
+			Parallelism int
+		}
+		BcryptCost   int
+		BreachCheck  bool
+		BreachedFile string
+	}
+	Mailer struct {
+		From       string
+		OutboxFile string
+	}
+	PasswordReset struct {
+		Expiration string
+		URL        string
+	}
+	EmailVerification struct {
+		Mode       EmailVerificationMode
+		Expiration string
+		URL        string
+	}
+	EmailChange struct {
+		Expiration string
+		URL        string
+	}
+	AccountDeletion struct {
+		GracePeriod   string
+		PurgeInterval string
+	}
+	GraphQL struct {
+		MaxDepth      int
+		MaxComplexity int
+		// PersistedQueryCacheSize is the number of persisted queries kept in memory.
+		PersistedQueryCacheSize int
+		// PersistedQueryLimit is the number of persisted queries stored in the database.
+		PersistedQueryLimit int
+		AllowlistFile       string
+	}
+	Lockout struct {
+		AccountThreshold int
+		IPThreshold      int
+		Window           string
+		BaseDelay        string
+		MaxDelay         string
+	}
 	JWT struct {
-		Access struct {
+		KeyringFile string
+		Access      struct {
 			Secret     string
+			KeyFile    string
+			KeyID      string
 			Expiration string
 		}
 		Refresh struct {
 			Secret     string
+			KeyFile    string
+			KeyID      string
 			Expiration string
 		}
 	}
 }
 
 type Envelope map[string]interface{}
+
+// EmailVerificationMode defines what unverified accounts can do.
+type EmailVerificationMode string
+
+const (
+	// EmailVerificationOptional lets unverified accounts use the whole API.
+	EmailVerificationOptional EmailVerificationMode = "optional"
+	// EmailVerificationRestricted lets unverified accounts log in, but refuses sensitive operations.
+	EmailVerificationRestricted EmailVerificationMode = "restricted"
+	// EmailVerificationRequired refuses the login of unverified accounts.
+	EmailVerificationRequired EmailVerificationMode = "required"
+)
@@ -31,6 +31,18 @@ type ClientCtx struct {
 	Agent   *Agent
 	User    *user.User
 	Session *user.Session
+	// AccessToken is set instead of Session when authenticated by a personal access token.
+	AccessToken *user.AccessToken
+}
+
+// HasScope checks if the client is allowed to perform operations of the scope,
+// sessions have every scope.
+func (c *ClientCtx) HasScope(scope user.Scope) bool {
+	if c.AccessToken == nil {
+		return true
+	}
+
+	return c.AccessToken.Scopes.Has(scope)
 }
 
 type Agent struct {
@@ -3,15 +3,18 @@ package application
 import (
 	"database/sql"
 
+	"github.com/brice-74/golang-base-api/internal/domains/persistedquery"
 	"github.com/brice-74/golang-base-api/internal/domains/user"
 )
 
 type Models struct {
-	User user.Model
+	User           user.Model
+	PersistedQuery persistedquery.Model
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		User: user.Model{DB: db},
+		User:           user.Model{DB: db},
+		PersistedQuery: persistedquery.Model{DB: db},
 	}
 }
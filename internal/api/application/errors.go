@@ -1,8 +1,19 @@
 package application
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+)
+
+// Codes returned with the error of an inactive client, telling the client why its
+// authentication is refused.
+const (
+	ErrCodeSessionExpired     = "SessionExpired"
+	ErrCodeSessionRevoked     = "SessionRevoked"
+	ErrCodeAccountDeactivated = "AccountDeactivated"
 )
 
 // ErrorResponse is a generic HTTP error helper.
@@ -65,6 +76,30 @@ func (app *Application) InvalidAuthenticationTokenResponse(w http.ResponseWriter
 	app.ErrorResponse(w, r, http.StatusUnauthorized, message)
 }
 
+// InactiveClientResponse returns a 401 error for an expired or revoked session and a 403 error
+// for a deactivated account, with the code of the reason.
+func (app *Application) InactiveClientResponse(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := http.StatusUnauthorized, ErrCodeSessionExpired
+	switch {
+	case errors.Is(err, user.ErrSessionRevoked):
+		code = ErrCodeSessionRevoked
+	case errors.Is(err, user.ErrDeactivatedUser):
+		status, code = http.StatusForbidden, ErrCodeAccountDeactivated
+	}
+
+	if status == http.StatusUnauthorized {
+		// Indicates to the client we expect a new bearer token.
+		w.Header().Set("Authorization", "Bearer")
+	}
+
+	env := Envelope{"error": err.Error(), "code": code}
+
+	if err := app.WriteJSON(w, status, env, nil); err != nil {
+		app.LogError(r, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 // AuthenticationRequiredResponse returns a 401 response to the client.
 func (app *Application) AuthenticationRequiredResponse(w http.ResponseWriter, r *http.Request, err error) {
 	message := "you must be authenticated to access this resource"
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/twinj/uuid"
 )
 
 type AuthError error
@@ -14,18 +15,34 @@ var (
 	InvalidToken AuthError = errors.New("Invalid token")
 )
 
+// Types of the single purpose tokens, set in the "typ" claim.
+const (
+	// MfaChallengeType proves that the password of a user with 2FA was checked.
+	MfaChallengeType = "mfa_challenge"
+	// EmailVerificationType proves that the user received an email at his address.
+	EmailVerificationType = "email_verification"
+	// EmailChangeType proves that the user received an email at his new address.
+	EmailChangeType = "email_change"
+)
+
 type JwtClaimKey string
 
 const (
 	UserIdClaim    JwtClaimKey = "user_id"
 	SessionIdClaim JwtClaimKey = "user_agent_id"
 	ExpireClaim    JwtClaimKey = "exp"
+	RefreshIdClaim JwtClaimKey = "jti"
+	TypeClaim      JwtClaimKey = "typ"
+	EmailClaim     JwtClaimKey = "email"
+	NewEmailClaim  JwtClaimKey = "new_email"
 )
 
 type TokensDetails struct {
 	AccessToken  string
 	RefreshToken string
 	RefreshExp   int64
+	// RefreshID is the single use identifier of the refresh token, to be stored with the session.
+	RefreshID string
 }
 
 func (app *Application) CreateTokens(userID string, userAgentID string) (*TokensDetails, error) {
@@ -41,13 +58,13 @@ func (app *Application) CreateTokens(userID string, userAgentID string) (*Tokens
 		return nil, err
 	}
 	td.RefreshExp = time.Now().Add(refreshDuration).Unix()
+	td.RefreshID = uuid.NewV4().String()
 
 	var atClaims = jwt.MapClaims{}
 	atClaims[string(SessionIdClaim)] = userAgentID
 	atClaims[string(UserIdClaim)] = userID
 	atClaims[string(ExpireClaim)] = time.Now().Add(accessDuration).Unix()
-	at := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
-	td.AccessToken, err = at.SignedString([]byte(app.Config.JWT.Access.Secret))
+	td.AccessToken, err = app.AccessKeys().Sign(atClaims)
 	if err != nil {
 		return nil, err
 	}
@@ -56,29 +73,94 @@ func (app *Application) CreateTokens(userID string, userAgentID string) (*Tokens
 	rtClaims[string(SessionIdClaim)] = userAgentID
 	rtClaims[string(UserIdClaim)] = userID
 	rtClaims[string(ExpireClaim)] = td.RefreshExp
-	rt := jwt.NewWithClaims(jwt.SigningMethodHS256, rtClaims)
-	td.RefreshToken, err = rt.SignedString([]byte(app.Config.JWT.Refresh.Secret))
+	rtClaims[string(RefreshIdClaim)] = td.RefreshID
+	td.RefreshToken, err = app.RefreshKeys().Sign(rtClaims)
 	if err != nil {
 		return nil, err
 	}
 	return td, nil
 }
 
-// Make sure that the token method conform to "SigningMethodHMAC" and is up to date
-func VerifyToken(bearer string, secret string) (*jwt.Token, error) {
-	token, err := jwt.Parse(string(bearer), func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || token.Method.Alg() != "HS256" {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Method.Alg())
-		}
-		return []byte(secret), nil
+// CreateMfaChallenge creates the short-lived token to exchange with a TOTP code, sessionID
+// is the session to reopen and is empty for a new session.
+func (app *Application) CreateMfaChallenge(userID string, sessionID string) (string, int64, error) {
+	return app.createTypedToken(MfaChallengeType, app.Config.MFA.ChallengeExpiration, jwt.MapClaims{
+		string(UserIdClaim):    userID,
+		string(SessionIdClaim): sessionID,
 	})
+}
+
+// VerifyMfaChallenge returns the claims of a valid 2FA challenge token.
+func (app *Application) VerifyMfaChallenge(bearer string) (map[JwtClaimKey]string, error) {
+	return app.verifyTypedToken(MfaChallengeType, bearer, []JwtClaimKey{UserIdClaim, SessionIdClaim})
+}
+
+// CreateEmailVerificationToken creates the token sent by email to verify the address of a user.
+func (app *Application) CreateEmailVerificationToken(userID string, email string) (string, int64, error) {
+	return app.createTypedToken(EmailVerificationType, app.Config.EmailVerification.Expiration, jwt.MapClaims{
+		string(UserIdClaim): userID,
+		string(EmailClaim):  email,
+	})
+}
+
+// VerifyEmailVerificationToken returns the claims of a valid email verification token.
+func (app *Application) VerifyEmailVerificationToken(bearer string) (map[JwtClaimKey]string, error) {
+	return app.verifyTypedToken(EmailVerificationType, bearer, []JwtClaimKey{UserIdClaim, EmailClaim})
+}
+
+// CreateEmailChangeToken creates the token sent to the new address of a user to confirm
+// the change of his email.
+func (app *Application) CreateEmailChangeToken(userID string, email string, newEmail string) (string, int64, error) {
+	return app.createTypedToken(EmailChangeType, app.Config.EmailChange.Expiration, jwt.MapClaims{
+		string(UserIdClaim):   userID,
+		string(EmailClaim):    email,
+		string(NewEmailClaim): newEmail,
+	})
+}
+
+// VerifyEmailChangeToken returns the claims of a valid email change token.
+func (app *Application) VerifyEmailChangeToken(bearer string) (map[JwtClaimKey]string, error) {
+	return app.verifyTypedToken(EmailChangeType, bearer, []JwtClaimKey{UserIdClaim, EmailClaim, NewEmailClaim})
+}
+
+// createTypedToken creates a single purpose token, signed by refresh keys so it
+// can't be used as an access token.
+func (app *Application) createTypedToken(typ string, expiration string, claims jwt.MapClaims) (string, int64, error) {
+	duration, err := time.ParseDuration(expiration)
+	if err != nil {
+		return "", 0, err
+	}
+	exp := time.Now().Add(duration).Unix()
+
+	claims[string(TypeClaim)] = typ
+	claims[string(ExpireClaim)] = exp
+
+	token, err := app.RefreshKeys().Sign(claims)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return token, exp, nil
+}
+
+// verifyTypedToken checks that the token has the type and returns the claims.
+func (app *Application) verifyTypedToken(typ string, bearer string, claimKeys []JwtClaimKey) (map[JwtClaimKey]string, error) {
+	token, err := app.RefreshKeys().Verify(bearer)
 	if err != nil {
 		return nil, err
 	}
-	if !token.Valid {
+
+	claims, err := ExtractTokenMetadata(token, append(claimKeys, TypeClaim))
+	if err != nil || claims[TypeClaim] != typ {
 		return nil, InvalidToken
 	}
-	return token, nil
+
+	return claims, nil
+}
+
+// Make sure that the token method conform to "HS256" and is up to date
+func VerifyToken(bearer string, secret string) (*jwt.Token, error) {
+	return NewHMACSigningKey("", secret).Verify(bearer)
 }
 
 func ExtractTokenMetadata(token *jwt.Token, claimKeys []JwtClaimKey) (map[JwtClaimKey]string, error) {
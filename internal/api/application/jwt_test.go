@@ -47,6 +47,15 @@ func TestCreateTokens(t *testing.T) {
 	if !rt.Valid {
 		t.Fatal("refresh token is invalid")
 	}
+
+	claims, err := application.ExtractTokenMetadata(rt, []application.JwtClaimKey{application.RefreshIdClaim})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, expect := claims[application.RefreshIdClaim], td.RefreshID; got != expect {
+		t.Fatalf("got refresh id claim %s, expected %s", got, expect)
+	}
 }
 
 func TestExtractTokenMetadata(t *testing.T) {
@@ -120,3 +129,120 @@ func TestVerifyToken(t *testing.T) {
 		}
 	})
 }
+
+func TestMfaChallenge(t *testing.T) {
+	app := &application.Application{}
+	app.Config.JWT.Access.Secret = "access-secret"
+	app.Config.JWT.Access.Expiration = "15m"
+	app.Config.JWT.Refresh.Secret = "refresh-secret"
+	app.Config.JWT.Refresh.Expiration = "15m"
+	app.Config.MFA.ChallengeExpiration = "5m"
+
+	t.Run("should verify challenge", func(t *testing.T) {
+		token, _, err := app.CreateMfaChallenge("1234", "5678")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claims, err := app.VerifyMfaChallenge(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if claims[application.UserIdClaim] != "1234" || claims[application.SessionIdClaim] != "5678" {
+			t.Fatalf("got unexpected claims: %v", claims)
+		}
+	})
+
+	t.Run("should refuse challenge as access token", func(t *testing.T) {
+		token, _, err := app.CreateMfaChallenge("1234", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := app.AccessKeys().Verify(token); err == nil {
+			t.Fatal("challenge should not be accepted as access token")
+		}
+	})
+
+	t.Run("should refuse refresh token as challenge", func(t *testing.T) {
+		td, err := app.CreateTokens("1234", "5678")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := app.VerifyMfaChallenge(td.RefreshToken); !errors.Is(err, application.InvalidToken) {
+			t.Fatalf("got error: %v, expect: %s", err, application.InvalidToken)
+		}
+	})
+}
+
+func TestEmailVerificationToken(t *testing.T) {
+	app := &application.Application{}
+	app.Config.JWT.Refresh.Secret = "refresh-secret"
+	app.Config.MFA.ChallengeExpiration = "5m"
+	app.Config.EmailVerification.Expiration = "1h"
+
+	t.Run("should verify token", func(t *testing.T) {
+		token, _, err := app.CreateEmailVerificationToken("1234", "test@test.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claims, err := app.VerifyEmailVerificationToken(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if claims[application.UserIdClaim] != "1234" || claims[application.EmailClaim] != "test@test.com" {
+			t.Fatalf("got unexpected claims: %v", claims)
+		}
+	})
+
+	t.Run("should refuse token of another type", func(t *testing.T) {
+		token, _, err := app.CreateMfaChallenge("1234", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := app.VerifyEmailVerificationToken(token); !errors.Is(err, application.InvalidToken) {
+			t.Fatalf("got error: %v, expect: %s", err, application.InvalidToken)
+		}
+	})
+}
+
+func TestEmailChangeToken(t *testing.T) {
+	app := &application.Application{}
+	app.Config.JWT.Refresh.Secret = "refresh-secret"
+	app.Config.EmailVerification.Expiration = "1h"
+	app.Config.EmailChange.Expiration = "1h"
+
+	t.Run("should verify token", func(t *testing.T) {
+		token, _, err := app.CreateEmailChangeToken("1234", "test@test.com", "new@test.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claims, err := app.VerifyEmailChangeToken(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if claims[application.UserIdClaim] != "1234" ||
+			claims[application.EmailClaim] != "test@test.com" ||
+			claims[application.NewEmailClaim] != "new@test.com" {
+			t.Fatalf("got unexpected claims: %v", claims)
+		}
+	})
+
+	t.Run("should refuse email verification token", func(t *testing.T) {
+		token, _, err := app.CreateEmailVerificationToken("1234", "test@test.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := app.VerifyEmailChangeToken(token); !errors.Is(err, application.InvalidToken) {
+			t.Fatalf("got error: %v, expect: %s", err, application.InvalidToken)
+		}
+	})
+}
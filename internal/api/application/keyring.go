@@ -0,0 +1,220 @@
+package application
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var ErrNoActiveKey = errors.New("Keyring must have an active signing key")
+
+// Keyring signs tokens with its active key and verifies them with the key matching
+// the token "kid" header, so that retired keys are still accepted during a rotation.
+type Keyring struct {
+	active  *SigningKey
+	retired []*SigningKey
+	keys    map[string]*SigningKey
+}
+
+// NewKeyring creates a keyring from the active key and the verify-only retired keys.
+func NewKeyring(active *SigningKey, retired ...*SigningKey) (*Keyring, error) {
+	if active == nil {
+		return nil, ErrNoActiveKey
+	}
+
+	kr := &Keyring{
+		active:  active,
+		retired: retired,
+		keys:    map[string]*SigningKey{active.ID: active},
+	}
+
+	for _, k := range retired {
+		if _, found := kr.keys[k.ID]; found {
+			return nil, fmt.Errorf("duplicate signing key id: %q", k.ID)
+		}
+		kr.keys[k.ID] = k
+	}
+
+	return kr, nil
+}
+
+// Active returns the key signing new tokens.
+func (kr *Keyring) Active() *SigningKey {
+	return kr.active
+}
+
+// Sign creates a token signed with the active key.
+func (kr *Keyring) Sign(claims jwt.MapClaims) (string, error) {
+	return kr.active.Sign(claims)
+}
+
+// Verify makes sure that the token is signed by one of the keys and is up to date.
+// Retired keys are refused once expired.
+func (kr *Keyring) Verify(bearer string) (*jwt.Token, error) {
+	return parseToken(bearer, func(kid string) (*SigningKey, error) {
+		k, found := kr.keys[kid]
+		if !found {
+			return nil, fmt.Errorf("unexpected signing key: %q", kid)
+		}
+		if k != kr.active && k.Expired() {
+			return nil, fmt.Errorf("signing key %q has expired", kid)
+		}
+		return k, nil
+	})
+}
+
+// JWKS returns the public keys still accepted by the keyring.
+func (kr *Keyring) JWKS() []*JWK {
+	keys := []*JWK{}
+
+	if jwk, ok := kr.active.JWK(); ok {
+		keys = append(keys, jwk)
+	}
+
+	for _, k := range kr.retired {
+		if k.Expired() {
+			continue
+		}
+		if jwk, ok := k.JWK(); ok {
+			keys = append(keys, jwk)
+		}
+	}
+
+	return keys
+}
+
+// JWTKeys contains the keyrings used for each kind of token.
+type JWTKeys struct {
+	Access  *Keyring
+	Refresh *Keyring
+}
+
+// KeyConfig describes a signing key from either a secret or a PEM key file.
+type KeyConfig struct {
+	ID        string    `json:"kid"`
+	Secret    string    `json:"secret"`
+	KeyFile   string    `json:"keyFile"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Load creates the signing key.
+func (c KeyConfig) Load() (*SigningKey, error) {
+	var (
+		k   *SigningKey
+		err error
+	)
+
+	switch {
+	case c.KeyFile != "":
+		if k, err = LoadSigningKey(c.ID, c.KeyFile); err != nil {
+			return nil, err
+		}
+	case c.Secret != "":
+		k = NewHMACSigningKey(c.ID, c.Secret)
+	default:
+		return nil, fmt.Errorf("signing key %q must have a secret or a key file", c.ID)
+	}
+
+	k.ExpiresAt = c.ExpiresAt
+
+	return k, nil
+}
+
+// KeyringConfig describes a keyring, retired keys should be removed once they expire.
+type KeyringConfig struct {
+	Active  KeyConfig   `json:"active"`
+	Retired []KeyConfig `json:"retired"`
+}
+
+// Load creates the keyring.
+func (c KeyringConfig) Load() (*Keyring, error) {
+	active, err := c.Active.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var retired []*SigningKey
+	for _, rc := range c.Retired {
+		k, err := rc.Load()
+		if err != nil {
+			return nil, err
+		}
+		retired = append(retired, k)
+	}
+
+	return NewKeyring(active, retired...)
+}
+
+// LoadJWTKeys loads the keyrings from the keyring file. Without keyring file, or when a
+// kind of token is missing from it, the single key configured by flags is used.
+func (app *Application) LoadJWTKeys() error {
+	var file struct {
+		Access  *KeyringConfig `json:"access"`
+		Refresh *KeyringConfig `json:"refresh"`
+	}
+
+	if path := app.Config.JWT.KeyringFile; path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if file.Access == nil {
+		file.Access = &KeyringConfig{Active: KeyConfig{
+			ID:      app.Config.JWT.Access.KeyID,
+			Secret:  app.Config.JWT.Access.Secret,
+			KeyFile: app.Config.JWT.Access.KeyFile,
+		}}
+	}
+
+	if file.Refresh == nil {
+		file.Refresh = &KeyringConfig{Active: KeyConfig{
+			ID:      app.Config.JWT.Refresh.KeyID,
+			Secret:  app.Config.JWT.Refresh.Secret,
+			KeyFile: app.Config.JWT.Refresh.KeyFile,
+		}}
+	}
+
+	var err error
+
+	if app.JWTKeys.Access, err = file.Access.Load(); err != nil {
+		return fmt.Errorf("access keyring: %w", err)
+	}
+
+	if app.JWTKeys.Refresh, err = file.Refresh.Load(); err != nil {
+		return fmt.Errorf("refresh keyring: %w", err)
+	}
+
+	return nil
+}
+
+// AccessKeys returns the keyring used for access tokens, defaulting to the configured secret.
+func (app *Application) AccessKeys() *Keyring {
+	if app.JWTKeys.Access != nil {
+		return app.JWTKeys.Access
+	}
+	kr, _ := NewKeyring(NewHMACSigningKey(app.Config.JWT.Access.KeyID, app.Config.JWT.Access.Secret))
+	return kr
+}
+
+// RefreshKeys returns the keyring used for refresh tokens, defaulting to the configured secret.
+func (app *Application) RefreshKeys() *Keyring {
+	if app.JWTKeys.Refresh != nil {
+		return app.JWTKeys.Refresh
+	}
+	kr, _ := NewKeyring(NewHMACSigningKey(app.Config.JWT.Refresh.KeyID, app.Config.JWT.Refresh.Secret))
+	return kr
+}
+
+// JWKS returns the public keys that third parties can use to verify access tokens.
+func (app *Application) JWKS() []*JWK {
+	return app.AccessKeys().JWKS()
+}
@@ -0,0 +1,182 @@
+package application_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/testutils/mocks"
+)
+
+func TestKeyring(t *testing.T) {
+	active := application.NewHMACSigningKey("new", "new secret")
+	retired := application.NewHMACSigningKey("old", "old secret")
+	expired := application.NewHMACSigningKey("older", "older secret")
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+
+	kr, err := application.NewKeyring(active, retired, expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := mocks.CreateClaims("1234", "5678", time.Now().Add(time.Minute))
+
+	t.Run("should sign with active key", func(t *testing.T) {
+		bearer, err := kr.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		token, err := kr.Verify(bearer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := token.Header["kid"]; got != active.ID {
+			t.Fatalf("got kid header: %v, expect: %s", got, active.ID)
+		}
+	})
+
+	tests := []struct {
+		title       string
+		key         *application.SigningKey
+		expectError string
+	}{
+		{
+			title: "should accept token from retired key",
+			key:   retired,
+		},
+		{
+			title:       "should refuse token from expired key",
+			key:         expired,
+			expectError: `signing key "older" has expired`,
+		},
+		{
+			title:       "should refuse token from unknown key",
+			key:         application.NewHMACSigningKey("unknown", "new secret"),
+			expectError: `unexpected signing key: "unknown"`,
+		},
+		{
+			title:       "should refuse token with wrong secret",
+			key:         application.NewHMACSigningKey("old", "new secret"),
+			expectError: "signature is invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			bearer, err := tt.key.Sign(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = kr.Verify(bearer)
+
+			if tt.expectError == "" {
+				if err != nil {
+					t.Fatalf("token should be accepted but error occured: %s", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tt.expectError {
+				t.Fatalf("got error: %v, expect: %s", err, tt.expectError)
+			}
+		})
+	}
+
+	t.Run("should return duplicate key id error", func(t *testing.T) {
+		_, err := application.NewKeyring(active, application.NewHMACSigningKey("new", "secret"))
+		expect := `duplicate signing key id: "new"`
+		if err == nil || err.Error() != expect {
+			t.Fatalf("got error: %v, expect: %s", err, expect)
+		}
+	})
+}
+
+func TestKeyringJWKS(t *testing.T) {
+	var keys []*application.SigningKey
+	for _, kid := range []string{"active", "retired", "expired"} {
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key, err := application.ParseSigningKeyPEM(kid, mocks.MarshalPKCS8PEM(t, edKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	keys[2].ExpiresAt = time.Now().Add(-time.Minute)
+
+	kr, err := application.NewKeyring(keys[0], keys[1], keys[2], application.NewHMACSigningKey("hmac", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := kr.JWKS()
+	if len(got) != 2 {
+		t.Fatalf("got %d published keys, expect 2", len(got))
+	}
+
+	if got[0].Kid != "active" || got[1].Kid != "retired" {
+		t.Fatalf("got published keys %s and %s, expect active and retired", got[0].Kid, got[1].Kid)
+	}
+}
+
+func TestLoadJWTKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyFile := filepath.Join(dir, "access.pem")
+	if err := ioutil.WriteFile(keyFile, mocks.MarshalPKCS8PEM(t, edKey), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	keyringFile := filepath.Join(dir, "keyring.json")
+	if err := ioutil.WriteFile(keyringFile, []byte(`{
+		"access": {
+			"active": {"kid": "2", "keyFile": "`+keyFile+`"},
+			"retired": [{"kid": "1", "secret": "old secret", "expiresAt": "2100-01-01T00:00:00Z"}]
+		}
+	}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &application.Application{}
+	app.Config.JWT.KeyringFile = keyringFile
+	app.Config.JWT.Refresh.Secret = "refresh secret"
+
+	if err := app.LoadJWTKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := app.AccessKeys().Active().Method.Alg(); got != "EdDSA" {
+		t.Fatalf("got access active key alg: %s, expect: EdDSA", got)
+	}
+
+	bearer, err := application.NewHMACSigningKey("1", "old secret").Sign(mocks.CreateClaims("1234", "5678", time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.AccessKeys().Verify(bearer); err != nil {
+		t.Fatalf("token signed by retired key should be accepted: %s", err)
+	}
+
+	bearer, err = app.RefreshKeys().Sign(mocks.CreateClaims("1234", "5678", time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := application.VerifyToken(bearer, "refresh secret"); err != nil {
+		t.Fatalf("refresh keyring should fallback on refresh secret: %s", err)
+	}
+}
@@ -0,0 +1,185 @@
+package application
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrKeyMustBePEMEncoded = errors.New("Signing key must be PEM encoded")
+	ErrUnsupportedKey      = errors.New("Signing key must be a RSA or Ed25519 private key")
+)
+
+// SigningKey holds the keys used to sign and verify JWT, the ID is set in the "kid" token header.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private interface{}
+	Public  interface{}
+	// ExpiresAt is the date after which a retired key is no longer accepted, zero means never.
+	ExpiresAt time.Time
+}
+
+// NewHMACSigningKey creates a HS256 symmetric key from a secret.
+func NewHMACSigningKey(id string, secret string) *SigningKey {
+	return &SigningKey{
+		ID:      id,
+		Method:  jwt.SigningMethodHS256,
+		Private: []byte(secret),
+		Public:  []byte(secret),
+	}
+}
+
+// ParseSigningKeyPEM creates an asymmetric key from a PEM encoded private key.
+// RSA keys sign with RS256 and Ed25519 keys with EdDSA.
+func ParseSigningKeyPEM(id string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrKeyMustBePEMEncoded
+	}
+
+	var (
+		private interface{}
+		err     error
+	)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{
+			ID:      id,
+			Method:  jwt.SigningMethodRS256,
+			Private: key,
+			Public:  &key.PublicKey,
+		}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{
+			ID:      id,
+			Method:  SigningMethodEd25519,
+			Private: key,
+			Public:  key.Public(),
+		}, nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// LoadSigningKey reads a PEM encoded private key file.
+func LoadSigningKey(id string, path string) (*SigningKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ParseSigningKeyPEM(id, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return key, nil
+}
+
+// Sign creates a signed token from claims.
+func (k *SigningKey) Sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(k.Method, claims)
+	if k.ID != "" {
+		token.Header["kid"] = k.ID
+	}
+
+	return token.SignedString(k.Private)
+}
+
+// Verify makes sure that the token is signed by the key and is up to date.
+func (k *SigningKey) Verify(bearer string) (*jwt.Token, error) {
+	return parseToken(bearer, func(kid string) (*SigningKey, error) {
+		if kid != k.ID {
+			return nil, fmt.Errorf("unexpected signing key: %q", kid)
+		}
+		return k, nil
+	})
+}
+
+// Expired checks if the key can no longer verify tokens.
+func (k *SigningKey) Expired() bool {
+	return !k.ExpiresAt.IsZero() && time.Now().After(k.ExpiresAt)
+}
+
+// parseToken verifies a token with the key returned by find for the token "kid" header.
+func parseToken(bearer string, find func(kid string) (*SigningKey, error)) (*jwt.Token, error) {
+	token, err := jwt.Parse(bearer, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		k, err := find(kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != k.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Method.Alg())
+		}
+		return k.Public, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, InvalidToken
+	}
+	return token, nil
+}
+
+// JWK returns the public part of an asymmetric key in the JSON Web Key format,
+// symmetric keys must never be published.
+func (k *SigningKey) JWK() (*JWK, bool) {
+	jwk := &JWK{
+		Kid: k.ID,
+		Alg: k.Method.Alg(),
+		Use: "sig",
+	}
+
+	switch key := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+	default:
+		return nil, false
+	}
+
+	return jwk, true
+}
+
+// JWK is a public key representation as described by RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
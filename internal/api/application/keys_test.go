@@ -0,0 +1,171 @@
+package application_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/testutils/mocks"
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestParseSigningKeyPEM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		title       string
+		pem         []byte
+		expectAlg   string
+		expectError error
+	}{
+		{
+			title:     "should parse PKCS1 RSA key",
+			pem:       pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+			expectAlg: "RS256",
+		},
+		{
+			title:     "should parse PKCS8 RSA key",
+			pem:       mocks.MarshalPKCS8PEM(t, rsaKey),
+			expectAlg: "RS256",
+		},
+		{
+			title:     "should parse PKCS8 Ed25519 key",
+			pem:       mocks.MarshalPKCS8PEM(t, edKey),
+			expectAlg: "EdDSA",
+		},
+		{
+			title:       "should return unsupported key",
+			pem:         mocks.MarshalPKCS8PEM(t, ecKey),
+			expectError: application.ErrUnsupportedKey,
+		},
+		{
+			title:       "should return key must be PEM encoded",
+			pem:         []byte("not a pem"),
+			expectError: application.ErrKeyMustBePEMEncoded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			key, err := application.ParseSigningKeyPEM("kid", tt.pem)
+
+			if tt.expectError != nil {
+				if err != tt.expectError {
+					t.Fatalf("got error: %v, expect: %s", err, tt.expectError)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := key.Method.Alg(); got != tt.expectAlg {
+				t.Fatalf("got alg: %s, expect: %s", got, tt.expectAlg)
+			}
+		})
+	}
+}
+
+func TestSigningKeySignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaSigning, err := application.ParseSigningKeyPEM("rsa", mocks.MarshalPKCS8PEM(t, rsaKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSigning, err := application.ParseSigningKeyPEM("ed", mocks.MarshalPKCS8PEM(t, edKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacSigning := application.NewHMACSigningKey("hmac", "secret")
+
+	claims := mocks.CreateClaims("1234", "5678", time.Now().Add(time.Minute))
+
+	for _, key := range []*application.SigningKey{rsaSigning, edSigning, hmacSigning} {
+		t.Run("should sign and verify with "+key.Method.Alg(), func(t *testing.T) {
+			bearer, err := key.Sign(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := key.Verify(bearer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := token.Header["kid"]; got != key.ID {
+				t.Fatalf("got kid header: %v, expect: %s", got, key.ID)
+			}
+		})
+	}
+
+	t.Run("should return signing method error", func(t *testing.T) {
+		bearer, err := application.NewHMACSigningKey(rsaSigning.ID, "secret").Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = rsaSigning.Verify(bearer)
+		expect := "unexpected signing method: HS256"
+		if err == nil || err.Error() != expect {
+			t.Fatalf("got error: %v, expect: %s", err, expect)
+		}
+	})
+
+	t.Run("should return signing key error", func(t *testing.T) {
+		bearer, err := application.NewHMACSigningKey("other", "secret").Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = hmacSigning.Verify(bearer)
+		expect := `unexpected signing key: "other"`
+		if err == nil || err.Error() != expect {
+			t.Fatalf("got error: %v, expect: %s", err, expect)
+		}
+	})
+
+	t.Run("should return signature error", func(t *testing.T) {
+		_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bearer, err := jwt.NewWithClaims(application.SigningMethodEd25519, claims).SignedString(otherKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = (&application.SigningKey{Method: edSigning.Method, Public: edSigning.Public}).Verify(bearer)
+		expect := "signature is invalid"
+		if err == nil || err.Error() != expect {
+			t.Fatalf("got error: %v, expect: %s", err, expect)
+		}
+	})
+}
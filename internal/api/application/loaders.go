@@ -0,0 +1,99 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/pkg/dataloader"
+)
+
+const (
+	LoadersCtxKey = contextKey("loaders")
+
+	// loaderWait is how long loaders collect keys before fetching them, long enough for
+	// the resolvers of the items of a list, which run concurrently.
+	loaderWait = 2 * time.Millisecond
+	// loaderMaxBatch is the maximum number of keys of a query.
+	loaderMaxBatch = 100
+)
+
+// Loaders batch and cache the lookups of a request, see NewLoaders.
+type Loaders struct {
+	users    *dataloader.Loader
+	sessions *dataloader.Loader
+}
+
+// NewLoaders returns the loaders of a request, their cache lives as long as the request.
+func (app *Application) NewLoaders() *Loaders {
+	return &Loaders{
+		users: dataloader.New(func(ids []string) (map[string]interface{}, error) {
+			us, err := app.Models.User.GetByIds(ids)
+			if err != nil {
+				return nil, err
+			}
+
+			values := make(map[string]interface{}, len(us))
+			for _, u := range us {
+				values[u.ID] = u
+			}
+
+			return values, nil
+		}, loaderWait, loaderMaxBatch),
+		sessions: dataloader.New(func(ids []string) (map[string]interface{}, error) {
+			sessions, err := app.Models.User.GetSessionsByIds(ids)
+			if err != nil {
+				return nil, err
+			}
+
+			values := make(map[string]interface{}, len(sessions))
+			for _, s := range sessions {
+				values[s.ID] = s
+			}
+
+			return values, nil
+		}, loaderWait, loaderMaxBatch),
+	}
+}
+
+// User loads the user of the id, ErrNotFoundUser if it doesn't exist.
+func (l *Loaders) User(id string) (*user.User, error) {
+	v, err := l.users.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, user.ErrNotFoundUser
+	}
+
+	return v.(*user.User), nil
+}
+
+// Session loads the session of the id, ErrNotFoundSession if it doesn't exist.
+func (l *Loaders) Session(id string) (*user.Session, error) {
+	v, err := l.sessions.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, user.ErrNotFoundSession
+	}
+
+	return v.(*user.Session), nil
+}
+
+// ContextWithLoaders returns a new context holding the loaders of the request.
+func (app *Application) ContextWithLoaders(ctx context.Context, l *Loaders) context.Context {
+	return context.WithValue(ctx, LoadersCtxKey, l)
+}
+
+// LoadersFromContext retrieves the loaders of the request, or new loaders when the context
+// has none, such as outside of the GraphQL handler.
+func (app *Application) LoadersFromContext(ctx context.Context) *Loaders {
+	l, ok := ctx.Value(LoadersCtxKey).(*Loaders)
+	if !ok {
+		return app.NewLoaders()
+	}
+
+	return l
+}
@@ -0,0 +1,56 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/brice-74/golang-base-api/internal/testutils/factory"
+	"github.com/twinj/uuid"
+)
+
+func TestLoaders(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		app = testutils.NewApplication(db)
+		fac = factory.New(t, db)
+	)
+
+	users := []*user.User{fac.CreateUserAccount(nil), fac.CreateUserAccount(nil)}
+	s := fac.CreateUserSession(&user.Session{UserID: users[0].ID})
+
+	l := app.LoadersFromContext(app.ContextWithLoaders(context.Background(), app.NewLoaders()))
+
+	var wg sync.WaitGroup
+	for _, u := range users {
+		wg.Add(1)
+		go func(u *user.User) {
+			defer wg.Done()
+
+			got, err := l.User(u.ID)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if got.ID != u.ID || got.Email != u.Email {
+				t.Errorf("got user: %s %s, expect: %s %s", got.ID, got.Email, u.ID, u.Email)
+			}
+		}(u)
+	}
+	wg.Wait()
+
+	if got, err := l.Session(s.ID); err != nil || got.UserID != users[0].ID {
+		t.Fatalf("got session: %+v %v, expect session of user %s", got, err, users[0].ID)
+	}
+
+	if _, err := l.User(uuid.NewV4().String()); !errors.Is(err, user.ErrNotFoundUser) {
+		t.Fatalf("got error: %v, expect: %v", err, user.ErrNotFoundUser)
+	}
+
+	if _, err := l.Session(uuid.NewV4().String()); !errors.Is(err, user.ErrNotFoundSession) {
+		t.Fatalf("got error: %v, expect: %v", err, user.ErrNotFoundSession)
+	}
+}
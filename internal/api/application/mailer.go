@@ -0,0 +1,25 @@
+package application
+
+import (
+	"errors"
+
+	"github.com/brice-74/golang-base-api/pkg/mailer"
+)
+
+// LoadMailer creates the mailer from the configuration. Without outbox file, emails are
+// kept in memory and never sent, which is refused in prod.
+func (app *Application) LoadMailer() error {
+	if app.Config.Mailer.OutboxFile != "" {
+		app.Mailer = mailer.NewFile(app.Config.Mailer.OutboxFile)
+		return nil
+	}
+
+	if app.Config.Env == "prod" {
+		return errors.New("a mailer outbox file is required in prod")
+	}
+
+	app.Mailer = mailer.NewMemory()
+	app.Logger.PrintInfo("no mailer outbox file, emails are kept in memory", nil)
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package application_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/testutils/mocks"
+	"github.com/brice-74/golang-base-api/pkg/mailer"
+)
+
+func TestLoadMailer(t *testing.T) {
+	tests := []struct {
+		title        string
+		env          string
+		outboxFile   string
+		expectErr    bool
+		expectMemory bool
+	}{
+		{title: "should load file mailer", env: "prod", outboxFile: filepath.Join(t.TempDir(), "outbox")},
+		{title: "should load memory mailer outside prod", env: "dev", expectMemory: true},
+		{title: "should refuse memory mailer in prod", env: "prod", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			app := &application.Application{Logger: mocks.NewLogger()}
+			app.Config.Env = tt.env
+			app.Config.Mailer.OutboxFile = tt.outboxFile
+
+			err := app.LoadMailer()
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expect an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, isMemory := app.Mailer.(*mailer.Memory); isMemory != tt.expectMemory {
+				t.Fatalf("got mailer: %T, expect memory: %t", app.Mailer, tt.expectMemory)
+			}
+		})
+	}
+}
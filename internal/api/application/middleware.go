@@ -138,7 +138,7 @@ func (app *Application) Authenticate(next http.Handler) http.Handler {
 		w.Header().Add("Vary", "Authorization")
 		// retrieve request information.
 		var a = &Agent{
-			IP:    r.RemoteAddr,
+			IP:    remoteIP(r),
 			Agent: r.UserAgent(),
 		}
 
@@ -155,8 +155,34 @@ func (app *Application) Authenticate(next http.Handler) http.Handler {
 			app.InvalidAuthenticationTokenResponse(w, r, nil)
 			return
 		}
+		// personal access tokens are opaque values stored hashed.
+		if user.IsAccessToken(headerParts[1]) {
+			u, t, err := app.Models.User.GetUserByAccessToken(user.HashToken(headerParts[1]))
+			if err != nil {
+				switch {
+				case errors.Is(err, user.ErrNotFoundAccessToken):
+					app.InvalidAuthenticationTokenResponse(w, r, err)
+				default:
+					app.ServerErrorResponse(w, r, err)
+				}
+				return
+			}
+
+			if u.Deactivated() {
+				app.InactiveClientResponse(w, r, user.ErrDeactivatedUser)
+				return
+			}
+
+			ctx := app.ContextWithClient(r.Context(), &ClientCtx{
+				Agent:       a,
+				User:        u,
+				AccessToken: t,
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
 		// check token is valid and up to date.
-		token, err := VerifyToken(headerParts[1], app.Config.JWT.Access.Secret)
+		token, err := app.AccessKeys().Verify(headerParts[1])
 		if err != nil {
 			app.InvalidAuthenticationTokenResponse(w, r, err)
 			return
@@ -179,6 +205,17 @@ func (app *Application) Authenticate(next http.Handler) http.Handler {
 				return
 			}
 		}
+		// access tokens issued before the deactivation of the account are refused,
+		// its sessions have been revoked by the deactivation
+		if u.Deactivated() {
+			app.InactiveClientResponse(w, r, user.ErrDeactivatedUser)
+			return
+		}
+		// a revoked or expired session can't be used anymore, even with an unexpired access token
+		if err := s.CheckActive(); err != nil {
+			app.InactiveClientResponse(w, r, err)
+			return
+		}
 		// create a reusable context for handlers and resolvers
 		ctx := app.ContextWithClient(r.Context(), &ClientCtx{
 			Agent:   a,
@@ -228,3 +265,15 @@ func (app *Application) LogRequest(next http.Handler) http.Handler {
 type QueryBody struct {
 	Query string
 }
+
+// remoteIP returns the IP address of the client without the port of its connection, a
+// new port being used for each connection.
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// the address has no port
+		return r.RemoteAddr
+	}
+
+	return ip
+}
@@ -1,8 +1,11 @@
 package application_test
 
 import (
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -157,16 +160,52 @@ func TestAuthenticate(t *testing.T) {
 
 	app.Config.JWT.Access.Secret = "secret"
 
+	retiredKey := application.NewHMACSigningKey("retired", "retired secret")
+	keyring, err := application.NewKeyring(application.NewHMACSigningKey("", app.Config.JWT.Access.Secret), retiredKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.JWTKeys.Access = keyring
+
 	u := fac.CreateUserAccount(nil)
 	s := fac.CreateUserSession(&user.Session{
-		UserID: u.ID,
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour).UTC().Round(time.Second),
+	})
+	expired := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(-time.Minute),
+	})
+	revoked := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
 	})
+	if err := app.Models.User.RevokeUserSession(revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	deactivated := fac.CreateUserAccount(nil)
+	deactivatedSession := fac.CreateUserSession(&user.Session{
+		UserID:        deactivated.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+	if err := app.Models.User.DeactivateUserAccount(deactivated.ID); err != nil {
+		t.Fatal(err)
+	}
 
 	goodClaims := mocks.CreateClaims(u.ID, s.ID, time.Now().Add(time.Minute*3))
+	expiredClaims := mocks.CreateClaims(u.ID, expired.ID, time.Now().Add(time.Minute*3))
+	revokedClaims := mocks.CreateClaims(u.ID, revoked.ID, time.Now().Add(time.Minute*3))
+	deactivatedClaims := mocks.CreateClaims(deactivated.ID, deactivatedSession.ID, time.Now().Add(time.Minute*3))
 	expClaims := mocks.CreateClaims("", "", time.Time{})
 	randomIdsClaims := mocks.CreateClaims(uuid.NewV4().String(), uuid.NewV4().String(), time.Now().Add(time.Minute*3))
 	badIdsClaims := mocks.CreateClaims("", "", time.Now().Add(time.Minute*3))
 
+	retiredToken, err := retiredKey.Sign(goodClaims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	a := &application.Agent{
 		IP:    "0.0.0.0",
 		Agent: "agent",
@@ -198,6 +237,20 @@ func TestAuthenticate(t *testing.T) {
 				Agent:   a,
 			},
 		},
+		{
+			title: "should return context with session from token signed by retired key",
+			headers: map[string]string{
+				"Authorization": "Bearer " + retiredToken,
+			},
+			expectedHeaders: map[string]string{
+				"Vary": "Authorization",
+			},
+			expectContext: &application.ClientCtx{
+				User:    u,
+				Session: s,
+				Agent:   a,
+			},
+		},
 		{
 			title: "should return context with anonymous user",
 			expectedHeaders: map[string]string{
@@ -291,6 +344,47 @@ func TestAuthenticate(t *testing.T) {
 				json: `{"error":"User or user session not found"}`,
 			},
 		},
+		{
+			title: "should return session expired",
+			headers: map[string]string{
+				"Authorization": "Bearer " + mocks.CreateToken(t, jwt.SigningMethodHS256, expiredClaims, app.Config.JWT.Access.Secret),
+			},
+			expectedHeaders: map[string]string{
+				"Vary":          "Authorization",
+				"Authorization": "Bearer",
+			},
+			expectError: &ExpectError{
+				code: 401,
+				json: `{"error":"Session expired","code":"SessionExpired"}`,
+			},
+		},
+		{
+			title: "should return session revoked",
+			headers: map[string]string{
+				"Authorization": "Bearer " + mocks.CreateToken(t, jwt.SigningMethodHS256, revokedClaims, app.Config.JWT.Access.Secret),
+			},
+			expectedHeaders: map[string]string{
+				"Vary":          "Authorization",
+				"Authorization": "Bearer",
+			},
+			expectError: &ExpectError{
+				code: 401,
+				json: `{"error":"Session revoked","code":"SessionRevoked"}`,
+			},
+		},
+		{
+			title: "should return account deactivated",
+			headers: map[string]string{
+				"Authorization": "Bearer " + mocks.CreateToken(t, jwt.SigningMethodHS256, deactivatedClaims, app.Config.JWT.Access.Secret),
+			},
+			expectedHeaders: map[string]string{
+				"Vary": "Authorization",
+			},
+			expectError: &ExpectError{
+				code: 403,
+				json: `{"error":"User account deactivated","code":"AccountDeactivated"}`,
+			},
+		},
 		{
 			title: "should return server error",
 			headers: map[string]string{
@@ -317,8 +411,8 @@ func TestAuthenticate(t *testing.T) {
 			})
 
 			req := httptest.NewRequest("GET", "/", nil)
-			// fix request addr and agent
-			req.RemoteAddr = a.IP
+			// fix request addr and agent, the port isn't part of the client ip
+			req.RemoteAddr = net.JoinHostPort(a.IP, "54321")
 			req.Header.Set("User-Agent", a.Agent)
 
 			for k, v := range tt.headers {
@@ -345,3 +439,149 @@ func TestAuthenticate(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthenticateAccessToken(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		fac = factory.New(t, db)
+		app = &application.Application{
+			Logger: mocks.NewLogger(),
+			Models: application.NewModels(db),
+		}
+	)
+
+	u := fac.CreateUserAccount(nil)
+	token, value := fac.CreateAccessToken(&user.AccessToken{
+		UserID: u.ID,
+		Scopes: user.Scopes{user.ScopeRead},
+	})
+	_, expiredValue := fac.CreateAccessToken(&user.AccessToken{
+		UserID:    u.ID,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+
+	deactivated := fac.CreateUserAccount(nil)
+	_, deactivatedValue := fac.CreateAccessToken(&user.AccessToken{UserID: deactivated.ID})
+	if err := app.Models.User.DeactivateUserAccount(deactivated.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		title       string
+		bearer      string
+		expectCode  int
+		expectError string
+	}{
+		{
+			title:      "should return context with access token",
+			bearer:     value,
+			expectCode: 200,
+		},
+		{
+			title:       "should refuse expired access token",
+			bearer:      expiredValue,
+			expectCode:  401,
+			expectError: `{"error":"Access token not found"}`,
+		},
+		{
+			title:       "should refuse unknown access token",
+			bearer:      user.AccessTokenPrefix + "unknown",
+			expectCode:  401,
+			expectError: `{"error":"Access token not found"}`,
+		},
+		{
+			title:       "should refuse access token of deactivated account",
+			bearer:      deactivatedValue,
+			expectCode:  403,
+			expectError: `{"error":"User account deactivated","code":"AccountDeactivated"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c := app.ClientFromContext(r.Context())
+
+				if c.User.ID != u.ID {
+					t.Errorf("got user: %s, expect: %s", c.User.ID, u.ID)
+				}
+
+				if c.Session != nil {
+					t.Error("expect no session")
+				}
+
+				if c.AccessToken == nil || c.AccessToken.ID != token.ID {
+					t.Fatalf("got access token: %v, expect: %s", c.AccessToken, token.ID)
+				}
+
+				if c.AccessToken.LastUsedAt.IsZero() {
+					t.Error("expect last used date")
+				}
+
+				if !c.HasScope(user.ScopeRead) || c.HasScope(user.ScopeWrite) {
+					t.Errorf("got unexpected scopes: %v", c.AccessToken.Scopes)
+				}
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Add("Authorization", "Bearer "+tt.bearer)
+
+			res := httptest.NewRecorder()
+			app.Authenticate(next).ServeHTTP(res, req)
+
+			if res.Code != tt.expectCode {
+				t.Errorf("got code: %d expect code: %d", res.Code, tt.expectCode)
+			}
+
+			if tt.expectError != "" {
+				require.JSONEqual(t, res.Body.String(), tt.expectError)
+			}
+		})
+	}
+}
+
+func TestAuthenticateLockoutByHost(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+	)
+
+	// each login is sent from a new connection of the same host
+	var login = func(port int) error {
+		var err error
+
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result := schema.Exec(r.Context(), `
+				mutation {
+					loginUserAccount(email: "unknown@test.com", password: "Test123!") {
+						... on Tokens {
+							access
+						}
+					}
+				}`, "", nil)
+			if len(result.Errors) > 0 {
+				err = result.Errors[0]
+			}
+		})
+
+		req := httptest.NewRequest("POST", "/", nil)
+		req.RemoteAddr = net.JoinHostPort("1.1.1.1", strconv.Itoa(port))
+
+		app.Authenticate(next).ServeHTTP(httptest.NewRecorder(), req)
+
+		return err
+	}
+
+	for i := 0; i < app.Config.Lockout.IPThreshold; i++ {
+		if err := login(40000 + i); err == nil || !strings.Contains(err.Error(), "NotFound") {
+			t.Fatalf("got: %v, expect not found user", err)
+		}
+	}
+
+	for _, port := range []int{50000, 50001} {
+		if err := login(port); err == nil || !strings.Contains(err.Error(), "TooManyRequests") {
+			t.Fatalf("got: %v from port %d, expect TooManyRequests", err, port)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/brice-74/golang-base-api/pkg/password"
+	"github.com/brice-74/golang-base-api/pkg/validator"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// LoadPasswordHasher creates the hasher of new passwords from the configuration.
+func (app *Application) LoadPasswordHasher() error {
+	cfg := app.Config.Password
+
+	switch cfg.Algorithm {
+	case "argon2id":
+		if cfg.Argon2id.Iterations < 1 || cfg.Argon2id.Parallelism < 1 || cfg.Argon2id.Parallelism > math.MaxUint8 {
+			return fmt.Errorf("invalid argon2id iterations %d or parallelism %d", cfg.Argon2id.Iterations, cfg.Argon2id.Parallelism)
+		}
+		// argon2id requires 8 KiB of memory per thread
+		if cfg.Argon2id.Memory < 8*cfg.Argon2id.Parallelism || int64(cfg.Argon2id.Memory) > math.MaxUint32 {
+			return fmt.Errorf("invalid argon2id memory %d KiB", cfg.Argon2id.Memory)
+		}
+
+		h := password.DefaultArgon2id
+		h.Memory = uint32(cfg.Argon2id.Memory)
+		h.Iterations = uint32(cfg.Argon2id.Iterations)
+		h.Parallelism = uint8(cfg.Argon2id.Parallelism)
+
+		app.Passwords = h
+	case "bcrypt":
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("invalid bcrypt cost %d", cfg.BcryptCost)
+		}
+
+		app.Passwords = password.Bcrypt{Cost: cfg.BcryptCost}
+	default:
+		return fmt.Errorf("invalid password algorithm: %s", cfg.Algorithm)
+	}
+
+	return nil
+}
+
+// LoadBreachedPasswords creates the list of passwords refused as new passwords: the common
+// passwords, and the breached passwords of the configured file.
+func (app *Application) LoadBreachedPasswords() error {
+	cfg := app.Config.Password
+
+	if !cfg.BreachCheck {
+		return nil
+	}
+
+	lists := validator.PasswordLists{validator.CommonPasswords}
+
+	if cfg.BreachedFile != "" {
+		f, err := validator.OpenHashFile(cfg.BreachedFile)
+		if err != nil {
+			return err
+		}
+
+		lists = append(lists, f)
+	}
+
+	app.BreachedPasswords = lists
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package application_test
+
+import (
+	"testing"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/pkg/password"
+)
+
+func TestLoadPasswordHasher(t *testing.T) {
+	tests := []struct {
+		title     string
+		algorithm string
+		cost      int
+		memory    int
+		expectErr bool
+	}{
+		{title: "should load argon2id", algorithm: "argon2id", memory: 1024},
+		{title: "should load bcrypt", algorithm: "bcrypt", cost: 10},
+		{title: "should refuse bcrypt cost out of range", algorithm: "bcrypt", cost: 2, expectErr: true},
+		{title: "should refuse argon2id memory below 8 KiB per thread", algorithm: "argon2id", memory: 4, expectErr: true},
+		{title: "should refuse unknown algorithm", algorithm: "md5", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			app := &application.Application{}
+			app.Config.Password.Algorithm = tt.algorithm
+			app.Config.Password.BcryptCost = tt.cost
+			app.Config.Password.Argon2id.Memory = tt.memory
+			app.Config.Password.Argon2id.Iterations = 1
+			app.Config.Password.Argon2id.Parallelism = 1
+
+			err := app.LoadPasswordHasher()
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expect an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hash, err := app.Passwords.Hash("Test123!")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok, _ := password.Verify("Test123!", hash); !ok || app.Passwords.NeedsRehash(hash) {
+				t.Fatalf("got hash: %s, expect a current hash of the password", hash)
+			}
+		})
+	}
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	app := &application.Application{}
+
+	if err := app.LoadBreachedPasswords(); err != nil || app.BreachedPasswords != nil {
+		t.Fatalf("got: %v %v, expect check disabled", app.BreachedPasswords, err)
+	}
+
+	app.Config.Password.BreachCheck = true
+	app.Config.Password.BreachedFile = "missing.txt"
+
+	if err := app.LoadBreachedPasswords(); err == nil {
+		t.Fatal("expect an error for a missing breached file")
+	}
+
+	app.Config.Password.BreachedFile = ""
+
+	if err := app.LoadBreachedPasswords(); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, _ := app.BreachedPasswords.Contains("Password1!"); !found {
+		t.Fatal("expect common passwords refused")
+	}
+}
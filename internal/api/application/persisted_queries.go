@@ -0,0 +1,145 @@
+package application
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/brice-74/golang-base-api/internal/domains/persistedquery"
+	"github.com/brice-74/golang-base-api/pkg/lru"
+)
+
+// PersistedQueries stores the automatic persisted queries by their SHA-256 hash: the most
+// recently used are cached in memory, and all of them are stored in the database.
+type PersistedQueries struct {
+	model persistedquery.Model
+	cache *lru.Cache
+	// limit is the number of queries registered at most in the database.
+	limit int
+	// allowlist holds the queries of the allowlist file by hash.
+	allowlist map[string]string
+	// Strict refuses every query missing from the allowlist, and the registration of
+	// new queries.
+	Strict bool
+}
+
+// LoadPersistedQueries creates the store of the persisted queries from the configuration.
+// The queries of the allowlist file are always known, and the only ones allowed in prod,
+// where the allowlist file is required.
+func (app *Application) LoadPersistedQueries() error {
+	cfg := app.Config.GraphQL
+
+	if cfg.PersistedQueryCacheSize < 1 {
+		return fmt.Errorf("invalid persisted query cache size %d", cfg.PersistedQueryCacheSize)
+	}
+
+	if cfg.PersistedQueryLimit < 1 {
+		return fmt.Errorf("invalid persisted query limit %d", cfg.PersistedQueryLimit)
+	}
+
+	if cfg.AllowlistFile == "" && app.Config.Env == "prod" {
+		return errors.New("a graphql allowlist file is required in prod")
+	}
+
+	pq := &PersistedQueries{
+		model:     app.Models.PersistedQuery,
+		cache:     lru.New(cfg.PersistedQueryCacheSize),
+		limit:     cfg.PersistedQueryLimit,
+		allowlist: map[string]string{},
+	}
+
+	if cfg.AllowlistFile != "" {
+		allowlist, err := readAllowlist(cfg.AllowlistFile)
+		if err != nil {
+			return err
+		}
+
+		pq.allowlist = allowlist
+		pq.Strict = app.Config.Env == "prod"
+	}
+
+	app.PersistedQueries = pq
+
+	return nil
+}
+
+// readAllowlist reads a JSON object of the queries by their hash.
+func readAllowlist(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	allowlist := map[string]string{}
+	if err = json.NewDecoder(f).Decode(&allowlist); err != nil {
+		return nil, fmt.Errorf("invalid allowlist file %s: %w", path, err)
+	}
+
+	for hash, query := range allowlist {
+		if persistedquery.Hash(query) != hash {
+			return nil, fmt.Errorf("invalid allowlist file %s: %s is not the SHA-256 hash of its query", path, hash)
+		}
+	}
+
+	return allowlist, nil
+}
+
+// Allowed reports whether the query of the hash can be run.
+func (pq *PersistedQueries) Allowed(hash string) bool {
+	if !pq.Strict {
+		return true
+	}
+
+	_, ok := pq.allowlist[hash]
+	return ok
+}
+
+// Get returns the query of the hash, ErrNotFoundPersistedQuery if it isn't registered.
+func (pq *PersistedQueries) Get(hash string) (string, error) {
+	if query, ok := pq.allowlist[hash]; ok {
+		return query, nil
+	}
+
+	if pq.Strict {
+		return "", persistedquery.ErrNotFoundPersistedQuery
+	}
+
+	if query, ok := pq.cache.Get(hash); ok {
+		return query.(string), nil
+	}
+
+	q, err := pq.model.GetByHash(hash)
+	if err != nil {
+		return "", err
+	}
+
+	pq.cache.Add(hash, q.Query)
+
+	return q.Query, nil
+}
+
+// Register stores a query by its hash, the caller checks the hash matches the query.
+// persistedquery.ErrPersistedQueryLimit is returned once the database holds the maximum
+// number of queries.
+func (pq *PersistedQueries) Register(hash, query string) error {
+	if pq.Strict {
+		return fmt.Errorf("cannot register query %s in strict mode", hash)
+	}
+
+	if _, ok := pq.allowlist[hash]; ok {
+		return nil
+	}
+	if _, ok := pq.cache.Get(hash); ok {
+		return nil
+	}
+
+	if err := pq.model.Insert(&persistedquery.PersistedQuery{Hash: hash, Query: query}, pq.limit); err != nil {
+		return err
+	}
+
+	pq.cache.Add(hash, query)
+
+	return nil
+}
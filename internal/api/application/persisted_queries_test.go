@@ -0,0 +1,140 @@
+package application_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/persistedquery"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+)
+
+func writeAllowlist(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadPersistedQueries(t *testing.T) {
+	allowed := "{queryCheck}"
+	hash := persistedquery.Hash(allowed)
+
+	tests := []struct {
+		title        string
+		env          string
+		allowlist    string
+		cacheSize    int
+		limit        int
+		expectErr    bool
+		expectStrict bool
+	}{
+		{title: "should load without allowlist", env: "dev", cacheSize: 10, limit: 10},
+		{title: "should load allowlist", env: "dev", allowlist: `{"` + hash + `":"` + allowed + `"}`, cacheSize: 10, limit: 10},
+		{title: "should be strict in prod with allowlist", env: "prod", allowlist: `{"` + hash + `":"` + allowed + `"}`, cacheSize: 10, limit: 10, expectStrict: true},
+		{title: "should require allowlist in prod", env: "prod", cacheSize: 10, limit: 10, expectErr: true},
+		{title: "should refuse hash not matching its query", env: "prod", allowlist: `{"` + hash + `":"{me{id}}"}`, cacheSize: 10, limit: 10, expectErr: true},
+		{title: "should refuse invalid allowlist", env: "prod", allowlist: `["{queryCheck}"]`, cacheSize: 10, limit: 10, expectErr: true},
+		{title: "should refuse empty cache", env: "dev", limit: 10, expectErr: true},
+		{title: "should refuse empty limit", env: "dev", cacheSize: 10, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			app := &application.Application{}
+			app.Config.Env = tt.env
+			app.Config.GraphQL.PersistedQueryCacheSize = tt.cacheSize
+			app.Config.GraphQL.PersistedQueryLimit = tt.limit
+			if tt.allowlist != "" {
+				app.Config.GraphQL.AllowlistFile = writeAllowlist(t, tt.allowlist)
+			}
+
+			err := app.LoadPersistedQueries()
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expect an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if app.PersistedQueries.Strict != tt.expectStrict {
+				t.Fatalf("got strict: %t, expect: %t", app.PersistedQueries.Strict, tt.expectStrict)
+			}
+
+			if tt.allowlist != "" {
+				if query, err := app.PersistedQueries.Get(hash); err != nil || query != allowed {
+					t.Fatalf("got %q %v, expect query of the allowlist", query, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPersistedQueriesStrict(t *testing.T) {
+	allowed := "{queryCheck}"
+	hash := persistedquery.Hash(allowed)
+	unknown := persistedquery.Hash("{me{id}}")
+
+	app := &application.Application{}
+	app.Config.Env = "prod"
+	app.Config.GraphQL.PersistedQueryCacheSize = 10
+	app.Config.GraphQL.PersistedQueryLimit = 10
+	app.Config.GraphQL.AllowlistFile = writeAllowlist(t, `{"`+hash+`":"`+allowed+`"}`)
+
+	if err := app.LoadPersistedQueries(); err != nil {
+		t.Fatal(err)
+	}
+	pq := app.PersistedQueries
+
+	if !pq.Allowed(hash) || pq.Allowed(unknown) {
+		t.Fatal("expect only the queries of the allowlist allowed")
+	}
+
+	// the database isn't read in strict mode
+	if _, err := pq.Get(unknown); !errors.Is(err, persistedquery.ErrNotFoundPersistedQuery) {
+		t.Fatalf("got error: %v, expect: %v", err, persistedquery.ErrNotFoundPersistedQuery)
+	}
+
+	if err := pq.Register(unknown, "{me{id}}"); err == nil {
+		t.Fatal("expect registration refused")
+	}
+}
+
+func TestPersistedQueriesRegister(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		app = testutils.NewApplication(db)
+	)
+
+	if err := app.LoadPersistedQueries(); err != nil {
+		t.Fatal(err)
+	}
+
+	query := "{queryCheck}"
+	hash := persistedquery.Hash(query)
+
+	if _, err := app.PersistedQueries.Get(hash); !errors.Is(err, persistedquery.ErrNotFoundPersistedQuery) {
+		t.Fatalf("got error: %v, expect: %v", err, persistedquery.ErrNotFoundPersistedQuery)
+	}
+
+	if err := app.PersistedQueries.Register(hash, query); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new store reads the query from the database
+	if err := app.LoadPersistedQueries(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := app.PersistedQueries.Get(hash); err != nil || got != query {
+		t.Fatalf("got %q %v, expect registered query", got, err)
+	}
+}
@@ -0,0 +1,33 @@
+package application
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// PurgeDeletedUserAccounts deletes the user accounts whose deletion grace period has passed,
+// once at start and then every interval until ctx is done.
+func (app *Application) PurgeDeletedUserAccounts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := app.Models.User.PurgeDeletedUserAccounts()
+		if err != nil {
+			app.Logger.PrintError(err, map[string]string{
+				"job": "purge deleted user accounts",
+			})
+		} else if n > 0 {
+			app.Logger.PrintInfo("deleted user accounts purged", map[string]string{
+				"count": strconv.Itoa(n),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -10,13 +10,23 @@ func AuthToken(app *application.Application) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := app.ClientFromContext(r.Context())
 
+		client := application.Envelope{
+			"Agent": ctx.Agent.Agent,
+			"IP":    ctx.Agent.IP,
+		}
+
+		if ctx.Session != nil {
+			client["Session"] = ctx.Session.ID
+		}
+
+		if ctx.AccessToken != nil {
+			client["AccessToken"] = ctx.AccessToken.ID
+			client["Scopes"] = ctx.AccessToken.Scopes
+		}
+
 		context := application.Envelope{
-			"Client": application.Envelope{
-				"Session": ctx.Session.ID,
-				"Agent":   ctx.Agent.Agent,
-				"IP":      ctx.Agent.IP,
-			},
-			"Roles": ctx.User.Roles,
+			"Client": client,
+			"Roles":  ctx.User.Roles,
 		}
 
 		if err := app.WriteJSON(w, http.StatusOK, context, nil); err != nil {
@@ -2,21 +2,238 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	stdErrors "errors"
 	"fmt"
 	"net/http"
 	"runtime"
+	"strconv"
 
 	"github.com/graph-gophers/graphql-go"
-	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/graph-gophers/graphql-go/errors"
 
 	"github.com/brice-74/golang-base-api/internal/api/application"
 	"github.com/brice-74/golang-base-api/internal/api/resolvers"
 	"github.com/brice-74/golang-base-api/internal/api/schema"
+	"github.com/brice-74/golang-base-api/internal/domains/persistedquery"
+	"github.com/brice-74/golang-base-api/pkg/complexity"
 )
 
+// Codes of the errors of the queries refused before their execution.
+const (
+	errCodeQueryTooDeep               = "QueryTooDeep"
+	errCodeQueryTooComplex            = "QueryTooComplex"
+	errCodeQueryNotAllowed            = "QueryNotAllowed"
+	errCodePersistedQueryHashMismatch = "PersistedQueryHashMismatch"
+)
+
+// Errors of the automatic persisted queries, their message and code are the ones Apollo
+// clients expect to send the query again with its text.
+var (
+	errPersistedQueryNotFound = &errors.QueryError{
+		Message:    "PersistedQueryNotFound",
+		Extensions: map[string]interface{}{"code": "PERSISTED_QUERY_NOT_FOUND"},
+	}
+	errPersistedQueryNotSupported = &errors.QueryError{
+		Message:    "PersistedQueryNotSupported",
+		Extensions: map[string]interface{}{"code": "PERSISTED_QUERY_NOT_SUPPORTED"},
+	}
+)
+
+// persistedQueryExtension is the extension of the requests sending a query by its hash.
+type persistedQueryExtension struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+// maxDepthExceededRule is the validation rule of graphql-go refusing the queries deeper
+// than graphql.MaxDepth.
+const maxDepthExceededRule = "MaxDepthExceeded"
+
+// defaultListSize is the number of items of the connections without first or last
+// argument, see utils.KeysetParams.PageSize.
+const defaultListSize = 20
+
 // GraphQL is the main entrypoint for queries and mutations.
 func GraphQL(app *application.Application) http.HandlerFunc {
-	opts := []graphql.SchemaOpt{graphql.Logger(Logger{App: app})}
+	s := MustParseSchema(app)
+	analyzer := complexity.NewAnalyzer(s.ASTSchema(), defaultListSize)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var params struct {
+			Query         string                 `json:"query"`
+			OperationName string                 `json:"operationName"`
+			Variables     map[string]interface{} `json:"variables"`
+			Extensions    struct {
+				PersistedQuery *persistedQueryExtension `json:"persistedQuery"`
+			} `json:"extensions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var (
+			response *graphql.Response
+			valid    bool
+		)
+		query, register, qErr := lookupQuery(app, params.Query, params.Extensions.PersistedQuery)
+		if qErr == nil {
+			valid, qErr = checkQueryLimits(app, s, analyzer, query, params.OperationName, params.Variables)
+		}
+
+		if qErr != nil {
+			response = &graphql.Response{Errors: []*errors.QueryError{qErr}}
+		} else {
+			// only the queries valid against the schema are stored, once the limit is
+			// reached the queries are run without being stored
+			if register && valid {
+				err := app.PersistedQueries.Register(params.Extensions.PersistedQuery.Sha256Hash, query)
+				if err != nil && !stdErrors.Is(err, persistedquery.ErrPersistedQueryLimit) {
+					app.Logger.PrintError(err, nil)
+				}
+			}
+
+			// lookups are batched and cached for the time of the request
+			ctx := app.ContextWithLoaders(r.Context(), app.NewLoaders())
+			response = s.Exec(ctx, query, params.OperationName, params.Variables)
+		}
+
+		responseJSON, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(responseJSON)
+	}
+}
+
+// lookupQuery returns the text of the query of a request: the query itself, or the
+// persisted query of the hash of its extension. register reports whether the query is
+// sent with its hash to be persisted. In strict mode, only the queries of the allowlist
+// are returned.
+func lookupQuery(
+	app *application.Application,
+	query string,
+	ext *persistedQueryExtension,
+) (_ string, register bool, _ *errors.QueryError) {
+	pq := app.PersistedQueries
+
+	if ext == nil {
+		if pq != nil && !pq.Allowed(persistedquery.Hash(query)) {
+			return "", false, queryError(errCodeQueryNotAllowed, "The query is not allowed", nil)
+		}
+		return query, false, nil
+	}
+
+	if pq == nil || ext.Version != 1 {
+		return "", false, errPersistedQueryNotSupported
+	}
+
+	if query == "" {
+		query, err := pq.Get(ext.Sha256Hash)
+		if err != nil {
+			if !stdErrors.Is(err, persistedquery.ErrNotFoundPersistedQuery) {
+				app.Logger.PrintError(err, nil)
+			}
+			return "", false, errPersistedQueryNotFound
+		}
+		return query, false, nil
+	}
+
+	if persistedquery.Hash(query) != ext.Sha256Hash {
+		return "", false, queryError(errCodePersistedQueryHashMismatch, "The hash of the persisted query doesn't match the query", nil)
+	}
+
+	if !pq.Allowed(ext.Sha256Hash) {
+		return "", false, queryError(errCodeQueryNotAllowed, "The query is not allowed", nil)
+	}
+
+	return query, !pq.Strict, nil
+}
+
+// checkQueryLimits refuses the queries deeper or more complex than allowed by the
+// configuration, before their execution, and logs the cost of the others. The depth is
+// limited by the validation of the schema, see MustParseSchema, and the cost is only
+// computed for valid queries. valid reports whether the query passed this validation.
+func checkQueryLimits(
+	app *application.Application,
+	s *graphql.Schema,
+	analyzer *complexity.Analyzer,
+	query, operationName string,
+	variables map[string]interface{},
+) (valid bool, _ *errors.QueryError) {
+	maxDepth, maxComplexity := app.Config.GraphQL.MaxDepth, app.Config.GraphQL.MaxComplexity
+
+	if errs := s.ValidateWithVariables(query, variables); len(errs) > 0 {
+		for _, err := range errs {
+			if err.Rule == maxDepthExceededRule {
+				return false, queryError(
+					errCodeQueryTooDeep,
+					fmt.Sprintf("The query exceeds the maximum depth %d", maxDepth),
+					map[string]interface{}{"maxDepth": maxDepth},
+				)
+			}
+		}
+
+		// invalid queries are refused with their errors by the execution
+		return false, nil
+	}
+
+	res, err := analyzer.Analyze(query, operationName, variables)
+	if err != nil {
+		// the execution refuses an unknown operation with a better error
+		if stdErrors.Is(err, complexity.ErrUnknownOperation) {
+			return true, nil
+		}
+
+		app.Logger.PrintError(err, map[string]string{"graphql query": "complexity analysis failed"})
+		return true, queryError(errCodeQueryTooComplex, "The complexity of the query could not be computed", nil)
+	}
+
+	app.Logger.PrintInfo("graphql query", map[string]string{
+		"operation":  res.OperationName,
+		"complexity": strconv.Itoa(res.Complexity),
+	})
+
+	if maxComplexity > 0 && res.Complexity > maxComplexity {
+		return true, queryError(
+			errCodeQueryTooComplex,
+			fmt.Sprintf("The query complexity %d exceeds the maximum complexity %d", res.Complexity, maxComplexity),
+			map[string]interface{}{"complexity": res.Complexity, "maxComplexity": maxComplexity},
+		)
+	}
+
+	return true, nil
+}
+
+// queryError returns an error with the extensions of the resolver errors, and additional
+// values such as the values exceeding the limits.
+func queryError(code, message string, values map[string]interface{}) *errors.QueryError {
+	extensions := map[string]interface{}{
+		"code":       code,
+		"statusCode": http.StatusBadRequest,
+		"message":    message,
+	}
+	for k, v := range values {
+		extensions[k] = v
+	}
+
+	return &errors.QueryError{Message: message, Extensions: extensions}
+}
+
+// MustParseSchema parses the schema with the resolvers of the application, limiting the
+// depth of the queries, the authorization rules of its directives are loaded once parsed.
+func MustParseSchema(app *application.Application) *graphql.Schema {
+	authorizer := resolvers.NewAuthorizer()
+
+	opts := []graphql.SchemaOpt{
+		graphql.Logger(Logger{App: app}),
+		graphql.Tracer(authorizer),
+		graphql.MaxDepth(app.Config.GraphQL.MaxDepth),
+	}
 
 	s := graphql.MustParseSchema(
 		schema.String(),
@@ -26,10 +243,11 @@ func GraphQL(app *application.Application) http.HandlerFunc {
 		opts...,
 	)
 
-	return func(w http.ResponseWriter, r *http.Request) {
-		h := relay.Handler{Schema: s}
-		h.ServeHTTP(w, r)
+	if err := authorizer.Load(s.ASTSchema()); err != nil {
+		panic(err)
 	}
+
+	return s
 }
 
 // logger for GraphQL
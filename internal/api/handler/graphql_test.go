@@ -1,13 +1,18 @@
 package handler_test
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/brice-74/golang-base-api/internal/api/application"
 	"github.com/brice-74/golang-base-api/internal/api/handler"
+	"github.com/brice-74/golang-base-api/internal/domains/persistedquery"
+	"github.com/brice-74/golang-base-api/internal/testutils"
 	"github.com/brice-74/golang-base-api/internal/testutils/mocks"
 	"github.com/brice-74/golang-base-api/internal/testutils/require"
 )
@@ -20,7 +25,9 @@ func TestGraphQL(t *testing.T) {
 
 	rr := httptest.NewRecorder()
 
-	app := &application.Application{}
+	app := &application.Application{
+		Logger: mocks.NewLogger(),
+	}
 	handler.GraphQL(app).ServeHTTP(rr, req)
 
 	expected := `{"data":{"queryCheck":"ok"}}`
@@ -44,3 +51,215 @@ func TestGraphQLPanic(t *testing.T) {
 	expected := `{"errors":[{"message":"panic occurred: I panic !!!","path":["queryPanic"]}],"data":null}`
 	require.JSONEqual(t, rr.Body.String(), expected)
 }
+
+func TestGraphQLQueryLimits(t *testing.T) {
+	// depth: 3, complexity: 1 + 3*(1+1)
+	query := `{"query":"{ nodes(ids: [\"a\", \"b\", \"c\"]) { ... on Session { user { id } } } }"}`
+
+	tests := []struct {
+		title         string
+		maxDepth      int
+		maxComplexity int
+		expected      string
+	}{
+		{
+			title:    "should refuse too deep query",
+			maxDepth: 2,
+			expected: `{"errors":[{"message":"The query exceeds the maximum depth 2","extensions":{"code":"QueryTooDeep","statusCode":400,"message":"The query exceeds the maximum depth 2","maxDepth":2}}]}`,
+		},
+		{
+			title:         "should refuse too complex query",
+			maxComplexity: 6,
+			expected:      `{"errors":[{"message":"The query complexity 7 exceeds the maximum complexity 6","extensions":{"code":"QueryTooComplex","statusCode":400,"message":"The query complexity 7 exceeds the maximum complexity 6","complexity":7,"maxComplexity":6}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/", strings.NewReader(query))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			app := &application.Application{
+				Logger: mocks.NewLogger(),
+			}
+			app.Config.GraphQL.MaxDepth = tt.maxDepth
+			app.Config.GraphQL.MaxComplexity = tt.maxComplexity
+
+			handler.GraphQL(app).ServeHTTP(rr, req)
+
+			require.JSONEqual(t, rr.Body.String(), tt.expected)
+		})
+	}
+}
+
+func TestGraphQLPersistedQueries(t *testing.T) {
+	allowed := "{queryCheck}"
+	hash := persistedquery.Hash(allowed)
+	unknown := persistedquery.Hash("{__typename}")
+
+	allowlist := filepath.Join(t.TempDir(), "allowlist.json")
+	if err := os.WriteFile(allowlist, []byte(fmt.Sprintf(`{"%s":"%s"}`, hash, allowed)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	extension := func(hash string) string {
+		return fmt.Sprintf(`"extensions":{"persistedQuery":{"version":1,"sha256Hash":"%s"}}`, hash)
+	}
+
+	tests := []struct {
+		title    string
+		env      string
+		disabled bool
+		body     string
+		expected string
+	}{
+		{
+			title:    "should run persisted query",
+			env:      "dev",
+			body:     `{` + extension(hash) + `}`,
+			expected: `{"data":{"queryCheck":"ok"}}`,
+		},
+		{
+			title:    "should run query sent with its hash",
+			env:      "prod",
+			body:     `{"query":"{queryCheck}",` + extension(hash) + `}`,
+			expected: `{"data":{"queryCheck":"ok"}}`,
+		},
+		{
+			title:    "should not find unknown persisted query",
+			env:      "prod",
+			body:     `{` + extension(unknown) + `}`,
+			expected: `{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`,
+		},
+		{
+			title:    "should refuse hash not matching the query",
+			env:      "dev",
+			body:     `{"query":"{queryCheck}",` + extension(unknown) + `}`,
+			expected: `{"errors":[{"message":"The hash of the persisted query doesn't match the query","extensions":{"code":"PersistedQueryHashMismatch","statusCode":400,"message":"The hash of the persisted query doesn't match the query"}}]}`,
+		},
+		{
+			title:    "should refuse query missing from the allowlist in prod",
+			env:      "prod",
+			body:     `{"query":"{__typename}"}`,
+			expected: `{"errors":[{"message":"The query is not allowed","extensions":{"code":"QueryNotAllowed","statusCode":400,"message":"The query is not allowed"}}]}`,
+		},
+		{
+			title:    "should refuse persisted query missing from the allowlist in prod",
+			env:      "prod",
+			body:     `{"query":"{__typename}",` + extension(unknown) + `}`,
+			expected: `{"errors":[{"message":"The query is not allowed","extensions":{"code":"QueryNotAllowed","statusCode":400,"message":"The query is not allowed"}}]}`,
+		},
+		{
+			title:    "should refuse persisted query when disabled",
+			disabled: true,
+			body:     `{` + extension(hash) + `}`,
+			expected: `{"errors":[{"message":"PersistedQueryNotSupported","extensions":{"code":"PERSISTED_QUERY_NOT_SUPPORTED"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			app := &application.Application{
+				Logger: mocks.NewLogger(),
+			}
+			if !tt.disabled {
+				app.Config.Env = tt.env
+				app.Config.GraphQL.PersistedQueryCacheSize = 10
+				app.Config.GraphQL.PersistedQueryLimit = 10
+				app.Config.GraphQL.AllowlistFile = allowlist
+				if err := app.LoadPersistedQueries(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			handler.GraphQL(app).ServeHTTP(rr, req)
+
+			require.JSONEqual(t, rr.Body.String(), tt.expected)
+		})
+	}
+}
+
+func TestGraphQLRegisterPersistedQuery(t *testing.T) {
+	app := testutils.NewApplication(testutils.PrepareDB(t))
+	if err := app.LoadPersistedQueries(); err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.GraphQL(app)
+	extension := fmt.Sprintf(`"extensions":{"persistedQuery":{"version":1,"sha256Hash":"%s"}}`, persistedquery.Hash("{queryCheck}"))
+
+	notFound := `{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`
+	ok := `{"data":{"queryCheck":"ok"}}`
+
+	steps := []struct {
+		body     string
+		expected string
+	}{
+		// the query is unknown until it's sent with its hash
+		{body: `{` + extension + `}`, expected: notFound},
+		{body: `{"query":"{queryCheck}",` + extension + `}`, expected: ok},
+		{body: `{` + extension + `}`, expected: ok},
+	}
+
+	for _, step := range steps {
+		req, err := http.NewRequest("POST", "/", strings.NewReader(step.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		require.JSONEqual(t, rr.Body.String(), step.expected)
+	}
+}
+
+func TestGraphQLPersistedQueryLimit(t *testing.T) {
+	app := testutils.NewApplication(testutils.PrepareDB(t))
+	app.Config.GraphQL.PersistedQueryLimit = 1
+	if err := app.LoadPersistedQueries(); err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.GraphQL(app)
+	extension := func(query string) string {
+		return fmt.Sprintf(`"extensions":{"persistedQuery":{"version":1,"sha256Hash":"%s"}}`, persistedquery.Hash(query))
+	}
+
+	notFound := `{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`
+	ok := `{"data":{"queryCheck":"ok"}}`
+
+	steps := []struct {
+		body     string
+		expected string
+	}{
+		{body: `{"query":"{queryCheck}",` + extension("{queryCheck}") + `}`, expected: ok},
+		// the limit is reached, the query runs without being stored
+		{body: `{"query":"{ queryCheck }",` + extension("{ queryCheck }") + `}`, expected: ok},
+		{body: `{` + extension("{ queryCheck }") + `}`, expected: notFound},
+		{body: `{` + extension("{queryCheck}") + `}`, expected: ok},
+	}
+
+	for _, step := range steps {
+		req, err := http.NewRequest("POST", "/", strings.NewReader(step.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		require.JSONEqual(t, rr.Body.String(), step.expected)
+	}
+}
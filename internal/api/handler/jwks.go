@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+)
+
+// JWKS publishes the public keys used to verify access tokens.
+func JWKS(app *application.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headers := http.Header{}
+		headers.Set("Cache-Control", "public, max-age=300")
+
+		env := application.Envelope{
+			"keys": app.JWKS(),
+		}
+
+		if err := app.WriteJSON(w, http.StatusOK, env, headers); err != nil {
+			app.ServerErrorResponse(w, r, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package handler_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/api/handler"
+	"github.com/brice-74/golang-base-api/internal/testutils/mocks"
+)
+
+func TestJWKS(t *testing.T) {
+	req, err := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := application.ParseSigningKeyPEM("key-1", mocks.MarshalPKCS8PEM(t, rsaKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &application.Application{}
+	app.JWTKeys.Access, err = application.NewKeyring(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.JWKS(app).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %d want %d", status, http.StatusOK)
+	}
+
+	var res struct {
+		Keys []application.JWK
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Keys) != 1 {
+		t.Fatalf("got %d keys, expect 1", len(res.Keys))
+	}
+
+	got := res.Keys[0]
+	if got.Kid != "key-1" || got.Kty != "RSA" || got.Alg != "RS256" || got.Use != "sig" || got.N == "" || got.E != "AQAB" {
+		t.Fatalf("unexpected JWK: %+v", got)
+	}
+}
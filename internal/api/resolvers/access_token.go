@@ -0,0 +1,179 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/pkg/validator"
+	"github.com/graph-gophers/graphql-go"
+)
+
+var errSessionRequired = errors.New("Operation not allowed with a personal access token")
+
+// CreateAccessToken: create a personal access token, its value is only returned once
+func (r Root) CreateAccessToken(ctx context.Context, params CreateAccessTokenParams) (*CreatedAccessTokenResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireSession(c); err != nil {
+		return nil, err
+	}
+
+	if err := r.requireVerifiedEmail(c.User); err != nil {
+		return nil, err
+	}
+
+	t := user.AccessToken{
+		Name:   params.Input.Name,
+		Scopes: params.Input.Scopes,
+		UserID: c.User.ID,
+	}
+	if params.Input.ExpiresAt != nil {
+		t.ExpiresAt = params.Input.ExpiresAt.Time
+	}
+	// check that all entries are valid
+	v := validator.New()
+	t.ValidateNameEntry(v)
+	t.ValidateScopesEntry(v)
+	t.ValidateExpiresAtEntry(v)
+	if !v.Valid() {
+		return nil, validatorError{Errors: v.Errors}
+	}
+
+	value, err := user.GenerateAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.App.Models.User.InsertAccessToken(&t, user.HashToken(value)); err != nil {
+		return nil, resolverErrDatabaseOperation(err)
+	}
+
+	return &CreatedAccessTokenResolver{
+		token:       value,
+		accessToken: AccessTokenResolver{app: r.App, token: t},
+	}, nil
+}
+
+type CreateAccessTokenParams struct {
+	Input CreateAccessTokenInput
+}
+
+type CreateAccessTokenInput struct {
+	Name      string
+	Scopes    []user.Scope
+	ExpiresAt *graphql.Time
+}
+
+// AccessTokens: get personal access tokens of the logged user
+func (r Root) AccessTokens(ctx context.Context) ([]*AccessTokenResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	tokens, err := r.App.Models.User.GetAllAccessTokens(c.User.ID)
+	if err != nil {
+		return nil, resolverErrDatabaseOperation(err)
+	}
+
+	res := make([]*AccessTokenResolver, len(tokens))
+	for i, t := range tokens {
+		res[i] = &AccessTokenResolver{app: r.App, token: *t}
+	}
+
+	return res, nil
+}
+
+// RevokeAccessToken: delete a personal access token of the logged user
+func (r Root) RevokeAccessToken(ctx context.Context, params RevokeAccessTokenParams) (bool, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireSession(c); err != nil {
+		return false, err
+	}
+
+	if err := r.App.Models.User.DeleteAccessToken(string(params.ID), c.User.ID); err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFoundAccessToken):
+			return false, resolverErrNotFound(err)
+		default:
+			return false, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	return true, nil
+}
+
+type RevokeAccessTokenParams struct {
+	ID graphql.ID
+}
+
+type CreatedAccessTokenResolver struct {
+	token       string
+	accessToken AccessTokenResolver
+}
+
+func (r CreatedAccessTokenResolver) Token() string {
+	return r.token
+}
+
+func (r CreatedAccessTokenResolver) AccessToken() AccessTokenResolver {
+	return r.accessToken
+}
+
+type AccessTokenResolver struct {
+	app   *application.Application
+	token user.AccessToken
+}
+
+func (r AccessTokenResolver) ID() graphql.ID {
+	return graphql.ID(r.token.ID)
+}
+
+func (r AccessTokenResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.token.CreatedAt}
+}
+
+func (r AccessTokenResolver) ExpiresAt() *graphql.Time {
+	if r.token.ExpiresAt.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: r.token.ExpiresAt}
+}
+
+func (r AccessTokenResolver) LastUsedAt() *graphql.Time {
+	if r.token.LastUsedAt.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: r.token.LastUsedAt}
+}
+
+func (r AccessTokenResolver) Name() string {
+	return r.token.Name
+}
+
+func (r AccessTokenResolver) Scopes() user.Scopes {
+	return r.token.Scopes
+}
+
+// requireSession refuses operations managing the account to personal access tokens.
+func requireSession(c *application.ClientCtx) error {
+	if c.AccessToken != nil {
+		return resolverErrForbidden(errSessionRequired)
+	}
+
+	return nil
+}
+
+// requireScope refuses operations to personal access tokens without the scope.
+func requireScope(c *application.ClientCtx, scope user.Scope) error {
+	if !c.HasScope(scope) {
+		return resolverErrForbidden(fmt.Errorf("Access token requires the %s scope", scope))
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package resolvers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/brice-74/golang-base-api/internal/testutils/factory"
+	"github.com/brice-74/golang-base-api/pkg/validator"
+	"github.com/graph-gophers/graphql-go/gqltesting"
+)
+
+func TestCreateAccessToken(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+	s := fac.CreateUserSession(&user.Session{UserID: u.ID})
+	pat, _ := fac.CreateAccessToken(&user.AccessToken{UserID: u.ID})
+
+	var queryString = func(name string, scopes string, expiresAt time.Time) string {
+		return fmt.Sprintf(`
+			mutation {
+				createAccessToken(input: {
+					name: "%s",
+					scopes: [%s],
+					expiresAt: "%s"
+				}) {
+					token
+					accessToken {
+						id
+						name
+						scopes
+						expiresAt
+					}
+				}
+			}`, name, scopes, expiresAt.Format(time.RFC3339),
+		)
+	}
+
+	sessionContext := app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Session: s})
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should create access token",
+			gqltest: &gqltesting.Test{
+				Context: sessionContext,
+				Schema:  schema,
+				Query:   queryString("script", "READ", time.Now().Add(time.Hour)),
+			},
+		},
+		{
+			title: "Should return validatorError",
+			gqltest: &gqltesting.Test{
+				Context: sessionContext,
+				Schema:  schema,
+				Query:   queryString("", "", time.Now().Add(-time.Hour)),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "validation error [ValidatorError]",
+				Extensions: map[string]interface{}{
+					"code":       "ValidatorError",
+					"statusCode": 422,
+					"errors": validator.Errors{
+						"name":       []string{"must be provided"},
+						"scopes":     []string{"must be provided"},
+						"expires at": []string{"must be in the future"},
+					},
+				},
+			},
+		},
+		{
+			title: "Should refuse access token client",
+			gqltest: &gqltesting.Test{
+				Context: app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, AccessToken: pat}),
+				Schema:  schema,
+				Query:   queryString("script", "READ", time.Now().Add(time.Hour)),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Forbidden]: Operation not allowed with a personal access token",
+				Extensions: map[string]interface{}{
+					"code":       "Forbidden",
+					"statusCode": 403,
+					"message":    "Operation not allowed with a personal access token",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(tt.gqltest.Context, tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			var res CreateAccessTokenResponse
+
+			data, _ := result.Data.MarshalJSON()
+			if err := json.Unmarshal(data, &res); err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(res.CreateAccessToken.Token, user.AccessTokenPrefix) {
+				t.Fatalf("got token without prefix: %s", res.CreateAccessToken.Token)
+			}
+
+			gotUser, gotToken, err := app.Models.User.GetUserByAccessToken(user.HashToken(res.CreateAccessToken.Token))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if gotUser.ID != u.ID || gotToken.ID != res.CreateAccessToken.AccessToken.ID {
+				t.Fatal("created token should authenticate the user")
+			}
+		})
+	}
+}
+
+type CreateAccessTokenResponse struct {
+	CreateAccessToken struct {
+		Token       string
+		AccessToken AccessTokenResponse
+	}
+}
+
+type AccessTokenResponse struct {
+	ID        string
+	Name      string
+	Scopes    user.Scopes
+	ExpiresAt *time.Time
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+	s := fac.CreateUserSession(&user.Session{UserID: u.ID})
+	own, value := fac.CreateAccessToken(&user.AccessToken{UserID: u.ID})
+	other, _ := fac.CreateAccessToken(nil)
+
+	var queryString = func(id string) string {
+		return fmt.Sprintf(`mutation { revokeAccessToken(id: "%s") }`, id)
+	}
+
+	sessionContext := app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Session: s})
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should return not found token of another user",
+			gqltest: &gqltesting.Test{
+				Context: sessionContext,
+				Schema:  schema,
+				Query:   queryString(other.ID),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [NotFoundError]: Access token not found",
+				Extensions: map[string]interface{}{
+					"code":       "NotFoundError",
+					"statusCode": 404,
+					"message":    "Access token not found",
+				},
+			},
+		},
+		{
+			title: "Should revoke access token",
+			gqltest: &gqltesting.Test{
+				Context: sessionContext,
+				Schema:  schema,
+				Query:   queryString(own.ID),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(tt.gqltest.Context, tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			if _, _, err := app.Models.User.GetUserByAccessToken(user.HashToken(value)); err != user.ErrNotFoundAccessToken {
+				t.Fatalf("got: %v, expect: %s", err, user.ErrNotFoundAccessToken)
+			}
+		})
+	}
+}
+
+func TestAccessTokenScopes(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+	readToken, _ := fac.CreateAccessToken(&user.AccessToken{UserID: u.ID, Scopes: user.Scopes{user.ScopeRead}})
+	writeToken, _ := fac.CreateAccessToken(&user.AccessToken{UserID: u.ID, Scopes: user.Scopes{user.ScopeWrite}})
+
+	t.Run("Should list access tokens with read scope", func(t *testing.T) {
+		result := schema.Exec(
+			app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, AccessToken: readToken}),
+			`query { accessTokens { id name scopes } }`, "", nil,
+		)
+		if len(result.Errors) > 0 {
+			t.Fatal(result.Errors[0])
+		}
+
+		var res AccessTokensResponse
+
+		data, _ := result.Data.MarshalJSON()
+		if err := json.Unmarshal(data, &res); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(res.AccessTokens) != 2 {
+			t.Fatalf("got %d access tokens, expect 2", len(res.AccessTokens))
+		}
+	})
+
+	t.Run("Should refuse access token without read scope", func(t *testing.T) {
+		result := schema.Exec(
+			app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, AccessToken: writeToken}),
+			`query { me { id } }`, "", nil,
+		)
+
+		testutils.TestGqlError(t, result.Errors[0], &testutils.ExpectResolverError{
+			Msg: "error [Forbidden]: Access token requires the READ scope",
+			Extensions: map[string]interface{}{
+				"code":       "Forbidden",
+				"statusCode": 403,
+				"message":    "Access token requires the READ scope",
+			},
+		})
+	})
+}
+
+type AccessTokensResponse struct {
+	AccessTokens []AccessTokenResponse
+}
@@ -0,0 +1,327 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/pkg/mailer"
+	"github.com/brice-74/golang-base-api/pkg/password"
+	"github.com/brice-74/golang-base-api/pkg/validator"
+	"github.com/graph-gophers/graphql-go"
+)
+
+var errIncorrectPassword = errors.New("incorrect password")
+
+// validateNewPassword checks a password chosen by a user, common and breached passwords
+// are refused when the check is enabled.
+func (r Root) validateNewPassword(v *validator.Validator, u user.User) error {
+	u.ValidatePasswordEntry(v)
+
+	if r.App.BreachedPasswords == nil {
+		return nil
+	}
+
+	return u.ValidatePasswordNotBreachedEntry(v, r.App.BreachedPasswords)
+}
+
+// verifyPassword checks the password of the logged user before a sensitive operation.
+// Failures are recorded like failed logins, so a stolen token can't be used to guess the
+// password.
+func (r Root) verifyPassword(ctx context.Context, u *user.User, plain string) error {
+	uctx := r.App.ClientFromContext(ctx)
+
+	attempt, err := r.beginLoginAttempt(u.ID, uctx.Agent.IP)
+	if err != nil {
+		return err
+	}
+
+	ok, err := password.Verify(plain, u.Password)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		if err = r.failLoginAttempt(attempt); err != nil {
+			return err
+		}
+		return resolverErrUnauthorized(errIncorrectPassword)
+	}
+	// same reset rule as the logins, see checkCredentials
+	return r.succeedLoginAttempt(attempt, !u.MfaEnabled())
+}
+
+// ChangePassword: replace the password of the logged user, other sessions can be revoked at the same time
+func (r Root) ChangePassword(ctx context.Context, params ChangePasswordParams) (bool, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireSession(c); err != nil {
+		return false, err
+	}
+
+	if err := r.verifyPassword(ctx, c.User, params.CurrentPassword); err != nil {
+		return false, err
+	}
+
+	uEntry := user.User{Password: params.NewPassword}
+
+	v := validator.New()
+	if err := r.validateNewPassword(v, uEntry); err != nil {
+		return false, err
+	}
+	if !v.Valid() {
+		return false, validatorError{Errors: v.Errors}
+	}
+
+	hash, err := r.App.Passwords.Hash(uEntry.Password)
+	if err != nil {
+		return false, err
+	}
+
+	if err = r.App.Models.User.UpdateUserPassword(c.User.ID, hash); err != nil {
+		return false, userAccountError(err)
+	}
+
+	if params.RevokeOtherSessions != nil && *params.RevokeOtherSessions {
+		if _, err = r.App.Models.User.RevokeOtherUserSessions(c.User.ID, c.Session.ID); err != nil {
+			return false, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	r.audit(ctx, c.User.ID, user.AuditPasswordChange)
+
+	return true, nil
+}
+
+type ChangePasswordParams struct {
+	CurrentPassword     string
+	NewPassword         string
+	RevokeOtherSessions *bool
+}
+
+// ChangeEmail: send a confirmation link to the new address, the email is replaced once confirmed
+func (r Root) ChangeEmail(ctx context.Context, params ChangeEmailParams) (bool, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireSession(c); err != nil {
+		return false, err
+	}
+
+	if err := r.verifyPassword(ctx, c.User, params.Password); err != nil {
+		return false, err
+	}
+
+	uEntry := user.User{Email: strings.TrimSpace(params.NewEmail)}
+
+	v := validator.New()
+	uEntry.ValidateEmailEntry(v)
+	v.Check(!strings.EqualFold(uEntry.Email, c.User.Email), "email", "must be different from the current email")
+	if !v.Valid() {
+		return false, validatorError{Errors: v.Errors}
+	}
+	// the address is checked again on confirmation, it could be taken in the meantime
+	_, err := r.App.Models.User.GetByEmail(uEntry.Email)
+	switch {
+	case err == nil:
+		return false, resolverErrConflict(user.ErrDuplicateEmail)
+	case !errors.Is(err, user.ErrNotFoundUser):
+		return false, resolverErrDatabaseOperation(err)
+	}
+
+	token, _, err := r.App.CreateEmailChangeToken(c.User.ID, c.User.Email, uEntry.Email)
+	if err != nil {
+		return false, err
+	}
+
+	link, err := linkWithToken(r.App.Config.EmailChange.URL, token)
+	if err != nil {
+		return false, err
+	}
+
+	if err = r.App.Mailer.Send(mailer.Message{
+		From:    r.App.Config.Mailer.From,
+		To:      uEntry.Email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nUse the following link to confirm your new email address:\n%s\n\nIf you didn't request this change, you can ignore this email.\n",
+			c.User.ProfilName, link,
+		),
+	}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+type ChangeEmailParams struct {
+	NewEmail string
+	Password string
+}
+
+// ConfirmEmailChange: replace the email of a user using the token sent to the new address
+func (r Root) ConfirmEmailChange(ctx context.Context, params ConfirmEmailChangeParams) (bool, error) {
+	claims, err := r.App.VerifyEmailChangeToken(params.Token)
+	if err != nil {
+		return false, resolverErrUnauthorized(err)
+	}
+	// the token is refused if the user has changed his email since it was sent
+	if err = r.App.Models.User.UpdateUserEmail(
+		claims[application.UserIdClaim],
+		claims[application.EmailClaim],
+		claims[application.NewEmailClaim],
+	); err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFoundUser):
+			return false, resolverErrNotFound(err)
+		case errors.Is(err, user.ErrDuplicateEmail):
+			return false, resolverErrConflict(err)
+		default:
+			return false, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	r.audit(ctx, claims[application.UserIdClaim], user.AuditEmailChange)
+
+	return true, nil
+}
+
+type ConfirmEmailChangeParams struct {
+	Token string
+}
+
+// UpdateProfile: update the profile of the logged user, omitted fields are kept and null optional fields are cleared
+func (r Root) UpdateProfile(ctx context.Context, params UpdateProfileParams) (*UserAccountResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeWrite); err != nil {
+		return nil, err
+	}
+
+	u := *c.User
+
+	if params.Input.FirstName.Set {
+		u.FirstName = nullStringValue(params.Input.FirstName)
+	}
+	if params.Input.LastName.Set {
+		u.LastName = nullStringValue(params.Input.LastName)
+	}
+	if params.Input.BirthDate.Set {
+		u.BirthDate = time.Time{}
+		if params.Input.BirthDate.Value != nil {
+			u.BirthDate = params.Input.BirthDate.Value.Time
+		}
+	}
+	if params.Input.ProfileVisibility != nil {
+		u.ProfileVisibility = *params.Input.ProfileVisibility
+	}
+
+	v := validator.New()
+	u.ValidateFirstNameEntry(v)
+	u.ValidateLastNameEntry(v)
+	u.ValidateBirthDateEntry(v)
+	if !v.Valid() {
+		return nil, validatorError{Errors: v.Errors}
+	}
+
+	if err := r.App.Models.User.UpdateUserProfile(&u); err != nil {
+		return nil, userAccountError(err)
+	}
+
+	return newUserAccountResolver(r.App, u), nil
+}
+
+type UpdateProfileParams struct {
+	Input UpdateProfileInput
+}
+
+type UpdateProfileInput struct {
+	FirstName graphql.NullString
+	LastName  graphql.NullString
+	BirthDate graphql.NullTime
+	// ProfileVisibility is kept when null, it can't be cleared.
+	ProfileVisibility *user.ProfileVisibility
+}
+
+// nullStringValue returns the trimmed value of a nullable string, empty when null.
+func nullStringValue(s graphql.NullString) string {
+	if s.Value == nil {
+		return ""
+	}
+	return strings.TrimSpace(*s.Value)
+}
+
+// RequestAccountDeletion: deactivate the account of the logged user, it is deleted once the grace period
+// has passed, returns the deletion date
+func (r Root) RequestAccountDeletion(ctx context.Context, params RequestAccountDeletionParams) (graphql.Time, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireSession(c); err != nil {
+		return graphql.Time{}, err
+	}
+
+	if err := r.verifyPassword(ctx, c.User, params.Password); err != nil {
+		return graphql.Time{}, err
+	}
+
+	gracePeriod, err := time.ParseDuration(r.App.Config.AccountDeletion.GracePeriod)
+	if err != nil {
+		return graphql.Time{}, err
+	}
+
+	deleteAt := time.Now().Add(gracePeriod).Truncate(time.Second)
+
+	if err = r.App.Models.User.ScheduleUserAccountDeletion(c.User.ID, deleteAt); err != nil {
+		return graphql.Time{}, userAccountError(err)
+	}
+
+	r.audit(ctx, c.User.ID, user.AuditDeletionRequest)
+
+	if err = r.App.Mailer.Send(mailer.Message{
+		From:    r.App.Config.Mailer.From,
+		To:      c.User.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nYour account has been deactivated and will be deleted on %s.\n\nIf you didn't request this deletion or changed your mind, contact us before this date to reactivate it.\n",
+			c.User.ProfilName, deleteAt.UTC().Format(time.RFC1123),
+		),
+	}); err != nil {
+		// the deletion is scheduled even if the email can't be sent
+		r.App.Logger.PrintError(err, map[string]string{
+			"deletion email": c.User.ID,
+		})
+	}
+
+	return graphql.Time{Time: deleteAt}, nil
+}
+
+type RequestAccountDeletionParams struct {
+	Password string
+}
+
+// ExportMyData: get a JSON archive of the personal data of the logged user
+func (r Root) ExportMyData(ctx context.Context) (string, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireSession(c); err != nil {
+		return "", err
+	}
+
+	export, err := r.App.Models.User.GetDataExport(c.User.ID)
+	if err != nil {
+		return "", userAccountError(err)
+	}
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		return "", err
+	}
+
+	r.audit(ctx, c.User.ID, user.AuditDataExport)
+
+	return string(data), nil
+}
@@ -0,0 +1,599 @@
+package resolvers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/brice-74/golang-base-api/internal/testutils/factory"
+	"github.com/brice-74/golang-base-api/pkg/mailer"
+	"github.com/brice-74/golang-base-api/pkg/password"
+	"github.com/brice-74/golang-base-api/pkg/validator"
+	"github.com/graph-gophers/graphql-go/gqltesting"
+)
+
+func TestChangePassword(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	const (
+		strPass     = "Test123!"
+		newPassword = "NewPass123!"
+	)
+
+	var queryString = func(current, password string) string {
+		return fmt.Sprintf(`
+			mutation {
+				changePassword(currentPassword: "%s", newPassword: "%s", revokeOtherSessions: true)
+			}`, current, password,
+		)
+	}
+
+	u := fac.CreateUserAccount(&user.User{Password: strPass})
+	current := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+	other := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+
+	agent := &application.Agent{IP: "0.0.0.0", Agent: "agent"}
+	queryContext := app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Session: current, Agent: agent})
+	app.BreachedPasswords = validator.CommonPasswords
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should return incorrect password",
+			gqltest: &gqltesting.Test{
+				Context: queryContext,
+				Schema:  schema,
+				Query:   queryString("Wrong123!", newPassword),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Unauthorized]: incorrect password",
+				Extensions: map[string]interface{}{
+					"code":       "Unauthorized",
+					"statusCode": 401,
+					"message":    "incorrect password",
+				},
+			},
+		},
+		{
+			title: "Should return validatorError",
+			gqltest: &gqltesting.Test{
+				Context: queryContext,
+				Schema:  schema,
+				Query:   queryString(strPass, "weakpass"),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "validation error [ValidatorError]",
+				Extensions: map[string]interface{}{
+					"code":       "ValidatorError",
+					"statusCode": 422,
+					"errors": validator.Errors{
+						"password": []string{
+							"must have minimum of 1 uppercase",
+							"must have minimum of 1 number",
+							"must have minimum of 1 special character",
+						},
+					},
+				},
+			},
+		},
+		{
+			title: "Should refuse common password",
+			gqltest: &gqltesting.Test{
+				Context: queryContext,
+				Schema:  schema,
+				Query:   queryString(strPass, "Password1!"),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "validation error [ValidatorError]",
+				Extensions: map[string]interface{}{
+					"code":       "ValidatorError",
+					"statusCode": 422,
+					"errors": validator.Errors{
+						"password": []string{"must not be a common or breached password"},
+					},
+				},
+			},
+		},
+		{
+			title: "Should change password and revoke other sessions",
+			gqltest: &gqltesting.Test{
+				Context: queryContext,
+				Schema:  schema,
+				Query:   queryString(strPass, newPassword),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(tt.gqltest.Context, tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			got, err := app.Models.User.GetById(u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok, _ := password.Verify(newPassword, got.Password); !ok {
+				t.Fatal("password should be replaced")
+			}
+
+			for _, s := range []*user.Session{current, other} {
+				gs, err := app.Models.User.GetSessionByID(s.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if active := gs.CheckActive() == nil; active != (s.ID == current.ID) {
+					t.Fatalf("got session %s active: %t", s.ID, active)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyPasswordLockout(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	const strPass = "Test123!"
+
+	u := fac.CreateUserAccount(&user.User{Password: strPass})
+	s := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+
+	agent := &application.Agent{IP: "0.0.0.0", Agent: "agent"}
+	queryContext := app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Session: s, Agent: agent})
+
+	var changePassword = func(current string) error {
+		result := schema.Exec(queryContext, fmt.Sprintf(`
+			mutation {
+				changePassword(currentPassword: "%s", newPassword: "NewPass123!")
+			}`, current,
+		), "", nil)
+		if len(result.Errors) > 0 {
+			return result.Errors[0]
+		}
+
+		return nil
+	}
+
+	for i := 0; i < app.Config.Lockout.AccountThreshold; i++ {
+		if err := changePassword("Wrong123!"); err == nil || !strings.Contains(err.Error(), "incorrect password") {
+			t.Fatalf("got: %v, expect incorrect password", err)
+		}
+	}
+
+	t.Run("Should refuse current password once locked", func(t *testing.T) {
+		if err := changePassword(strPass); err == nil || !strings.Contains(err.Error(), "TooManyRequests") {
+			t.Fatalf("got: %v, expect TooManyRequests", err)
+		}
+	})
+}
+
+func TestChangeEmail(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+		outbox = app.Mailer.(*mailer.Memory)
+	)
+
+	const (
+		strPass  = "Test123!"
+		newEmail = "new@test.com"
+	)
+
+	var queryString = func(email, password string) string {
+		return fmt.Sprintf(`mutation { changeEmail(newEmail: "%s", password: "%s") }`, email, password)
+	}
+
+	u := fac.CreateUserAccount(&user.User{Password: strPass})
+	taken := fac.CreateUserAccount(nil)
+
+	agent := &application.Agent{IP: "0.0.0.0", Agent: "agent"}
+	queryContext := app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Agent: agent})
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should return incorrect password",
+			gqltest: &gqltesting.Test{
+				Context: queryContext,
+				Schema:  schema,
+				Query:   queryString(newEmail, "Wrong123!"),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Unauthorized]: incorrect password",
+				Extensions: map[string]interface{}{
+					"code":       "Unauthorized",
+					"statusCode": 401,
+					"message":    "incorrect password",
+				},
+			},
+		},
+		{
+			title: "Should return validatorError",
+			gqltest: &gqltesting.Test{
+				Context: queryContext,
+				Schema:  schema,
+				Query:   queryString(u.Email, strPass),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "validation error [ValidatorError]",
+				Extensions: map[string]interface{}{
+					"code":       "ValidatorError",
+					"statusCode": 422,
+					"errors": validator.Errors{
+						"email": []string{"must be different from the current email"},
+					},
+				},
+			},
+		},
+		{
+			title: "Should return duplicate email",
+			gqltest: &gqltesting.Test{
+				Context: queryContext,
+				Schema:  schema,
+				Query:   queryString(taken.Email, strPass),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [ConflictError]: Duplicate email",
+				Extensions: map[string]interface{}{
+					"code":       "ConflictError",
+					"statusCode": 409,
+					"message":    "Duplicate email",
+				},
+			},
+		},
+		{
+			title: "Should send confirmation link to new address",
+			gqltest: &gqltesting.Test{
+				Context: queryContext,
+				Schema:  schema,
+				Query:   queryString(newEmail, strPass),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(tt.gqltest.Context, tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+		})
+	}
+
+	token := tokenFromEmail(t, outbox, newEmail, app.Config.EmailChange.URL)
+	confirm := fmt.Sprintf(`mutation { confirmEmailChange(token: "%s") }`, token)
+
+	t.Run("Should confirm email change", func(t *testing.T) {
+		result := schema.Exec(context.Background(), confirm, "", nil)
+		if len(result.Errors) > 0 {
+			t.Fatal(result.Errors[0])
+		}
+
+		got, err := app.Models.User.GetById(u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Email != newEmail {
+			t.Fatalf("got email: %s, expect: %s", got.Email, newEmail)
+		}
+	})
+
+	t.Run("Should refuse token once email changed", func(t *testing.T) {
+		result := schema.Exec(context.Background(), confirm, "", nil)
+
+		testutils.TestGqlError(t, result.Errors[0], &testutils.ExpectResolverError{
+			Msg: "error [NotFoundError]: User not found",
+			Extensions: map[string]interface{}{
+				"code":       "NotFoundError",
+				"statusCode": 404,
+				"message":    "User not found",
+			},
+		})
+	})
+}
+
+func TestUpdateProfile(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	var queryString = func(input string) string {
+		return fmt.Sprintf(`mutation { updateProfile(input: { %s }) { id } }`, input)
+	}
+
+	u := fac.CreateUserAccount(nil)
+	birthDate := time.Date(1990, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		title       string
+		input       string
+		expectUser  user.User
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should return validatorError",
+			input: `firstName: "J0hn", birthDate: "2100-01-01T00:00:00Z"`,
+			expectError: &testutils.ExpectResolverError{
+				Msg: "validation error [ValidatorError]",
+				Extensions: map[string]interface{}{
+					"code":       "ValidatorError",
+					"statusCode": 422,
+					"errors": validator.Errors{
+						"first name": []string{"must only contain letters, spaces, hyphens and apostrophes"},
+						"birth date": []string{"must be at least 13 years old"},
+					},
+				},
+			},
+		},
+		{
+			title:      "Should set profile",
+			input:      `firstName: "John", lastName: "Doe", birthDate: "1990-06-15T00:00:00Z"`,
+			expectUser: user.User{FirstName: "John", LastName: "Doe", BirthDate: birthDate},
+		},
+		{
+			title:      "Should keep omitted fields",
+			input:      `lastName: "O'Neil"`,
+			expectUser: user.User{FirstName: "John", LastName: "O'Neil", BirthDate: birthDate},
+		},
+		{
+			title:      "Should clear null fields",
+			input:      `firstName: null, birthDate: null`,
+			expectUser: user.User{LastName: "O'Neil"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			// the client is loaded by each request
+			current, err := app.Models.User.GetById(u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := app.ContextWithClient(context.Background(), &application.ClientCtx{User: current})
+			result := schema.Exec(ctx, queryString(tt.input), "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			got, err := app.Models.User.GetById(u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.FirstName != tt.expectUser.FirstName ||
+				got.LastName != tt.expectUser.LastName ||
+				!got.BirthDate.Equal(tt.expectUser.BirthDate) {
+				t.Fatalf(
+					"got profile: %q %q %s, expect: %q %q %s",
+					got.FirstName, got.LastName, got.BirthDate,
+					tt.expectUser.FirstName, tt.expectUser.LastName, tt.expectUser.BirthDate,
+				)
+			}
+		})
+	}
+}
+
+func TestRequestAccountDeletion(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+		outbox = app.Mailer.(*mailer.Memory)
+	)
+
+	const strPass = "Test123!"
+
+	var queryString = func(password string) string {
+		return fmt.Sprintf(`mutation { requestAccountDeletion(password: "%s") }`, password)
+	}
+
+	u := fac.CreateUserAccount(&user.User{Password: strPass})
+	s := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+
+	agent := &application.Agent{IP: "0.0.0.0", Agent: "agent"}
+	queryContext := app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Session: s, Agent: agent})
+
+	t.Run("Should return incorrect password", func(t *testing.T) {
+		result := schema.Exec(queryContext, queryString("Wrong123!"), "", nil)
+
+		testutils.TestGqlError(t, result.Errors[0], &testutils.ExpectResolverError{
+			Msg: "error [Unauthorized]: incorrect password",
+			Extensions: map[string]interface{}{
+				"code":       "Unauthorized",
+				"statusCode": 401,
+				"message":    "incorrect password",
+			},
+		})
+	})
+
+	t.Run("Should deactivate account and schedule deletion", func(t *testing.T) {
+		result := schema.Exec(queryContext, queryString(strPass), "", nil)
+		if len(result.Errors) > 0 {
+			t.Fatal(result.Errors[0])
+		}
+
+		got, err := app.Models.User.GetById(u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !got.Deactivated() {
+			t.Fatal("expect deactivated account")
+		}
+
+		gs, err := app.Models.User.GetSessionByID(s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if gs.CheckActive() != user.ErrSessionRevoked {
+			t.Fatal("session should be revoked")
+		}
+
+		if _, ok := outbox.Last(u.Email); !ok {
+			t.Fatal("expect a deletion email")
+		}
+		// the grace period isn't over, the account is kept
+		if _, err = app.Models.User.PurgeDeletedUserAccounts(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = app.Models.User.GetById(u.ID); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestExportMyData(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+	s := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+	fac.CreateAccessToken(&user.AccessToken{UserID: u.ID})
+
+	if err := app.Models.User.InsertLoginFailure(u.ID, "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Models.User.InsertAuditEntry(&user.AuditEntry{
+		Action: user.AuditPasswordChange,
+		IP:     "127.0.0.1",
+		UserID: u.ID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	queryContext := app.ContextWithClient(context.Background(), &application.ClientCtx{
+		User:    u,
+		Session: s,
+		Agent: &application.Agent{
+			IP:    "0.0.0.0",
+			Agent: "agent",
+		},
+	})
+
+	result := schema.Exec(queryContext, `{ exportMyData }`, "", nil)
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors[0])
+	}
+
+	var res struct {
+		ExportMyData string
+	}
+
+	if err := json.Unmarshal(result.Data, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	var export user.DataExport
+
+	if err := json.Unmarshal([]byte(res.ExportMyData), &export); err != nil {
+		t.Fatal(err)
+	}
+
+	if export.Account.ID != u.ID || export.Account.Email != u.Email {
+		t.Fatalf("got account: %s %s, expect: %s %s", export.Account.ID, export.Account.Email, u.ID, u.Email)
+	}
+
+	if len(export.Sessions) != 1 || len(export.AccessTokens) != 1 || len(export.AuditEntries) != 1 || len(export.LoginFailures) != 1 {
+		t.Fatalf(
+			"got %d sessions, %d access tokens, %d audit entries and %d login failures, expect one of each",
+			len(export.Sessions), len(export.AccessTokens), len(export.AuditEntries), len(export.LoginFailures),
+		)
+	}
+
+	if export.AuditEntries[0].Action != user.AuditPasswordChange {
+		t.Fatalf("got audit action: %s, expect: %s", export.AuditEntries[0].Action, user.AuditPasswordChange)
+	}
+
+	t.Run("Should record the export in the audit log", func(t *testing.T) {
+		entries, err := app.Models.User.GetAuditEntries(u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, e := range entries {
+			if e.Action == user.AuditDataExport && e.IP == "0.0.0.0" {
+				return
+			}
+		}
+
+		t.Fatalf("got %d audit entries, expect the export from 0.0.0.0", len(entries))
+	})
+
+	if strings.Contains(res.ExportMyData, u.Password) {
+		t.Fatal("password hash exported")
+	}
+}
@@ -0,0 +1,354 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/utils"
+	"github.com/brice-74/golang-base-api/pkg/validator"
+	"github.com/graph-gophers/graphql-go"
+)
+
+var errOwnAccount = errors.New("Operation not allowed on your own account")
+
+// Users: search user accounts
+func (r Root) Users(ctx context.Context, params UserListParams) (*UserListResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	v := validator.New()
+
+	qp := utils.QueryParams{
+		Sort: params.Sort,
+		SortableFields: []string{
+			"createdAt",
+			"-createdAt",
+			"email",
+			"-email",
+			"profilName",
+			"-profilName",
+		},
+		Offset: int(params.Offset),
+		Limit:  int(params.Limit),
+	}
+
+	if qp.Validate(v); !v.Valid() {
+		return nil, validatorError{Errors: v.Errors}
+	}
+
+	users, total, err := r.App.Models.User.GetAllUsers(
+		qp,
+		user.GetAllUsersIncludeFilters{
+			Search: params.Search,
+			Roles:  params.Include.Roles,
+			States: params.Include.States,
+		},
+	)
+	if err != nil {
+		return nil, resolverErrDatabaseOperation(err)
+	}
+
+	var ur []UserAccountResolver
+	for _, u := range users {
+		ur = append(ur, *newUserAccountResolver(r.App, *u))
+	}
+
+	return &UserListResolver{total: total, resolvers: ur}, nil
+}
+
+type UserListParams struct {
+	ResolverParams
+	Search  string
+	Include UserListIncludeFiltersInput
+}
+
+type UserListIncludeFiltersInput struct {
+	States []user.UserActivityState
+	Roles  user.Roles
+}
+
+type UserListResolver struct {
+	total     int
+	resolvers []UserAccountResolver
+}
+
+func (r UserListResolver) Total() int32 {
+	return int32(r.total)
+}
+
+func (r UserListResolver) List() []UserAccountResolver {
+	return r.resolvers
+}
+
+// User: get any user account
+func (r Root) User(ctx context.Context, params UserParams) (*UserAccountResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	id, ok := localID(params.ID, userAccountNodeType)
+	if !ok {
+		return nil, resolverErrNotFound(user.ErrNotFoundUser)
+	}
+
+	return r.userAccount(id)
+}
+
+type UserParams struct {
+	ID graphql.ID
+}
+
+// Sessions: get sessions of any user
+func (r Root) Sessions(ctx context.Context, params AnySessionListParams) (*SessionListResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	v := validator.New()
+
+	qp := utils.QueryParams{
+		Sort: params.Sort,
+		SortableFields: []string{
+			"deactivatedAt",
+			"-deactivatedAt",
+		},
+		Offset: int(params.Offset),
+		Limit:  int(params.Limit),
+	}
+
+	if qp.Validate(v); !v.Valid() {
+		return nil, validatorError{Errors: v.Errors}
+	}
+
+	userIDs := make([]string, len(params.Include.UserIds))
+	for i, id := range params.Include.UserIds {
+		local, ok := localID(id, userAccountNodeType)
+		v.Check(ok, "userIds", "must be ids of user accounts")
+		userIDs[i] = local
+	}
+
+	if !v.Valid() {
+		return nil, validatorError{Errors: v.Errors}
+	}
+
+	sessions, total, err := r.App.Models.User.GetAllSession(
+		qp,
+		user.GetAllSessionIncludeFilters{
+			States:  params.Include.States,
+			UserIds: userIDs,
+		},
+	)
+	if err != nil {
+		return nil, resolverErrDatabaseOperation(err)
+	}
+
+	var sr []SessionResolver
+	for _, s := range sessions {
+		sr = append(sr, SessionResolver{app: r.App, session: *s})
+	}
+
+	return &SessionListResolver{total: total, resolvers: sr}, nil
+}
+
+type AnySessionListParams struct {
+	ResolverParams
+	Include AnySessionListIncludeFiltersInput
+}
+
+type AnySessionListIncludeFiltersInput struct {
+	States  []user.SessionActivityState
+	UserIds []graphql.ID
+}
+
+// DeactivateUserAccount: deactivate a user account and revoke its sessions
+func (r Root) DeactivateUserAccount(ctx context.Context, params UserParams) (*UserAccountResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeWrite); err != nil {
+		return nil, err
+	}
+
+	id, ok := localID(params.ID, userAccountNodeType)
+	if !ok {
+		return nil, resolverErrNotFound(user.ErrNotFoundUser)
+	}
+
+	if id == c.User.ID {
+		return nil, resolverErrForbidden(errOwnAccount)
+	}
+
+	if err := r.App.Models.User.DeactivateUserAccount(id); err != nil {
+		return nil, userAccountError(err)
+	}
+
+	return r.userAccount(id)
+}
+
+// ReactivateUserAccount: reactivate a deactivated user account
+func (r Root) ReactivateUserAccount(ctx context.Context, params UserParams) (*UserAccountResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeWrite); err != nil {
+		return nil, err
+	}
+
+	id, ok := localID(params.ID, userAccountNodeType)
+	if !ok {
+		return nil, resolverErrNotFound(user.ErrNotFoundUser)
+	}
+
+	if err := r.App.Models.User.ReactivateUserAccount(id); err != nil {
+		return nil, userAccountError(err)
+	}
+
+	return r.userAccount(id)
+}
+
+// UpdateUserAccountRoles: replace the roles of a user account
+func (r Root) UpdateUserAccountRoles(ctx context.Context, params UpdateUserAccountRolesParams) (*UserAccountResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeWrite); err != nil {
+		return nil, err
+	}
+
+	u := user.User{Roles: params.Roles}
+
+	v := validator.New()
+	u.ValidateRolesEntry(v)
+	if !v.Valid() {
+		return nil, validatorError{Errors: v.Errors}
+	}
+	id, ok := localID(params.ID, userAccountNodeType)
+	if !ok {
+		return nil, resolverErrNotFound(user.ErrNotFoundUser)
+	}
+	// an admin can't remove their own admin role
+	if id == c.User.ID && !u.Roles.Has(user.RoleAdmin) {
+		return nil, resolverErrForbidden(errOwnAccount)
+	}
+
+	if err := r.App.Models.User.UpdateUserRoles(id, u.Roles); err != nil {
+		return nil, userAccountError(err)
+	}
+
+	return r.userAccount(id)
+}
+
+type UpdateUserAccountRolesParams struct {
+	ID    graphql.ID
+	Roles user.Roles
+}
+
+// UnlockUserAccount: forget the failed logins locking a user account
+func (r Root) UnlockUserAccount(ctx context.Context, params UserParams) (bool, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeWrite); err != nil {
+		return false, err
+	}
+
+	id, ok := localID(params.ID, userAccountNodeType)
+	if !ok {
+		return false, resolverErrNotFound(user.ErrNotFoundUser)
+	}
+
+	if _, err := r.userAccount(id); err != nil {
+		return false, err
+	}
+
+	if err := r.App.Models.User.UnlockUserAccount(id); err != nil {
+		return false, resolverErrDatabaseOperation(err)
+	}
+
+	return true, nil
+}
+
+// RevokeUserSession: revoke any session
+func (r Root) RevokeUserSession(ctx context.Context, params RevokeUserSessionParams) (bool, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeWrite); err != nil {
+		return false, err
+	}
+
+	id, ok := localID(params.ID, sessionNodeType)
+	if !ok {
+		return false, resolverErrNotFound(user.ErrNotFoundSession)
+	}
+
+	if _, err := r.App.Models.User.GetSessionByID(id); err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFoundSession):
+			return false, resolverErrNotFound(err)
+		default:
+			return false, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	if err := r.App.Models.User.RevokeUserSession(id); err != nil {
+		return false, resolverErrDatabaseOperation(err)
+	}
+
+	return true, nil
+}
+
+type RevokeUserSessionParams struct {
+	ID graphql.ID
+}
+
+// RevokeAllUserSessions: revoke every active session of a user, returns the number of revoked sessions
+func (r Root) RevokeAllUserSessions(ctx context.Context, params RevokeAllUserSessionsParams) (int32, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeWrite); err != nil {
+		return 0, err
+	}
+
+	userID, ok := localID(params.UserID, userAccountNodeType)
+	if !ok {
+		return 0, resolverErrNotFound(user.ErrNotFoundUser)
+	}
+
+	if _, err := r.userAccount(userID); err != nil {
+		return 0, err
+	}
+
+	n, err := r.App.Models.User.RevokeAllUserSessions(userID)
+	if err != nil {
+		return 0, resolverErrDatabaseOperation(err)
+	}
+
+	return int32(n), nil
+}
+
+type RevokeAllUserSessionsParams struct {
+	UserID graphql.ID
+}
+
+// userAccount returns the resolver of the user account identified by id.
+func (r Root) userAccount(id string) (*UserAccountResolver, error) {
+	u, err := r.App.Models.User.GetById(id)
+	if err != nil {
+		return nil, userAccountError(err)
+	}
+
+	return newUserAccountResolver(r.App, *u), nil
+}
+
+func userAccountError(err error) error {
+	switch {
+	case errors.Is(err, user.ErrNotFoundUser):
+		return resolverErrNotFound(err)
+	default:
+		return resolverErrDatabaseOperation(err)
+	}
+}
@@ -0,0 +1,332 @@
+package resolvers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/brice-74/golang-base-api/internal/testutils/factory"
+	"github.com/brice-74/golang-base-api/internal/utils"
+	"github.com/brice-74/golang-base-api/pkg/validator"
+	"github.com/graph-gophers/graphql-go/gqltesting"
+)
+
+func TestUsers(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	admin := fac.CreateUserAccount(&user.User{Roles: user.Roles{user.RoleAdmin}})
+	u := fac.CreateUserAccount(&user.User{Email: "searched@test.com"})
+	fac.CreateUserAccount(nil)
+
+	var queryString = func(search string, roles string) string {
+		return fmt.Sprintf(`
+			{
+				users(search: "%s", include: { roles: [%s] }) {
+					total
+					list {
+						id
+						email
+					}
+				}
+			}`, search, roles,
+		)
+	}
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectIDs   []string
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should search users by email",
+			gqltest: &gqltesting.Test{
+				Context: app.ContextWithClient(context.Background(), &application.ClientCtx{User: admin}),
+				Schema:  schema,
+				Query:   queryString("searched", ""),
+			},
+			expectIDs: []string{u.ID},
+		},
+		{
+			title: "Should filter users by role",
+			gqltest: &gqltesting.Test{
+				Context: app.ContextWithClient(context.Background(), &application.ClientCtx{User: admin}),
+				Schema:  schema,
+				Query:   queryString("", "ROLE_ADMIN"),
+			},
+			expectIDs: []string{admin.ID},
+		},
+		{
+			title: "Should be forbidden for user",
+			gqltest: &gqltesting.Test{
+				Context: app.ContextWithClient(context.Background(), &application.ClientCtx{User: u}),
+				Schema:  schema,
+				Query:   queryString("", ""),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Forbidden]: Forbidden access",
+				Extensions: map[string]interface{}{
+					"code":       "Forbidden",
+					"statusCode": 403,
+					"message":    "Forbidden access",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(tt.gqltest.Context, tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			var res UsersResponse
+
+			data, _ := result.Data.MarshalJSON()
+			if err := json.Unmarshal(data, &res); err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Users.Total != len(tt.expectIDs) || len(res.Users.List) != len(tt.expectIDs) {
+				t.Fatalf("got %d users, expect %d", res.Users.Total, len(tt.expectIDs))
+			}
+
+			for i, id := range tt.expectIDs {
+				if res.Users.List[i].ID != utils.EncodeGlobalID("UserAccount", id) {
+					t.Fatalf("got user: %s, expect: %s", res.Users.List[i].ID, id)
+				}
+			}
+		})
+	}
+}
+
+type UsersResponse struct {
+	Users struct {
+		Total int
+		List  []struct {
+			ID    string
+			Email string
+		}
+	}
+}
+
+func TestDeactivateUserAccount(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	admin := fac.CreateUserAccount(&user.User{Roles: user.Roles{user.RoleAdmin}})
+	u := fac.CreateUserAccount(nil)
+	s := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+
+	var queryString = func(id string) string {
+		return fmt.Sprintf(`mutation { deactivateUserAccount(id: "%s") { id active } }`, id)
+	}
+
+	adminContext := app.ContextWithClient(context.Background(), &application.ClientCtx{User: admin})
+
+	notFoundUserError := &testutils.ExpectResolverError{
+		Msg: "error [NotFoundError]: User not found",
+		Extensions: map[string]interface{}{
+			"code":       "NotFoundError",
+			"statusCode": 404,
+			"message":    "User not found",
+		},
+	}
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should refuse own account",
+			gqltest: &gqltesting.Test{
+				Context: adminContext,
+				Schema:  schema,
+				Query:   queryString(admin.ID),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Forbidden]: Operation not allowed on your own account",
+				Extensions: map[string]interface{}{
+					"code":       "Forbidden",
+					"statusCode": 403,
+					"message":    "Operation not allowed on your own account",
+				},
+			},
+		},
+		{
+			title: "Should return not found for the id of a session",
+			gqltest: &gqltesting.Test{
+				Context: adminContext,
+				Schema:  schema,
+				Query:   queryString(utils.EncodeGlobalID("Session", s.ID)),
+			},
+			expectError: notFoundUserError,
+		},
+		{
+			title: "Should return not found for a malformed id",
+			gqltest: &gqltesting.Test{
+				Context: adminContext,
+				Schema:  schema,
+				Query:   queryString("not-an-id"),
+			},
+			expectError: notFoundUserError,
+		},
+		{
+			title: "Should deactivate account and revoke sessions",
+			gqltest: &gqltesting.Test{
+				Context: adminContext,
+				Schema:  schema,
+				Query:   queryString(u.ID),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(tt.gqltest.Context, tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			got, err := app.Models.User.GetById(u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !got.Deactivated() {
+				t.Fatal("expect deactivated account")
+			}
+
+			gs, err := app.Models.User.GetSessionByID(s.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if gs.DeactivatedAt.After(time.Now()) {
+				t.Fatal("session should be revoked")
+			}
+		})
+	}
+}
+
+func TestUpdateUserAccountRoles(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	admin := fac.CreateUserAccount(&user.User{Roles: user.Roles{user.RoleAdmin}})
+	u := fac.CreateUserAccount(nil)
+
+	var queryString = func(id string, roles string) string {
+		return fmt.Sprintf(`mutation { updateUserAccountRoles(id: "%s", roles: [%s]) { id roles } }`, id, roles)
+	}
+
+	adminContext := app.ContextWithClient(context.Background(), &application.ClientCtx{User: admin})
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should return validatorError",
+			gqltest: &gqltesting.Test{
+				Context: adminContext,
+				Schema:  schema,
+				Query:   queryString(u.ID, "ROLE_ANONYMOUS"),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "validation error [ValidatorError]",
+				Extensions: map[string]interface{}{
+					"code":       "ValidatorError",
+					"statusCode": 422,
+					"errors": validator.Errors{
+						"roles": []string{"can't assign role ROLE_ANONYMOUS"},
+					},
+				},
+			},
+		},
+		{
+			title: "Should refuse to remove own admin role",
+			gqltest: &gqltesting.Test{
+				Context: adminContext,
+				Schema:  schema,
+				Query:   queryString(admin.ID, "ROLE_USER"),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Forbidden]: Operation not allowed on your own account",
+				Extensions: map[string]interface{}{
+					"code":       "Forbidden",
+					"statusCode": 403,
+					"message":    "Operation not allowed on your own account",
+				},
+			},
+		},
+		{
+			title: "Should update roles",
+			gqltest: &gqltesting.Test{
+				Context: adminContext,
+				Schema:  schema,
+				Query:   queryString(u.ID, "ROLE_USER, ROLE_ADMIN"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(tt.gqltest.Context, tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			got, err := app.Models.User.GetById(u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !got.Roles.Has(user.RoleAdmin) {
+				t.Fatalf("got roles: %v, expect admin", got.Roles)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+)
+
+// audit records an action in the audit log of an account, with the ip of the client if
+// known. A failure is only logged so that the action itself isn't undone.
+func (r Root) audit(ctx context.Context, userID string, action user.AuditAction) {
+	e := &user.AuditEntry{
+		Action: action,
+		UserID: userID,
+	}
+	// actions using a token sent by email may be done without client
+	if c, ok := ctx.Value(application.ClientCtxKey).(*application.ClientCtx); ok && c.Agent != nil {
+		e.IP = c.Agent.IP
+	}
+
+	if err := r.App.Models.User.InsertAuditEntry(e); err != nil {
+		r.App.Logger.PrintError(err, map[string]string{
+			"audit entry": userID,
+			"action":      string(action),
+		})
+	}
+}
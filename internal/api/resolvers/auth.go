@@ -7,10 +7,11 @@ import (
 
 	"github.com/brice-74/golang-base-api/internal/api/application"
 	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/pkg/password"
 	"github.com/brice-74/golang-base-api/pkg/validator"
 	"github.com/graph-gophers/graphql-go"
+	"github.com/twinj/uuid"
 	"github.com/ventu-io/go-shortid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // RegisterUserAccount: register a new user account
@@ -23,7 +24,9 @@ func (r Root) RegisterUserAccount(_ context.Context, params RegisterUserAccountP
 	// check that all entries are valid
 	v := validator.New()
 	u.ValidateEmailEntry(v)
-	u.ValidatePasswordEntry(v)
+	if err := r.validateNewPassword(v, u); err != nil {
+		return nil, err
+	}
 	u.ValidateProfilNameEntry(v)
 	if !v.Valid() {
 		return nil, validatorError{Errors: v.Errors}
@@ -35,10 +38,10 @@ func (r Root) RegisterUserAccount(_ context.Context, params RegisterUserAccountP
 		u.ShortId = short
 	}
 	// hash password
-	if hash, err := bcrypt.GenerateFromPassword([]byte(u.Password), 14); err != nil {
+	if hash, err := r.App.Passwords.Hash(u.Password); err != nil {
 		return nil, err
 	} else {
-		u.Password = string(hash)
+		u.Password = hash
 	}
 	// add user role
 	u.Roles = []user.Role{user.RoleUser}
@@ -46,8 +49,14 @@ func (r Root) RegisterUserAccount(_ context.Context, params RegisterUserAccountP
 	if err := r.App.Models.User.InsertRegisteredUserAccount(&u); err != nil {
 		return nil, resolverErrDatabaseOperation(err)
 	}
+	// the account is created even if the email can't be sent, it can be sent again
+	if err := r.sendVerificationEmail(&u); err != nil {
+		r.App.Logger.PrintError(err, map[string]string{
+			"verification email": u.ID,
+		})
+	}
 
-	return &UserAccountResolver{app: r.App, user: u}, nil
+	return newUserAccountResolver(r.App, u), nil
 }
 
 type RegisterUserAccountParams struct {
@@ -60,13 +69,119 @@ type RegisterUserAccountInput struct {
 	ProfilName string
 }
 
-// LoginUserAccount: authenticate a user by returning tokens
-func (r Root) LoginUserAccount(ctx context.Context, params LoginUserAccountParams) (*TokensUserAccountResolver, error) {
+// LoginUserAccount: authenticate a user by returning tokens of a new session,
+// or a challenge to verify when the user has enabled 2FA
+func (r Root) LoginUserAccount(ctx context.Context, params LoginUserAccountParams) (*AuthResultResolver, error) {
+	uReg, err := r.checkCredentials(ctx, params.Email, params.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if uReg.MfaEnabled() {
+		return r.mfaChallenge(uReg.ID, "")
+	}
+
+	tokens, err := r.openSession(ctx, uReg.ID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResultResolver{result: tokens}, nil
+}
+
+type LoginUserAccountParams struct {
+	Email    string
+	Password string
+}
+
+// ReloginUserAccount: authenticate a user again in one of his existing sessions
+func (r Root) ReloginUserAccount(ctx context.Context, params ReloginUserAccountParams) (*AuthResultResolver, error) {
+	uReg, err := r.checkCredentials(ctx, params.Email, params.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, ok := localID(params.SessionID, sessionNodeType)
+	if !ok {
+		return nil, resolverErrNotFound(user.ErrNotFoundSession)
+	}
+
+	if uReg.MfaEnabled() {
+		return r.mfaChallenge(uReg.ID, sessionID)
+	}
+
+	tokens, err := r.openSession(ctx, uReg.ID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResultResolver{result: tokens}, nil
+}
+
+type ReloginUserAccountParams struct {
+	Email     string
+	Password  string
+	SessionID graphql.ID
+}
+
+// openSession returns the tokens of a new session, or reopens sessionID when not empty,
+// only if the session belongs to the user and hasn't been revoked.
+func (r Root) openSession(ctx context.Context, userID string, sessionID string) (*TokensUserAccountResolver, error) {
+	uctx := r.App.ClientFromContext(ctx)
+
+	reopen := sessionID != ""
+	// the session identifier is always generated by the server
+	if !reopen {
+		sessionID = uuid.NewV4().String()
+	}
+	// create jwt access & refresh
+	td, err := r.App.CreateTokens(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &user.Session{
+		ID:             sessionID,
+		DeactivatedAt:  time.Unix(td.RefreshExp, 0),
+		IP:             uctx.Agent.IP,
+		Agent:          uctx.Agent.Agent,
+		UserID:         userID,
+		RefreshTokenID: td.RefreshID,
+	}
+
+	if reopen {
+		err = r.App.Models.User.UpdateUserSession(s)
+	} else {
+		err = r.App.Models.User.InsertUserSession(s)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFoundSession):
+			return nil, resolverErrNotFound(err)
+		case errors.Is(err, user.ErrSessionRevoked):
+			return nil, resolverErrInactiveClient(err)
+		default:
+			return nil, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	r.audit(ctx, userID, user.AuditLogin)
+
+	return &TokensUserAccountResolver{app: r.App, tokens: user.Tokens{
+		Access:    td.AccessToken,
+		Refresh:   td.RefreshToken,
+		SessionID: sessionID,
+	}}, nil
+}
+
+// checkCredentials returns the registered user matching email and password.
+// Failed attempts are recorded to lock the logins of the account and of the client IP.
+func (r Root) checkCredentials(ctx context.Context, email, plainPassword string) (*user.User, error) {
 	uctx := r.App.ClientFromContext(ctx)
 
 	uEntry := user.User{
-		Email:    params.Email,
-		Password: params.Password,
+		Email:    email,
+		Password: plainPassword,
 	}
 	// check that all entries are valid
 	v := validator.New()
@@ -77,61 +192,101 @@ func (r Root) LoginUserAccount(ctx context.Context, params LoginUserAccountParam
 	}
 	// find registered user
 	uReg, err := r.App.Models.User.GetByEmail(uEntry.Email)
+	if err != nil && err != user.ErrNotFoundUser {
+		return nil, resolverErrDatabaseOperation(err)
+	}
+
+	var userID string
+	if uReg != nil {
+		userID = uReg.ID
+	}
+	// the attempt is counted before checking the password, locked logins are refused
+	attempt, err := r.beginLoginAttempt(userID, uctx.Agent.IP)
 	if err != nil {
-		if err == user.ErrNotFoundUser {
-			return nil, resolverErrNotFound(err)
-		} else {
-			return nil, resolverErrDatabaseOperation(err)
+		return nil, err
+	}
+
+	if uReg == nil {
+		if err = r.failLoginAttempt(attempt); err != nil {
+			return nil, err
 		}
+		return nil, resolverErrNotFound(user.ErrNotFoundUser)
 	}
 	// check password
-	if err = bcrypt.CompareHashAndPassword([]byte(uReg.Password), []byte(uEntry.Password)); err != nil {
-		return nil, resolverErrUnauthorized(errors.New("incorrect password"))
+	if ok, err := password.Verify(uEntry.Password, uReg.Password); err != nil {
+		return nil, err
+	} else if !ok {
+		if err = r.failLoginAttempt(attempt); err != nil {
+			return nil, err
+		}
+		return nil, resolverErrUnauthorized(errIncorrectPassword)
 	}
-	// create jwt access & refresh
-	td, err := r.App.CreateTokens(uReg.ID, string(params.SessionID))
-	if err != nil {
+	// a successful login resets the backoff of the account, only once the code is
+	// verified for accounts with 2FA so logging in again doesn't reset guesses of codes
+	if err = r.succeedLoginAttempt(attempt, !uReg.MfaEnabled()); err != nil {
 		return nil, err
 	}
-	// insert session information or update if user need re login
-	if err = r.App.Models.User.InsertOrUpdateUserSession(
-		&user.Session{
-			ID:            string(params.SessionID),
-			DeactivatedAt: time.Unix(td.RefreshExp, 0),
-			IP:            uctx.Agent.IP,
-			Agent:         uctx.Agent.Agent,
-			UserID:        uReg.ID,
-		},
-	); err != nil {
-		return nil, resolverErrDatabaseOperation(err)
+	if err = r.checkLoginAllowed(uReg); err != nil {
+		return nil, err
 	}
-	// everything is good, return tokens using resolver
-	return &TokensUserAccountResolver{app: r.App, tokens: user.Tokens{
-		Access:  td.AccessToken,
-		Refresh: td.RefreshToken,
-	}}, nil
+	// hashes of an outdated algorithm or parameters are upgraded while the password is known
+	if r.App.Passwords.NeedsRehash(uReg.Password) {
+		r.rehashPassword(uReg, uEntry.Password)
+	}
+
+	return uReg, nil
 }
 
-type LoginUserAccountParams struct {
-	Email     string
-	Password  string
-	SessionID graphql.ID
+// checkLoginAllowed returns an error if the account can't open a session, deactivated or
+// unverified while verification is required.
+func (r Root) checkLoginAllowed(u *user.User) error {
+	if u.Deactivated() {
+		return resolverErrInactiveClient(user.ErrDeactivatedUser)
+	}
+	// unverified accounts can't log in when verification is required
+	if r.App.Config.EmailVerification.Mode == application.EmailVerificationRequired && !u.Verified() {
+		return resolverErrForbidden(errEmailNotVerified)
+	}
+
+	return nil
+}
+
+// rehashPassword replaces the hash of a user password by a hash of the current hasher,
+// a failure is only logged since the old hash still works.
+func (r Root) rehashPassword(u *user.User, plainPassword string) {
+	hash, err := r.App.Passwords.Hash(plainPassword)
+	if err == nil {
+		err = r.App.Models.User.UpdateUserPassword(u.ID, hash)
+	}
+
+	if err != nil {
+		r.App.Logger.PrintError(err, map[string]string{
+			"password rehash": u.ID,
+		})
+		return
+	}
+
+	u.Password = hash
 }
 
 func (r Root) RefreshUserAccount(ctx context.Context, params RefreshUserAccountParams) (*TokensUserAccountResolver, error) {
 	uctx := r.App.ClientFromContext(ctx)
 	// check token is valid and up to date
-	token, err := application.VerifyToken(params.Token, r.App.Config.JWT.Refresh.Secret)
+	token, err := r.App.RefreshKeys().Verify(params.Token)
 	if err != nil {
 		return nil, err
 	}
 	// extract token claims
-	claims, err := application.ExtractTokenMetadata(token, []application.JwtClaimKey{application.UserIdClaim, application.SessionIdClaim})
+	claims, err := application.ExtractTokenMetadata(token, []application.JwtClaimKey{
+		application.UserIdClaim,
+		application.SessionIdClaim,
+		application.RefreshIdClaim,
+	})
 	if err != nil {
 		return nil, errors.New("Required claims from token not found")
 	}
 	// get session and verify that user id claim is associated to session id claim
-	_, s, err := r.App.Models.User.GetUserAndSession(claims[application.UserIdClaim], claims[application.SessionIdClaim])
+	u, s, err := r.App.Models.User.GetUserAndSession(claims[application.UserIdClaim], claims[application.SessionIdClaim])
 	if err != nil {
 		switch {
 		case errors.Is(err, user.ErrNotFoundUserAndSession):
@@ -140,30 +295,60 @@ func (r Root) RefreshUserAccount(ctx context.Context, params RefreshUserAccountP
 			return nil, resolverErrDatabaseOperation(err)
 		}
 	}
+	// the sessions of a deactivated account have been revoked by the deactivation
+	if u.Deactivated() {
+		return nil, resolverErrInactiveClient(user.ErrDeactivatedUser)
+	}
+	// a revoked or expired session can't be reopened by a refresh
+	if err = s.CheckActive(); err != nil {
+		return nil, resolverErrInactiveClient(err)
+	}
+	// a refresh token which isn't the last issued one has already been exchanged,
+	// it has probably been stolen so the whole session is revoked
+	if s.RefreshTokenID != claims[application.RefreshIdClaim] {
+		return nil, r.revokeReusedSession(s.ID)
+	}
 	// create new tokens
 	td, err := r.App.CreateTokens(claims[application.UserIdClaim], claims[application.SessionIdClaim])
 	if err != nil {
 		return nil, err
 	}
-	// update session information
-	if err = r.App.Models.User.InsertOrUpdateUserSession(
+	// update session information, the previous refresh token can't be used anymore
+	if err = r.App.Models.User.RotateSessionRefreshToken(
 		&user.Session{
-			ID:            s.ID,
-			DeactivatedAt: time.Unix(td.RefreshExp, 0),
-			IP:            uctx.Agent.IP,
-			Agent:         uctx.Agent.Agent,
-			UserID:        s.UserID,
+			ID:             s.ID,
+			DeactivatedAt:  time.Unix(td.RefreshExp, 0),
+			IP:             uctx.Agent.IP,
+			Agent:          uctx.Agent.Agent,
+			UserID:         s.UserID,
+			RefreshTokenID: td.RefreshID,
 		},
+		s.RefreshTokenID,
 	); err != nil {
-		return nil, resolverErrDatabaseOperation(err)
+		switch {
+		case errors.Is(err, user.ErrRefreshTokenReused):
+			return nil, r.revokeReusedSession(s.ID)
+		default:
+			return nil, resolverErrDatabaseOperation(err)
+		}
 	}
 
 	return &TokensUserAccountResolver{app: r.App, tokens: user.Tokens{
-		Access:  td.AccessToken,
-		Refresh: td.RefreshToken,
+		Access:    td.AccessToken,
+		Refresh:   td.RefreshToken,
+		SessionID: s.ID,
 	}}, nil
 }
 
+// revokeReusedSession revokes a session whose refresh token has been used more than once.
+func (r Root) revokeReusedSession(sessionID string) error {
+	if err := r.App.Models.User.RevokeUserSession(sessionID); err != nil {
+		return resolverErrDatabaseOperation(err)
+	}
+
+	return resolverErrUnauthorized(user.ErrRefreshTokenReused)
+}
+
 type RefreshUserAccountParams struct {
 	Token string
 }
@@ -173,6 +358,21 @@ type TokensUserAccountResolver struct {
 	tokens user.Tokens
 }
 
+// AuthResultResolver resolves either tokens or a 2FA challenge.
+type AuthResultResolver struct {
+	result interface{}
+}
+
+func (r AuthResultResolver) ToTokens() (*TokensUserAccountResolver, bool) {
+	res, ok := r.result.(*TokensUserAccountResolver)
+	return res, ok
+}
+
+func (r AuthResultResolver) ToMfaChallenge() (*MfaChallengeResolver, bool) {
+	res, ok := r.result.(*MfaChallengeResolver)
+	return res, ok
+}
+
 func (r TokensUserAccountResolver) Access() string {
 	return r.tokens.Access
 }
@@ -181,20 +381,72 @@ func (r TokensUserAccountResolver) Refresh() string {
 	return r.tokens.Refresh
 }
 
+func (r TokensUserAccountResolver) SessionId() graphql.ID {
+	return globalID(sessionNodeType, r.tokens.SessionID)
+}
+
 func (r Root) LogoutUserAccount(ctx context.Context) (bool, error) {
 	c := r.App.ClientFromContext(ctx)
 
-	if err := r.App.Models.User.InsertOrUpdateUserSession(
-		&user.Session{
-			ID:            c.Session.ID,
-			DeactivatedAt: time.Now(),
-			IP:            c.Agent.IP,
-			Agent:         c.Agent.Agent,
-			UserID:        c.User.ID,
-		},
-	); err != nil {
+	if err := requireSession(c); err != nil {
+		return false, err
+	}
+	// the refresh token of the session can't be used anymore once deactivated
+	if err := r.App.Models.User.RevokeUserSession(c.Session.ID); err != nil {
 		return false, resolverErrDatabaseOperation(err)
 	}
 
 	return true, nil
 }
+
+// RevokeSession: deactivate one of the sessions of the logged user
+func (r Root) RevokeSession(ctx context.Context, params RevokeSessionParams) (bool, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireSession(c); err != nil {
+		return false, err
+	}
+
+	id, ok := localID(params.ID, sessionNodeType)
+	if !ok {
+		return false, resolverErrNotFound(user.ErrNotFoundSession)
+	}
+
+	if err := r.App.Models.User.RevokeOwnedUserSession(id, c.User.ID); err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFoundSession):
+			return false, resolverErrNotFound(err)
+		default:
+			return false, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	r.audit(ctx, c.User.ID, user.AuditSessionRevoke)
+
+	return true, nil
+}
+
+type RevokeSessionParams struct {
+	ID graphql.ID
+}
+
+// RevokeAllOtherSessions: deactivate every session of the logged user except the current one,
+// returns the number of revoked sessions
+func (r Root) RevokeAllOtherSessions(ctx context.Context) (int32, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireSession(c); err != nil {
+		return 0, err
+	}
+
+	n, err := r.App.Models.User.RevokeOtherUserSessions(c.User.ID, c.Session.ID)
+	if err != nil {
+		return 0, resolverErrDatabaseOperation(err)
+	}
+
+	if n > 0 {
+		r.audit(ctx, c.User.ID, user.AuditSessionRevoke)
+	}
+
+	return int32(n), nil
+}
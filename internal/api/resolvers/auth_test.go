@@ -12,6 +12,8 @@ import (
 	"github.com/brice-74/golang-base-api/internal/testutils"
 	"github.com/brice-74/golang-base-api/internal/testutils/factory"
 	"github.com/brice-74/golang-base-api/internal/testutils/mocks"
+	"github.com/brice-74/golang-base-api/internal/utils"
+	"github.com/brice-74/golang-base-api/pkg/password"
 	"github.com/brice-74/golang-base-api/pkg/validator"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/graph-gophers/graphql-go/gqltesting"
@@ -28,7 +30,7 @@ func TestRegisterUserAccount(t *testing.T) {
 
 	const (
 		email      string = "test@test.com"
-		password   string = "passWORD123!"
+		strPass    string = "passWORD123!"
 		profilName string = "name"
 	)
 
@@ -45,7 +47,6 @@ func TestRegisterUserAccount(t *testing.T) {
 					updatedAt
 					active
 					email
-					password
 					roles
 					profilName
 					shortId
@@ -63,14 +64,14 @@ func TestRegisterUserAccount(t *testing.T) {
 			title: "Should insert and return available user",
 			gqltest: &gqltesting.Test{
 				Schema: schema,
-				Query:  queryString(email, password, profilName),
+				Query:  queryString(email, strPass, profilName),
 			},
 		},
 		{
 			title: "Should return database error conflict",
 			gqltest: &gqltesting.Test{
 				Schema: schema,
-				Query:  queryString(email, password, profilName),
+				Query:  queryString(email, strPass, profilName),
 			},
 			expectError: &testutils.ExpectResolverError{
 				Msg: "error [DatabaseOperationError]: Duplicate email",
@@ -99,8 +100,18 @@ func TestRegisterUserAccount(t *testing.T) {
 
 				var u = res.RegisterUserAccount
 
-				if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
-					t.Fatal("incorrect password from resolver")
+				_, id, err := utils.DecodeGlobalID(u.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := app.Models.User.GetById(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if ok, _ := password.Verify(strPass, got.Password); !ok {
+					t.Fatal("incorrect stored password")
 				}
 
 				testUserOutput := []struct {
@@ -133,25 +144,26 @@ type RegisterUserAccountResponse struct {
 
 func TestLoginUserAccount(t *testing.T) {
 	var (
-		db        = testutils.PrepareDB(t)
-		app       = testutils.NewApplication(db)
-		schema    = testutils.ParseTestSchema(app)
-		fac       = factory.New(t, db)
-		sessionID = uuid.NewV4().String()
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
 	)
 
-	var queryString = func(email, pass, sessionID string) string {
+	var queryString = func(email, pass string) string {
 		return fmt.Sprintf(`
 			mutation {
 				loginUserAccount(
 					email: "%s",
-					password: "%s",
-					sessionID: "%s"
+					password: "%s"
 				) {
-					access
-					refresh
+					... on Tokens {
+						access
+						refresh
+						sessionId
+					}
 				}
-			}`, email, pass, sessionID,
+			}`, email, pass,
 		)
 	}
 
@@ -178,7 +190,7 @@ func TestLoginUserAccount(t *testing.T) {
 			gqltest: &gqltesting.Test{
 				Schema:  schema,
 				Context: queryContext,
-				Query:   queryString(u.Email, strPass, sessionID),
+				Query:   queryString(u.Email, strPass),
 			},
 		},
 		{
@@ -186,7 +198,7 @@ func TestLoginUserAccount(t *testing.T) {
 			gqltest: &gqltesting.Test{
 				Schema:  schema,
 				Context: queryContext,
-				Query:   queryString("bad email", "bad pass", sessionID),
+				Query:   queryString("bad email", "bad pass"),
 			},
 			expectError: &testutils.ExpectResolverError{
 				Msg: "validation error [ValidatorError]",
@@ -209,7 +221,7 @@ func TestLoginUserAccount(t *testing.T) {
 			gqltest: &gqltesting.Test{
 				Schema:  schema,
 				Context: queryContext,
-				Query:   queryString("unknow@email.com", strPass, sessionID),
+				Query:   queryString("unknow@email.com", strPass),
 			},
 			expectError: &testutils.ExpectResolverError{
 				Msg: "error [NotFoundError]: User not found",
@@ -225,7 +237,7 @@ func TestLoginUserAccount(t *testing.T) {
 			gqltest: &gqltesting.Test{
 				Schema:  schema,
 				Context: queryContext,
-				Query:   queryString(u.Email, "IncorrectPass123!", sessionID),
+				Query:   queryString(u.Email, "IncorrectPass123!"),
 			},
 			expectError: &testutils.ExpectResolverError{
 				Msg: "error [Unauthorized]: incorrect password",
@@ -260,13 +272,18 @@ func TestLoginUserAccount(t *testing.T) {
 					t.Fatalf("Refresh token verification fail: %s", err.Error())
 				}
 
+				_, sessionID, err := utils.DecodeGlobalID(res.LoginUserAccount.SessionID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
 				s, err := app.Models.User.GetSessionByID(sessionID)
 				if err != nil {
 					t.Fatalf("error during database session recovery: %s", err.Error())
 				}
 
-				if s == nil {
-					t.Fatal("expect existing session, got nil")
+				if s.UserID != u.ID {
+					t.Fatalf("got session user: %s, expect: %s", s.UserID, u.ID)
 				}
 			}
 		})
@@ -277,6 +294,233 @@ type LoginUserAccountResponse struct {
 	LoginUserAccount user.Tokens
 }
 
+func TestLoginUserAccountRehash(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	const strPass = "Test123!"
+	u := fac.CreateUserAccount(nil)
+	// hash of an account created before the switch to argon2id
+	hash, err := password.Bcrypt{Cost: bcrypt.MinCost}.Hash(strPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = app.Models.User.UpdateUserPassword(u.ID, hash); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := app.ContextWithClient(context.Background(), &application.ClientCtx{
+		Agent: &application.Agent{
+			IP:    "0.0.0.0",
+			Agent: "agent",
+		},
+	})
+
+	result := schema.Exec(ctx, fmt.Sprintf(`
+		mutation {
+			loginUserAccount(email: "%s", password: "%s") {
+				... on Tokens {
+					sessionId
+				}
+			}
+		}`, u.Email, strPass), "", nil)
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors[0])
+	}
+
+	got, err := app.Models.User.GetById(u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if app.Passwords.NeedsRehash(got.Password) {
+		t.Fatalf("got hash: %s, expect upgraded to the current hasher", got.Password)
+	}
+
+	if ok, _ := password.Verify(strPass, got.Password); !ok {
+		t.Fatal("upgraded hash should match the password")
+	}
+}
+
+func TestReloginUserAccount(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	var queryString = func(email, pass, sessionID string) string {
+		return fmt.Sprintf(`
+			mutation {
+				reloginUserAccount(
+					email: "%s",
+					password: "%s",
+					sessionID: "%s"
+				) {
+					... on Tokens {
+						access
+						refresh
+						sessionId
+					}
+				}
+			}`, email, pass, sessionID,
+		)
+	}
+
+	var queryContext = app.ContextWithClient(context.Background(), &application.ClientCtx{
+		Agent: &application.Agent{
+			IP:    "0.0.0.0",
+			Agent: "agent",
+		},
+	})
+
+	const strPass = "Test123!"
+	u := fac.CreateUserAccount(&user.User{
+		Email:    "test@test.com",
+		Password: strPass,
+	})
+	s := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now(),
+	})
+
+	other := fac.CreateUserAccount(&user.User{
+		Email:    "other@test.com",
+		Password: strPass,
+	})
+	otherSession := fac.CreateUserSession(&user.Session{
+		UserID:        other.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+
+	revokedSession := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+	if err := app.Models.User.RevokeUserSession(revokedSession.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should reopen session and return available tokens",
+			gqltest: &gqltesting.Test{
+				Schema:  schema,
+				Context: queryContext,
+				Query:   queryString(u.Email, strPass, s.ID),
+			},
+		},
+		{
+			title: "Should return not found session of another user",
+			gqltest: &gqltesting.Test{
+				Schema:  schema,
+				Context: queryContext,
+				Query:   queryString(u.Email, strPass, otherSession.ID),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [NotFoundError]: User session not found",
+				Extensions: map[string]interface{}{
+					"code":       "NotFoundError",
+					"statusCode": 404,
+					"message":    "User session not found",
+				},
+			},
+		},
+		{
+			title: "Should not reopen revoked session",
+			gqltest: &gqltesting.Test{
+				Schema:  schema,
+				Context: queryContext,
+				Query:   queryString(u.Email, strPass, revokedSession.ID),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [SessionRevoked]: Session revoked",
+				Extensions: map[string]interface{}{
+					"code":       "SessionRevoked",
+					"statusCode": 401,
+					"message":    "Session revoked",
+				},
+			},
+		},
+		{
+			title: "Should return not found unknown session",
+			gqltest: &gqltesting.Test{
+				Schema:  schema,
+				Context: queryContext,
+				Query:   queryString(u.Email, strPass, uuid.NewV4().String()),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [NotFoundError]: User session not found",
+				Extensions: map[string]interface{}{
+					"code":       "NotFoundError",
+					"statusCode": 404,
+					"message":    "User session not found",
+				},
+			},
+		},
+		{
+			title: "Should return incorrect password",
+			gqltest: &gqltesting.Test{
+				Schema:  schema,
+				Context: queryContext,
+				Query:   queryString(u.Email, "IncorrectPass123!", s.ID),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Unauthorized]: incorrect password",
+				Extensions: map[string]interface{}{
+					"code":       "Unauthorized",
+					"statusCode": 401,
+					"message":    "incorrect password",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(tt.gqltest.Context, tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+			} else {
+				var res ReloginUserAccountResponse
+
+				data, _ := result.Data.MarshalJSON()
+				if err := json.Unmarshal(data, &res); err != nil {
+					t.Fatal(err)
+				}
+
+				if res.ReloginUserAccount.SessionID != s.ID {
+					t.Fatalf("got session: %s, expect: %s", res.ReloginUserAccount.SessionID, s.ID)
+				}
+
+				got, err := app.Models.User.GetSessionByID(s.ID)
+				if err != nil {
+					t.Fatalf("error during database session recovery: %s", err.Error())
+				}
+
+				if !got.DeactivatedAt.After(time.Now()) {
+					t.Fatal("expect reopened session")
+				}
+			}
+		})
+	}
+}
+
+type ReloginUserAccountResponse struct {
+	ReloginUserAccount user.Tokens
+}
+
 func TestRefreshUserAccount(t *testing.T) {
 	var (
 		db     = testutils.PrepareDB(t)
@@ -311,17 +555,46 @@ func TestRefreshUserAccount(t *testing.T) {
 	})
 
 	s := fac.CreateUserSession(&user.Session{
-		UserID: u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour).UTC().Round(time.Second),
+		UserID:        u.ID,
+	})
+
+	sExp := fac.CreateUserSession(&user.Session{
+		DeactivatedAt: time.Date(2021, 0, 0, 0, 0, 0, 0, time.UTC),
+		UserID:        u.ID,
+	})
+
+	sRevoked := fac.CreateUserSession(&user.Session{
+		DeactivatedAt: time.Now().Add(time.Hour),
+		UserID:        u.ID,
+	})
+	if err := app.Models.User.RevokeUserSession(sRevoked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	uDeactivated := fac.CreateUserAccount(nil)
+	sDeactivated := fac.CreateUserSession(&user.Session{
+		DeactivatedAt: time.Now().Add(time.Hour),
+		UserID:        uDeactivated.ID,
 	})
+	if err := app.Models.User.DeactivateUserAccount(uDeactivated.ID); err != nil {
+		t.Fatal(err)
+	}
 
 	exp := time.Now().Add(time.Minute * 3)
 
 	uuid := uuid.NewV4().String()
-	goodClaims := mocks.CreateClaims(u.ID, s.ID, exp)
-	badUuidClaims := mocks.CreateClaims("", "", exp)
-	badIdsClaims := mocks.CreateClaims(uuid, uuid, exp)
+	goodClaims := mocks.CreateRefreshClaims(u.ID, s.ID, s.RefreshTokenID, exp)
+	expSessionClaims := mocks.CreateRefreshClaims(u.ID, sExp.ID, sExp.RefreshTokenID, exp)
+	revokedSessionClaims := mocks.CreateRefreshClaims(u.ID, sRevoked.ID, sRevoked.RefreshTokenID, exp)
+	deactivatedUserClaims := mocks.CreateRefreshClaims(uDeactivated.ID, sDeactivated.ID, sDeactivated.RefreshTokenID, exp)
+	badUuidClaims := mocks.CreateRefreshClaims("", "", uuid, exp)
+	badIdsClaims := mocks.CreateRefreshClaims(uuid, uuid, uuid, exp)
 
 	goodToken := mocks.CreateToken(t, jwt.SigningMethodHS256, goodClaims, app.Config.JWT.Refresh.Secret)
+	expSessionToken := mocks.CreateToken(t, jwt.SigningMethodHS256, expSessionClaims, app.Config.JWT.Refresh.Secret)
+	revokedSessionToken := mocks.CreateToken(t, jwt.SigningMethodHS256, revokedSessionClaims, app.Config.JWT.Refresh.Secret)
+	deactivatedUserToken := mocks.CreateToken(t, jwt.SigningMethodHS256, deactivatedUserClaims, app.Config.JWT.Refresh.Secret)
 	badClaimsToken := mocks.CreateToken(t, jwt.SigningMethodHS256, nil, app.Config.JWT.Refresh.Secret)
 	badUuidToken := mocks.CreateToken(t, jwt.SigningMethodHS256, badUuidClaims, app.Config.JWT.Refresh.Secret)
 	badIdsToken := mocks.CreateToken(t, jwt.SigningMethodHS256, badIdsClaims, app.Config.JWT.Refresh.Secret)
@@ -339,6 +612,70 @@ func TestRefreshUserAccount(t *testing.T) {
 				Query:   queryString(goodToken),
 			},
 		},
+		{
+			title: "Should return refresh token already used",
+			gqltest: &gqltesting.Test{
+				Schema:  schema,
+				Context: queryContext,
+				Query:   queryString(goodToken),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Unauthorized]: Refresh token already used",
+				Extensions: map[string]interface{}{
+					"code":       "Unauthorized",
+					"statusCode": 401,
+					"message":    "Refresh token already used",
+				},
+			},
+		},
+		{
+			title: "Should return session expired",
+			gqltest: &gqltesting.Test{
+				Schema:  schema,
+				Context: queryContext,
+				Query:   queryString(expSessionToken),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [SessionExpired]: Session expired",
+				Extensions: map[string]interface{}{
+					"code":       "SessionExpired",
+					"statusCode": 401,
+					"message":    "Session expired",
+				},
+			},
+		},
+		{
+			title: "Should return session revoked",
+			gqltest: &gqltesting.Test{
+				Schema:  schema,
+				Context: queryContext,
+				Query:   queryString(revokedSessionToken),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [SessionRevoked]: Session revoked",
+				Extensions: map[string]interface{}{
+					"code":       "SessionRevoked",
+					"statusCode": 401,
+					"message":    "Session revoked",
+				},
+			},
+		},
+		{
+			title: "Should return account deactivated",
+			gqltest: &gqltesting.Test{
+				Schema:  schema,
+				Context: queryContext,
+				Query:   queryString(deactivatedUserToken),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [AccountDeactivated]: User account deactivated",
+				Extensions: map[string]interface{}{
+					"code":       "AccountDeactivated",
+					"statusCode": 403,
+					"message":    "User account deactivated",
+				},
+			},
+		},
 		{
 			title: "Should return claims not found from token",
 			gqltest: &gqltesting.Test{
@@ -408,6 +745,17 @@ func TestRefreshUserAccount(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("Should have revoked the session after token reuse", func(t *testing.T) {
+		got, err := app.Models.User.GetSessionByID(s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.DeactivatedAt.After(time.Now()) {
+			t.Fatalf("session should be deactivated, got deactivated at: %s", got.DeactivatedAt)
+		}
+	})
 }
 
 type RefreshUserAccountResponse struct {
@@ -449,3 +797,136 @@ func TestLogoutUserAccount(t *testing.T) {
 		`,
 	})
 }
+
+func TestRevokeSession(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+	current := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+	s := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+	other := fac.CreateUserSession(&user.Session{
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+
+	var queryString = func(id string) string {
+		return fmt.Sprintf(`mutation { revokeSession(id: "%s") }`, id)
+	}
+
+	queryContext := app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Session: current})
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should return not found session of another user",
+			gqltest: &gqltesting.Test{
+				Context: queryContext,
+				Schema:  schema,
+				Query:   queryString(other.ID),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [NotFoundError]: User session not found",
+				Extensions: map[string]interface{}{
+					"code":       "NotFoundError",
+					"statusCode": 404,
+					"message":    "User session not found",
+				},
+			},
+		},
+		{
+			title: "Should revoke session",
+			gqltest: &gqltesting.Test{
+				Context: queryContext,
+				Schema:  schema,
+				Query:   queryString(s.ID),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(tt.gqltest.Context, tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			gs, err := app.Models.User.GetSessionByID(s.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if gs.DeactivatedAt.After(time.Now()) {
+				t.Fatal("session should be revoked")
+			}
+		})
+	}
+}
+
+func TestRevokeAllOtherSessions(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+
+	var sessions []*user.Session
+	for i := 0; i < 3; i++ {
+		sessions = append(sessions, fac.CreateUserSession(&user.Session{
+			UserID:        u.ID,
+			DeactivatedAt: time.Now().Add(time.Hour),
+		}))
+	}
+	current := sessions[0]
+
+	result := schema.Exec(
+		app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Session: current}),
+		`mutation { revokeAllOtherSessions }`, "", nil,
+	)
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors[0])
+	}
+
+	var res struct{ RevokeAllOtherSessions int }
+
+	data, _ := result.Data.MarshalJSON()
+	if err := json.Unmarshal(data, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.RevokeAllOtherSessions != 2 {
+		t.Fatalf("got %d revoked sessions, expect 2", res.RevokeAllOtherSessions)
+	}
+
+	for _, s := range sessions {
+		gs, err := app.Models.User.GetSessionByID(s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if active := gs.DeactivatedAt.After(time.Now()); active != (s.ID == current.ID) {
+			t.Fatalf("got session %s active: %t", s.ID, active)
+		}
+	}
+}
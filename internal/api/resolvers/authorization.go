@@ -0,0 +1,151 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/introspection"
+	"github.com/graph-gophers/graphql-go/trace"
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+const (
+	hasRoleDirective       = "hasRole"
+	hasPermissionDirective = "hasPermission"
+)
+
+// Authorizer enforces the @hasRole and @hasPermission directives of the schema fields.
+// It's a tracer because tracers are called before each field resolver: the context of
+// a denied field is cancelled, so its resolver isn't called.
+type Authorizer struct {
+	// rules are indexed by "Type.field".
+	rules map[string]fieldRule
+}
+
+type fieldRule struct {
+	roles       []user.Role
+	permissions []user.Permission
+}
+
+func NewAuthorizer() *Authorizer {
+	return &Authorizer{rules: map[string]fieldRule{}}
+}
+
+// Load reads the rules declared by the directives of the schema fields.
+func (a *Authorizer) Load(s *types.Schema) error {
+	for typeName, t := range s.Types {
+		object, ok := t.(*types.ObjectTypeDefinition)
+		if !ok {
+			continue
+		}
+
+		for _, field := range object.Fields {
+			var rule fieldRule
+
+			for _, d := range field.Directives {
+				switch d.Name.Name {
+				case hasRoleDirective:
+					rule.roles = append(rule.roles, user.Role(directiveArgument(d, "role")))
+				case hasPermissionDirective:
+					p := user.Permission(directiveArgument(d, "name"))
+					if !p.Known() {
+						return fmt.Errorf("unknown permission %q required by %s.%s", p, typeName, field.Name)
+					}
+					rule.permissions = append(rule.permissions, p)
+				}
+			}
+
+			if len(rule.roles) > 0 || len(rule.permissions) > 0 {
+				a.rules[typeName+"."+field.Name] = rule
+			}
+		}
+	}
+
+	return nil
+}
+
+func directiveArgument(d *types.Directive, name string) string {
+	v, ok := d.Arguments.Get(name)
+	if !ok {
+		return ""
+	}
+
+	s, _ := v.Deserialize(nil).(string)
+	return s
+}
+
+// authorize returns false with the error to return if the client is missing
+// a role or a permission of the rule.
+func (rule fieldRule) authorize(c *application.ClientCtx) (resolverError, bool) {
+	u := user.AnonymousUser
+	if c != nil && c.User != nil {
+		u = c.User
+	}
+
+	allowed := true
+	for _, r := range rule.roles {
+		allowed = allowed && u.Roles.Has(r)
+	}
+	for _, p := range rule.permissions {
+		allowed = allowed && u.Roles.HasPermission(p)
+	}
+
+	switch {
+	case allowed:
+		return resolverError{}, true
+	case u.IsAnonymous():
+		return resolverErrUnauthorized(nil), false
+	default:
+		return resolverErrForbidden(nil), false
+	}
+}
+
+func (a *Authorizer) TraceQuery(
+	ctx context.Context,
+	queryString string,
+	operationName string,
+	variables map[string]interface{},
+	varTypes map[string]*introspection.Type,
+) (context.Context, trace.TraceQueryFinishFunc) {
+	return ctx, func(errs []*errors.QueryError) {}
+}
+
+func (a *Authorizer) TraceField(
+	ctx context.Context,
+	label, typeName, fieldName string,
+	trivial bool,
+	args map[string]interface{},
+) (context.Context, trace.TraceFieldFinishFunc) {
+	rule, ok := a.rules[typeName+"."+fieldName]
+	if !ok {
+		return ctx, func(err *errors.QueryError) {}
+	}
+
+	c, _ := ctx.Value(application.ClientCtxKey).(*application.ClientCtx)
+
+	denied, ok := rule.authorize(c)
+	if ok {
+		return ctx, func(err *errors.QueryError) {}
+	}
+
+	return deniedContext{Context: ctx, err: denied}, func(err *errors.QueryError) {
+		// the execution only keeps the message of the context error
+		if err != nil {
+			err.ResolverError = denied
+			err.Extensions = denied.Extensions()
+		}
+	}
+}
+
+// deniedContext is cancelled by the authorization error.
+type deniedContext struct {
+	context.Context
+	err error
+}
+
+func (c deniedContext) Err() error {
+	return c.err
+}
@@ -0,0 +1,90 @@
+package resolvers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/api/resolvers"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/graph-gophers/graphql-go"
+)
+
+func TestAuthorizer(t *testing.T) {
+	var (
+		app    = &application.Application{}
+		schema = testutils.ParseTestSchema(app)
+	)
+
+	const queryString = `{ me { id } }`
+
+	tests := []struct {
+		title       string
+		client      *application.ClientCtx
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title:  "Should allow role granting permission",
+			client: &application.ClientCtx{User: &user.User{ID: "id", Roles: user.Roles{user.RoleUser}}},
+		},
+		{
+			title:  "Should allow any role granting permission",
+			client: &application.ClientCtx{User: &user.User{ID: "id", Roles: user.Roles{user.RoleAdmin}}},
+		},
+		{
+			title:  "Should be unauthorized for anonymous",
+			client: &application.ClientCtx{User: user.AnonymousUser},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Unauthorized]: Unauthorized access",
+				Extensions: map[string]interface{}{
+					"code":       "Unauthorized",
+					"message":    "Unauthorized access",
+					"statusCode": 401,
+				},
+			},
+		},
+		{
+			title:  "Should be forbidden without permission",
+			client: &application.ClientCtx{User: &user.User{ID: "id", Roles: user.Roles{user.RoleAnonymous}}},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Forbidden]: Forbidden access",
+				Extensions: map[string]interface{}{
+					"code":       "Forbidden",
+					"message":    "Forbidden access",
+					"statusCode": 403,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := schema.Exec(app.ContextWithClient(context.Background(), tt.client), queryString, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+		})
+	}
+}
+
+func TestAuthorizerLoad(t *testing.T) {
+	const schemaString = `
+		directive @hasPermission(name: String!) on FIELD_DEFINITION
+
+		type Query {
+			field: String! @hasPermission(name: "unknown:read:any")
+		}`
+
+	s := graphql.MustParseSchema(schemaString, nil)
+
+	if err := resolvers.NewAuthorizer().Load(s.ASTSchema()); err == nil {
+		t.Fatal("expect unknown permission error")
+	}
+}
@@ -0,0 +1,65 @@
+package resolvers
+
+import (
+	"github.com/brice-74/golang-base-api/internal/utils"
+)
+
+// ConnectionParams are the arguments of the Relay connections, paginated by cursor.
+type ConnectionParams struct {
+	SortParams
+	First  *int32
+	After  *string
+	Last   *int32
+	Before *string
+}
+
+// keysetParams returns the keyset pagination of the arguments, to validate.
+func (p ConnectionParams) keysetParams(sortableFields []string) utils.KeysetParams {
+	kp := utils.KeysetParams{
+		Sort:           p.Sort,
+		SortableFields: sortableFields,
+	}
+
+	if p.First != nil {
+		kp.First = int(*p.First)
+	}
+	if p.After != nil {
+		kp.After = *p.After
+	}
+	if p.Last != nil {
+		kp.Last = int(*p.Last)
+	}
+	if p.Before != nil {
+		kp.Before = *p.Before
+	}
+
+	return kp
+}
+
+type PageInfoResolver struct {
+	info        utils.PageInfo
+	startCursor string
+	endCursor   string
+}
+
+func (r PageInfoResolver) HasNextPage() bool {
+	return r.info.HasNextPage
+}
+
+func (r PageInfoResolver) HasPreviousPage() bool {
+	return r.info.HasPreviousPage
+}
+
+func (r PageInfoResolver) StartCursor() *string {
+	if r.startCursor == "" {
+		return nil
+	}
+	return &r.startCursor
+}
+
+func (r PageInfoResolver) EndCursor() *string {
+	if r.endCursor == "" {
+		return nil
+	}
+	return &r.endCursor
+}
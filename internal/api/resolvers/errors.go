@@ -1,8 +1,11 @@
 package resolvers
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
 	"github.com/brice-74/golang-base-api/pkg/validator"
 )
 
@@ -12,6 +15,13 @@ const (
 	errValidator         = "ValidatorError"
 	errDatabaseOperation = "DatabaseOperationError"
 	errNotFound          = "NotFoundError"
+	errConflict          = "ConflictError"
+	errForbidden         = "Forbidden"
+	errTooManyRequests   = "TooManyRequests"
+	// codes of an inactive client, shared with the authentication middleware
+	errSessionExpired     = application.ErrCodeSessionExpired
+	errSessionRevoked     = application.ErrCodeSessionRevoked
+	errAccountDeactivated = application.ErrCodeAccountDeactivated
 )
 
 func resolverErrNotFound(err error) resolverError {
@@ -53,6 +63,70 @@ func resolverErrUnauthorized(err error) resolverError {
 	}
 }
 
+func resolverErrForbidden(err error) resolverError {
+	msg := "Forbidden access"
+	if err != nil {
+		msg = err.Error()
+	}
+
+	return resolverError{
+		Code:       errForbidden,
+		StatusCode: 403,
+		Message:    msg,
+	}
+}
+
+func resolverErrConflict(err error) resolverError {
+	msg := "Ressource state conflict"
+	if err != nil {
+		msg = err.Error()
+	}
+
+	return resolverError{
+		Code:       errConflict,
+		StatusCode: 409,
+		Message:    msg,
+	}
+}
+
+func resolverErrTooManyRequests(err error) resolverError {
+	msg := "Too many requests"
+	if err != nil {
+		msg = err.Error()
+	}
+
+	return resolverError{
+		Code:       errTooManyRequests,
+		StatusCode: 429,
+		Message:    msg,
+	}
+}
+
+// resolverErrInactiveClient returns an unauthorized error for an expired or revoked
+// session and a forbidden error for a deactivated account, with the code of the reason.
+func resolverErrInactiveClient(err error) resolverError {
+	switch {
+	case errors.Is(err, user.ErrDeactivatedUser):
+		return resolverError{
+			Code:       errAccountDeactivated,
+			StatusCode: 403,
+			Message:    err.Error(),
+		}
+	case errors.Is(err, user.ErrSessionRevoked):
+		return resolverError{
+			Code:       errSessionRevoked,
+			StatusCode: 401,
+			Message:    err.Error(),
+		}
+	default:
+		return resolverError{
+			Code:       errSessionExpired,
+			StatusCode: 401,
+			Message:    err.Error(),
+		}
+	}
+}
+
 func resolverErrDatabaseOperation(err error) resolverError {
 	msg := "Database operation error"
 	if err != nil {
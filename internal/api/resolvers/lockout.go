@@ -0,0 +1,117 @@
+package resolvers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+)
+
+// loginLockouts returns the lockouts of accounts and IP addresses, and the period
+// over which failed logins are counted.
+func (r Root) loginLockouts() (account user.Lockout, ip user.Lockout, window time.Duration, err error) {
+	cfg := r.App.Config.Lockout
+
+	if window, err = time.ParseDuration(cfg.Window); err != nil {
+		return
+	}
+
+	base, err := time.ParseDuration(cfg.BaseDelay)
+	if err != nil {
+		return
+	}
+
+	max, err := time.ParseDuration(cfg.MaxDelay)
+	if err != nil {
+		return
+	}
+
+	account = user.Lockout{Threshold: cfg.AccountThreshold, BaseDelay: base, MaxDelay: max}
+	ip = user.Lockout{Threshold: cfg.IPThreshold, BaseDelay: base, MaxDelay: max}
+
+	return
+}
+
+// loginAttempt is an attempt of login, or of a code or password check, recorded as a
+// failure before the credentials are checked, see user.Model.InsertLoginAttempt.
+type loginAttempt struct {
+	id     string
+	userID string
+	ip     string
+	// failures are the recent failures recorded before the attempt.
+	failures *user.LoginFailures
+}
+
+// beginLoginAttempt records an attempt of the account and ip as a failure, or returns an
+// error if one of them is locked. The attempt must be ended by failLoginAttempt or
+// succeedLoginAttempt once the credentials are checked.
+func (r Root) beginLoginAttempt(userID string, ip string) (*loginAttempt, error) {
+	accountLockout, ipLockout, window, err := r.loginLockouts()
+	if err != nil {
+		return nil, err
+	}
+
+	f, id, err := r.App.Models.User.InsertLoginAttempt(userID, ip, time.Now().Add(-window), accountLockout, ipLockout)
+	if err != nil {
+		return nil, resolverErrDatabaseOperation(err)
+	}
+
+	if id == "" {
+		until := accountLockout.LockedUntil(f.Account, f.LastAccount)
+		if ipUntil := ipLockout.LockedUntil(f.IP, f.LastIP); ipUntil.After(until) {
+			until = ipUntil
+		}
+
+		return nil, resolverErrTooManyRequests(
+			fmt.Errorf("Too many failed logins, try again after %s", until.Format(time.RFC3339)),
+		)
+	}
+
+	return &loginAttempt{id: id, userID: userID, ip: ip, failures: f}, nil
+}
+
+// failLoginAttempt keeps the attempt as a failed login, and logs the locks it causes.
+func (r Root) failLoginAttempt(a *loginAttempt) error {
+	accountLockout, ipLockout, _, err := r.loginLockouts()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	if until := accountLockout.LockedUntil(a.failures.Account+1, now); a.userID != "" && !until.IsZero() {
+		r.App.Logger.PrintInfo("account login locked", map[string]string{
+			"user_id":      a.userID,
+			"ip":           a.ip,
+			"failures":     fmt.Sprint(a.failures.Account + 1),
+			"locked_until": until.Format(time.RFC3339),
+		})
+	}
+
+	if until := ipLockout.LockedUntil(a.failures.IP+1, now); !until.IsZero() {
+		r.App.Logger.PrintInfo("ip login locked", map[string]string{
+			"ip":           a.ip,
+			"failures":     fmt.Sprint(a.failures.IP + 1),
+			"locked_until": until.Format(time.RFC3339),
+		})
+	}
+
+	return nil
+}
+
+// succeedLoginAttempt forgets the attempt, and the previous failures of the account when
+// resetAccount is true, which resets its backoff.
+func (r Root) succeedLoginAttempt(a *loginAttempt, resetAccount bool) error {
+	var err error
+	if resetAccount && a.failures.Account > 0 {
+		err = r.App.Models.User.UnlockUserAccount(a.userID)
+	} else {
+		err = r.App.Models.User.DeleteLoginFailure(a.id)
+	}
+
+	if err != nil {
+		return resolverErrDatabaseOperation(err)
+	}
+
+	return nil
+}
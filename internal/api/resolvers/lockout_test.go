@@ -0,0 +1,146 @@
+package resolvers_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/brice-74/golang-base-api/internal/testutils/factory"
+	"github.com/brice-74/golang-base-api/internal/testutils/mocks"
+)
+
+func TestLoginLockout(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+		logger = app.Logger.(*mocks.Logger)
+	)
+
+	const strPass = "Test123!"
+	u := fac.CreateUserAccount(&user.User{Password: strPass})
+
+	var login = func(ip, password string) error {
+		ctx := app.ContextWithClient(context.Background(), &application.ClientCtx{
+			Agent: &application.Agent{
+				IP:    ip,
+				Agent: "agent",
+			},
+		})
+
+		result := schema.Exec(ctx, fmt.Sprintf(`
+			mutation {
+				loginUserAccount(email: "%s", password: "%s") {
+					... on Tokens {
+						access
+					}
+				}
+			}`, u.Email, password,
+		), "", nil)
+		if len(result.Errors) > 0 {
+			return result.Errors[0]
+		}
+
+		return nil
+	}
+
+	for i := 0; i < app.Config.Lockout.AccountThreshold; i++ {
+		if err := login("1.1.1.1", "Wrong123!"); err == nil || !strings.Contains(err.Error(), "incorrect password") {
+			t.Fatalf("got: %v, expect incorrect password", err)
+		}
+	}
+
+	if !logger.PrintInfoCalled {
+		t.Fatal("expect lock event logged")
+	}
+
+	t.Run("Should refuse login of locked account from any ip", func(t *testing.T) {
+		if err := login("2.2.2.2", strPass); err == nil || !strings.Contains(err.Error(), "TooManyRequests") {
+			t.Fatalf("got: %v, expect TooManyRequests", err)
+		}
+	})
+
+	t.Run("Should login once unlocked", func(t *testing.T) {
+		if err := app.Models.User.UnlockUserAccount(u.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := login("2.2.2.2", strPass); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestLoginLockoutParallel(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+
+	var queryContext = app.ContextWithClient(context.Background(), &application.ClientCtx{
+		Agent: &application.Agent{
+			IP:    "1.1.1.1",
+			Agent: "agent",
+		},
+	})
+
+	const attempts = 20
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   = make(chan error, attempts)
+		tested int
+	)
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(password string) {
+			defer wg.Done()
+
+			result := schema.Exec(queryContext, fmt.Sprintf(`
+				mutation {
+					loginUserAccount(email: "%s", password: "%s") {
+						... on Tokens {
+							access
+						}
+					}
+				}`, u.Email, password,
+			), "", nil)
+			if len(result.Errors) == 0 {
+				errs <- fmt.Errorf("password %s accepted", password)
+				return
+			}
+
+			switch err := result.Errors[0].Error(); {
+			case strings.Contains(err, "incorrect password"):
+				mu.Lock()
+				tested++
+				mu.Unlock()
+			case !strings.Contains(err, "TooManyRequests"):
+				errs <- result.Errors[0]
+			}
+		}(fmt.Sprintf("Wrong%03d!", i))
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if tested > app.Config.Lockout.AccountThreshold {
+		t.Fatalf("got %d passwords tested, expect at most %d", tested, app.Config.Lockout.AccountThreshold)
+	}
+}
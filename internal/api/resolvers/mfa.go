@@ -0,0 +1,254 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/pkg/totp"
+	"github.com/graph-gophers/graphql-go"
+)
+
+var (
+	errInvalidMfaCode = errors.New("Invalid two-factor authentication code")
+	errMfaNotEnabled  = errors.New("Two-factor authentication not enabled")
+)
+
+// EnrollMfa: generate a new TOTP secret, 2FA is enabled once confirmed with a code
+func (r Root) EnrollMfa(ctx context.Context) (*MfaEnrollmentResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireSession(c); err != nil {
+		return nil, err
+	}
+
+	if err := r.requireVerifiedEmail(c.User); err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.App.Models.User.SetUserMfaSecret(c.User.ID, secret); err != nil {
+		switch {
+		case errors.Is(err, user.ErrMfaAlreadyEnabled):
+			return nil, resolverErrConflict(err)
+		default:
+			return nil, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	return &MfaEnrollmentResolver{
+		secret: secret,
+		uri:    totp.ProvisioningURI(r.App.Config.MFA.Issuer, c.User.Email, secret),
+	}, nil
+}
+
+type MfaEnrollmentResolver struct {
+	secret string
+	uri    string
+}
+
+func (r MfaEnrollmentResolver) Secret() string {
+	return r.secret
+}
+
+func (r MfaEnrollmentResolver) Uri() string {
+	return r.uri
+}
+
+// ConfirmMfa: enable 2FA with a first code and return the recovery codes, which are only shown once
+func (r Root) ConfirmMfa(ctx context.Context, params ConfirmMfaParams) ([]string, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireSession(c); err != nil {
+		return nil, err
+	}
+
+	if c.User.MfaEnabled() {
+		return nil, resolverErrConflict(user.ErrMfaAlreadyEnabled)
+	}
+
+	if c.User.MfaSecret == "" {
+		return nil, resolverErrConflict(user.ErrMfaNotEnrolled)
+	}
+
+	if err := r.checkMfaCode(ctx, c.User, params.Code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := user.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.App.Models.User.EnableUserMfa(c.User.ID, hashes); err != nil {
+		switch {
+		case errors.Is(err, user.ErrMfaNotEnrolled):
+			return nil, resolverErrConflict(err)
+		default:
+			return nil, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	r.audit(ctx, c.User.ID, user.AuditMfaEnable)
+
+	return codes, nil
+}
+
+type ConfirmMfaParams struct {
+	Code string
+}
+
+// DisableMfa: disable 2FA, a TOTP or recovery code is required
+func (r Root) DisableMfa(ctx context.Context, params DisableMfaParams) (bool, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireSession(c); err != nil {
+		return false, err
+	}
+
+	if !c.User.MfaEnabled() {
+		return false, resolverErrConflict(errMfaNotEnabled)
+	}
+
+	if err := r.checkMfaCode(ctx, c.User, params.Code, true); err != nil {
+		return false, err
+	}
+
+	if err := r.App.Models.User.DisableUserMfa(c.User.ID); err != nil {
+		return false, resolverErrDatabaseOperation(err)
+	}
+
+	r.audit(ctx, c.User.ID, user.AuditMfaDisable)
+
+	return true, nil
+}
+
+type DisableMfaParams struct {
+	Code string
+}
+
+// VerifyMfa: exchange the challenge returned by login and a TOTP or recovery code for tokens
+func (r Root) VerifyMfa(ctx context.Context, params VerifyMfaParams) (*TokensUserAccountResolver, error) {
+	claims, err := r.App.VerifyMfaChallenge(params.Token)
+	if err != nil {
+		return nil, resolverErrUnauthorized(err)
+	}
+
+	u, err := r.App.Models.User.GetById(claims[application.UserIdClaim])
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFoundUser):
+			return nil, resolverErrNotFound(err)
+		default:
+			return nil, resolverErrDatabaseOperation(err)
+		}
+	}
+	// 2FA may have been disabled since the challenge was issued
+	if !u.MfaEnabled() {
+		return nil, resolverErrUnauthorized(errMfaNotEnabled)
+	}
+	// the account may have been deactivated, or must be verified, since the login
+	if err = r.checkLoginAllowed(u); err != nil {
+		return nil, err
+	}
+
+	if err = r.checkMfaCode(ctx, u, params.Code, true); err != nil {
+		return nil, err
+	}
+
+	return r.openSession(ctx, u.ID, claims[application.SessionIdClaim])
+}
+
+type VerifyMfaParams struct {
+	Token string
+	Code  string
+}
+
+// mfaChallenge returns the challenge to verify instead of tokens.
+func (r Root) mfaChallenge(userID string, sessionID string) (*AuthResultResolver, error) {
+	token, exp, err := r.App.CreateMfaChallenge(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResultResolver{result: &MfaChallengeResolver{
+		token:     token,
+		expiresAt: time.Unix(exp, 0),
+	}}, nil
+}
+
+type MfaChallengeResolver struct {
+	token     string
+	expiresAt time.Time
+}
+
+func (r MfaChallengeResolver) Token() string {
+	return r.token
+}
+
+func (r MfaChallengeResolver) ExpiresAt() graphql.Time {
+	return graphql.Time{Time: r.expiresAt}
+}
+
+// checkMfaCode accepts a TOTP code newer than the last accepted one, or consumes a
+// recovery code when recovery is true. Each code is counted as a failed login until it's
+// accepted, so codes can't be guessed, even in parallel, without locking the logins of
+// the account and of the client IP.
+func (r Root) checkMfaCode(ctx context.Context, u *user.User, code string, recovery bool) error {
+	uctx := r.App.ClientFromContext(ctx)
+
+	attempt, err := r.beginLoginAttempt(u.ID, uctx.Agent.IP)
+	if err != nil {
+		return err
+	}
+
+	ok, err := r.useMfaCode(u, code, recovery)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		if err = r.failLoginAttempt(attempt); err != nil {
+			return err
+		}
+		return resolverErrUnauthorized(errInvalidMfaCode)
+	}
+	// a verified code resets the backoff of the account
+	return r.succeedLoginAttempt(attempt, true)
+}
+
+// useMfaCode reports whether code is a TOTP code not used before or, when recovery is
+// true, an unused recovery code, which is then consumed.
+func (r Root) useMfaCode(u *user.User, code string, recovery bool) (bool, error) {
+	if step, ok := totp.ValidateStep(code, u.MfaSecret, time.Now()); ok {
+		err := r.App.Models.User.UseMfaStep(u.ID, step)
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.Is(err, user.ErrMfaCodeReused):
+			return false, nil
+		default:
+			return false, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	if !recovery {
+		return false, nil
+	}
+
+	err := r.App.Models.User.UseRecoveryCode(u.ID, user.HashRecoveryCode(code))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, user.ErrInvalidRecoveryCode):
+		return false, nil
+	default:
+		return false, resolverErrDatabaseOperation(err)
+	}
+}
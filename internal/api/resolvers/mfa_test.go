@@ -0,0 +1,608 @@
+package resolvers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/brice-74/golang-base-api/internal/testutils/factory"
+	"github.com/brice-74/golang-base-api/internal/utils"
+	"github.com/brice-74/golang-base-api/pkg/totp"
+	"github.com/graph-gophers/graphql-go/gqltesting"
+)
+
+func TestEnrollAndConfirmMfa(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	agent := &application.Agent{IP: "0.0.0.0", Agent: "agent"}
+	u := fac.CreateUserAccount(nil)
+
+	result := schema.Exec(
+		app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Agent: agent}),
+		`mutation { enrollMfa { secret uri } }`, "", nil,
+	)
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors[0])
+	}
+
+	var enrollRes EnrollMfaResponse
+	data, _ := result.Data.MarshalJSON()
+	if err := json.Unmarshal(data, &enrollRes); err != nil {
+		t.Fatal(err)
+	}
+
+	if enrollRes.EnrollMfa.Uri != totp.ProvisioningURI("test", u.Email, enrollRes.EnrollMfa.Secret) {
+		t.Fatalf("got unexpected provisioning uri: %s", enrollRes.EnrollMfa.Uri)
+	}
+	// the client context user is reloaded on each request by the middleware
+	u, err := app.Models.User.GetById(u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var queryString = func(code string) string {
+		return fmt.Sprintf(`mutation { confirmMfa(code: "%s") }`, code)
+	}
+
+	code, err := totp.Code(enrollRes.EnrollMfa.Secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should return invalid code",
+			gqltest: &gqltesting.Test{
+				Context: app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Agent: agent}),
+				Schema:  schema,
+				Query:   queryString("000000"),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Unauthorized]: Invalid two-factor authentication code",
+				Extensions: map[string]interface{}{
+					"code":       "Unauthorized",
+					"statusCode": 401,
+					"message":    "Invalid two-factor authentication code",
+				},
+			},
+		},
+		{
+			title: "Should enable 2FA and return recovery codes",
+			gqltest: &gqltesting.Test{
+				Context: app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Agent: agent}),
+				Schema:  schema,
+				Query:   queryString(code),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(tt.gqltest.Context, tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+			} else {
+				var res ConfirmMfaResponse
+
+				data, _ := result.Data.MarshalJSON()
+				if err := json.Unmarshal(data, &res); err != nil {
+					t.Fatal(err)
+				}
+
+				if len(res.ConfirmMfa) != user.RecoveryCodesCount {
+					t.Fatalf("got %d recovery codes, expect %d", len(res.ConfirmMfa), user.RecoveryCodesCount)
+				}
+
+				got, err := app.Models.User.GetById(u.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !got.MfaEnabled() {
+					t.Fatal("expect 2FA enabled")
+				}
+			}
+		})
+	}
+}
+
+type EnrollMfaResponse struct {
+	EnrollMfa struct {
+		Secret string
+		Uri    string
+	}
+}
+
+type ConfirmMfaResponse struct {
+	ConfirmMfa []string
+}
+
+func TestLoginAndVerifyMfa(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	var queryContext = app.ContextWithClient(context.Background(), &application.ClientCtx{
+		Agent: &application.Agent{
+			IP:    "0.0.0.0",
+			Agent: "agent",
+		},
+	})
+
+	const strPass = "Test123!"
+	u := fac.CreateUserAccount(&user.User{
+		Email:    "test@test.com",
+		Password: strPass,
+	})
+	recoveryCodes := fac.EnableUserMfa(u)
+
+	var login = func(t *testing.T) string {
+		result := schema.Exec(queryContext, fmt.Sprintf(`
+			mutation {
+				loginUserAccount(email: "%s", password: "%s") {
+					... on Tokens {
+						access
+					}
+					... on MfaChallenge {
+						token
+						expiresAt
+					}
+				}
+			}`, u.Email, strPass,
+		), "", nil)
+		if len(result.Errors) > 0 {
+			t.Fatal(result.Errors[0])
+		}
+
+		var res LoginMfaChallengeResponse
+		data, _ := result.Data.MarshalJSON()
+		if err := json.Unmarshal(data, &res); err != nil {
+			t.Fatal(err)
+		}
+
+		if res.LoginUserAccount.Access != "" {
+			t.Fatal("expect mfa challenge, got tokens")
+		}
+
+		if _, err := app.VerifyMfaChallenge(res.LoginUserAccount.Token); err != nil {
+			t.Fatalf("mfa challenge verification fail: %s", err)
+		}
+
+		return res.LoginUserAccount.Token
+	}
+
+	var queryString = func(token, code string) string {
+		return fmt.Sprintf(`
+			mutation {
+				verifyMfa(token: "%s", code: "%s") {
+					access
+					refresh
+					sessionId
+				}
+			}`, token, code,
+		)
+	}
+
+	code, err := totp.Code(u.MfaSecret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	td, err := app.CreateTokens(u.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalidCodeError := &testutils.ExpectResolverError{
+		Msg: "error [Unauthorized]: Invalid two-factor authentication code",
+		Extensions: map[string]interface{}{
+			"code":       "Unauthorized",
+			"statusCode": 401,
+			"message":    "Invalid two-factor authentication code",
+		},
+	}
+
+	tests := []struct {
+		title       string
+		query       func(challenge string) string
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should return tokens from TOTP code",
+			query: func(challenge string) string { return queryString(challenge, code) },
+		},
+		{
+			title: "Should return tokens from recovery code",
+			query: func(challenge string) string { return queryString(challenge, recoveryCodes[0]) },
+		},
+		{
+			title:       "Should refuse reused TOTP code",
+			query:       func(challenge string) string { return queryString(challenge, code) },
+			expectError: invalidCodeError,
+		},
+		{
+			title:       "Should refuse used recovery code",
+			query:       func(challenge string) string { return queryString(challenge, recoveryCodes[0]) },
+			expectError: invalidCodeError,
+		},
+		{
+			title:       "Should refuse invalid code",
+			query:       func(challenge string) string { return queryString(challenge, "000000") },
+			expectError: invalidCodeError,
+		},
+		{
+			title: "Should refuse other tokens than challenge",
+			query: func(challenge string) string { return queryString(td.RefreshToken, code) },
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Unauthorized]: Invalid token",
+				Extensions: map[string]interface{}{
+					"code":       "Unauthorized",
+					"statusCode": 401,
+					"message":    "Invalid token",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := schema.Exec(queryContext, tt.query(login(t)), "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+			} else {
+				var res VerifyMfaResponse
+
+				data, _ := result.Data.MarshalJSON()
+				if err := json.Unmarshal(data, &res); err != nil {
+					t.Fatal(err)
+				}
+
+				if _, err := application.VerifyToken(res.VerifyMfa.Access, app.Config.JWT.Access.Secret); err != nil {
+					t.Fatalf("Access token verification fail: %s", err.Error())
+				}
+
+				_, sessionID, err := utils.DecodeGlobalID(res.VerifyMfa.SessionID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				s, err := app.Models.User.GetSessionByID(sessionID)
+				if err != nil {
+					t.Fatalf("error during database session recovery: %s", err.Error())
+				}
+
+				if s.UserID != u.ID {
+					t.Fatalf("got session user: %s, expect: %s", s.UserID, u.ID)
+				}
+			}
+		})
+	}
+}
+
+type LoginMfaChallengeResponse struct {
+	LoginUserAccount struct {
+		Access    string
+		Token     string
+		ExpiresAt time.Time
+	}
+}
+
+type VerifyMfaResponse struct {
+	VerifyMfa user.Tokens
+}
+
+func TestDisableMfa(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	agent := &application.Agent{IP: "0.0.0.0", Agent: "agent"}
+	u := fac.CreateUserAccount(nil)
+	recoveryCodes := fac.EnableUserMfa(u)
+
+	var queryString = func(code string) string {
+		return fmt.Sprintf(`mutation { disableMfa(code: "%s") }`, code)
+	}
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should return invalid code",
+			gqltest: &gqltesting.Test{
+				Context: app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Agent: agent}),
+				Schema:  schema,
+				Query:   queryString("000000"),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Unauthorized]: Invalid two-factor authentication code",
+				Extensions: map[string]interface{}{
+					"code":       "Unauthorized",
+					"statusCode": 401,
+					"message":    "Invalid two-factor authentication code",
+				},
+			},
+		},
+		{
+			title: "Should disable 2FA with recovery code",
+			gqltest: &gqltesting.Test{
+				Context: app.ContextWithClient(context.Background(), &application.ClientCtx{User: u, Agent: agent}),
+				Schema:  schema,
+				Query:   queryString(recoveryCodes[0]),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(tt.gqltest.Context, tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+			} else {
+				if len(result.Errors) > 0 {
+					t.Fatal(result.Errors[0])
+				}
+
+				got, err := app.Models.User.GetById(u.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got.MfaEnabled() || got.MfaSecret != "" {
+					t.Fatal("expect 2FA disabled")
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyMfaAccountState(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	var queryContext = app.ContextWithClient(context.Background(), &application.ClientCtx{
+		Agent: &application.Agent{
+			IP:    "0.0.0.0",
+			Agent: "agent",
+		},
+	})
+
+	tests := []struct {
+		title       string
+		mode        application.EmailVerificationMode
+		update      func(u *user.User) error
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title:  "Should refuse account deactivated after the login",
+			mode:   application.EmailVerificationOptional,
+			update: func(u *user.User) error { return app.Models.User.DeactivateUserAccount(u.ID) },
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [AccountDeactivated]: User account deactivated",
+				Extensions: map[string]interface{}{
+					"code":       "AccountDeactivated",
+					"statusCode": 403,
+					"message":    "User account deactivated",
+				},
+			},
+		},
+		{
+			title:  "Should refuse unverified account in required mode",
+			mode:   application.EmailVerificationRequired,
+			update: func(u *user.User) error { return nil },
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Forbidden]: Email address not verified",
+				Extensions: map[string]interface{}{
+					"code":       "Forbidden",
+					"statusCode": 403,
+					"message":    "Email address not verified",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			app.Config.EmailVerification.Mode = tt.mode
+
+			u := fac.CreateUserAccount(nil)
+			fac.EnableUserMfa(u)
+
+			challenge, _, err := app.CreateMfaChallenge(u.ID, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.update(u); err != nil {
+				t.Fatal(err)
+			}
+
+			code, err := totp.Code(u.MfaSecret, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := schema.Exec(queryContext, fmt.Sprintf(`
+				mutation {
+					verifyMfa(token: "%s", code: "%s") {
+						access
+					}
+				}`, challenge, code,
+			), "", nil)
+			if len(result.Errors) == 0 {
+				t.Fatal("expect error, got tokens")
+			}
+
+			testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+		})
+	}
+}
+
+func TestVerifyMfaLockout(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	var queryContext = app.ContextWithClient(context.Background(), &application.ClientCtx{
+		Agent: &application.Agent{
+			IP:    "0.0.0.0",
+			Agent: "agent",
+		},
+	})
+
+	u := fac.CreateUserAccount(nil)
+	recoveryCodes := fac.EnableUserMfa(u)
+
+	challenge, _, err := app.CreateMfaChallenge(u.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var verify = func(code string) error {
+		result := schema.Exec(queryContext, fmt.Sprintf(`
+			mutation {
+				verifyMfa(token: "%s", code: "%s") {
+					access
+				}
+			}`, challenge, code,
+		), "", nil)
+		if len(result.Errors) > 0 {
+			return result.Errors[0]
+		}
+
+		return nil
+	}
+
+	for i := 0; i < app.Config.Lockout.AccountThreshold; i++ {
+		if err := verify("000000"); err == nil || !strings.Contains(err.Error(), "Invalid two-factor authentication code") {
+			t.Fatalf("got: %v, expect invalid code", err)
+		}
+	}
+
+	t.Run("Should refuse valid code once locked", func(t *testing.T) {
+		if err := verify(recoveryCodes[0]); err == nil || !strings.Contains(err.Error(), "TooManyRequests") {
+			t.Fatalf("got: %v, expect TooManyRequests", err)
+		}
+	})
+
+	t.Run("Should refuse login of locked account", func(t *testing.T) {
+		result := schema.Exec(queryContext, fmt.Sprintf(`
+			mutation {
+				loginUserAccount(email: "%s", password: "Test123!") {
+					... on MfaChallenge {
+						token
+					}
+				}
+			}`, u.Email,
+		), "", nil)
+		if len(result.Errors) == 0 || !strings.Contains(result.Errors[0].Error(), "TooManyRequests") {
+			t.Fatalf("got: %v, expect TooManyRequests", result.Errors)
+		}
+	})
+}
+
+func TestVerifyMfaParallelGuesses(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	var queryContext = app.ContextWithClient(context.Background(), &application.ClientCtx{
+		Agent: &application.Agent{
+			IP:    "0.0.0.0",
+			Agent: "agent",
+		},
+	})
+
+	u := fac.CreateUserAccount(nil)
+	fac.EnableUserMfa(u)
+
+	challenge, _, err := app.CreateMfaChallenge(u.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const guesses = 20
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   = make(chan error, guesses)
+		tested int
+	)
+
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func(code string) {
+			defer wg.Done()
+
+			result := schema.Exec(queryContext, fmt.Sprintf(`
+				mutation {
+					verifyMfa(token: "%s", code: "%s") {
+						access
+					}
+				}`, challenge, code,
+			), "", nil)
+			if len(result.Errors) == 0 {
+				errs <- fmt.Errorf("code %s accepted", code)
+				return
+			}
+
+			switch err := result.Errors[0].Error(); {
+			case strings.Contains(err, "Invalid two-factor authentication code"):
+				mu.Lock()
+				tested++
+				mu.Unlock()
+			case !strings.Contains(err, "TooManyRequests"):
+				errs <- result.Errors[0]
+			}
+		}(fmt.Sprintf("%06d", 999000+i))
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if tested > app.Config.Lockout.AccountThreshold {
+		t.Fatalf("got %d codes tested, expect at most %d", tested, app.Config.Lockout.AccountThreshold)
+	}
+}
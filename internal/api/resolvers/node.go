@@ -0,0 +1,208 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/utils"
+	"github.com/brice-74/golang-base-api/pkg/validator"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/twinj/uuid"
+)
+
+// names of the types implementing the Node interface, part of their global ids.
+const (
+	userAccountNodeType = "UserAccount"
+	sessionNodeType     = "Session"
+)
+
+// maxNodes is the maximum number of ids of a nodes query.
+const maxNodes = 100
+
+// nodeLoader returns the node identified by id, nil when it doesn't exist or the client
+// isn't allowed to see it.
+type nodeLoader func(r Root, ctx context.Context, client *user.User, id string) (node, error)
+
+// nodeLoaders is the registry of the types implementing the Node interface, by type name.
+var nodeLoaders = map[string]nodeLoader{
+	userAccountNodeType: loadUserAccountNode,
+	sessionNodeType:     loadSessionNode,
+}
+
+// node is a resolver of a type implementing the Node interface.
+type node interface {
+	ID() graphql.ID
+}
+
+// globalID returns the id of the object of the type given to clients.
+func globalID(typeName, id string) graphql.ID {
+	return graphql.ID(utils.EncodeGlobalID(typeName, id))
+}
+
+// localID returns the id of the object of the type identified by a global id, the
+// arguments still accept the raw ids used before global ids. False is returned for the
+// global id of another type or an id which can't exist, so it is never queried.
+func localID(id graphql.ID, typeName string) (string, bool) {
+	if local, ok := databaseID(string(id)); ok {
+		return local, true
+	}
+
+	t, local, err := utils.DecodeGlobalID(string(id))
+	if err != nil || t != typeName {
+		return "", false
+	}
+
+	return databaseID(local)
+}
+
+// databaseID returns the canonical form of an id of the database, false if it isn't a
+// uuid like every id of the database.
+func databaseID(id string) (string, bool) {
+	u, err := uuid.Parse(id)
+	if err != nil {
+		return "", false
+	}
+
+	return u.String(), true
+}
+
+// Node: get any object from its global id, null if it doesn't exist or isn't visible to the client
+func (r Root) Node(ctx context.Context, params NodeParams) (*NodeResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	return r.node(ctx, c, params.ID)
+}
+
+type NodeParams struct {
+	ID graphql.ID
+}
+
+// Nodes: get objects from their global ids, in the same order, null for each object which
+// doesn't exist or isn't visible to the client
+func (r Root) Nodes(ctx context.Context, params NodesParams) ([]*NodeResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	v := validator.New()
+	v.Check(len(params.Ids) <= maxNodes, "ids", fmt.Sprintf("must not contain more than %d ids", maxNodes))
+	if !v.Valid() {
+		return nil, validatorError{Errors: v.Errors}
+	}
+
+	nodes := make([]*NodeResolver, len(params.Ids))
+	errs := make([]error, len(params.Ids))
+	// nodes are loaded concurrently, so the loaders batch the ids of each type
+	var wg sync.WaitGroup
+	for i, id := range params.Ids {
+		wg.Add(1)
+		go func(i int, id graphql.ID) {
+			defer wg.Done()
+			nodes[i], errs[i] = r.node(ctx, c, id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nodes, nil
+}
+
+type NodesParams struct {
+	Ids []graphql.ID
+}
+
+// node loads the node of a global id with the loader of its type.
+func (r Root) node(ctx context.Context, c *application.ClientCtx, id graphql.ID) (*NodeResolver, error) {
+	typeName, local, err := utils.DecodeGlobalID(string(id))
+	if err != nil {
+		return nil, nil
+	}
+
+	load, ok := nodeLoaders[typeName]
+	if !ok {
+		return nil, nil
+	}
+	// the ids of the database are uuids, others can't exist
+	local, ok = databaseID(local)
+	if !ok {
+		return nil, nil
+	}
+
+	n, err := load(r, ctx, clientUser(c), local)
+	if err != nil || n == nil {
+		return nil, err
+	}
+
+	return &NodeResolver{node: n}, nil
+}
+
+func loadUserAccountNode(r Root, ctx context.Context, client *user.User, id string) (node, error) {
+	if !userAccountVisibleTo(client, id) {
+		return nil, nil
+	}
+
+	u, err := r.App.LoadersFromContext(ctx).User(id)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFoundUser) {
+			return nil, nil
+		}
+		return nil, resolverErrDatabaseOperation(err)
+	}
+
+	return newUserAccountResolver(r.App, *u), nil
+}
+
+func loadSessionNode(r Root, ctx context.Context, client *user.User, id string) (node, error) {
+	if !client.Roles.HasPermission(user.PermissionSessionReadOwn) &&
+		!client.Roles.HasPermission(user.PermissionSessionReadAny) {
+		return nil, nil
+	}
+
+	s, err := r.App.LoadersFromContext(ctx).Session(id)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFoundSession) {
+			return nil, nil
+		}
+		return nil, resolverErrDatabaseOperation(err)
+	}
+	// a session of another user is hidden, as if it doesn't exist
+	if s.UserID != client.ID && !client.Roles.HasPermission(user.PermissionSessionReadAny) {
+		return nil, nil
+	}
+
+	return &SessionResolver{app: r.App, session: *s}, nil
+}
+
+// NodeResolver resolves the Node interface to the resolver of its type.
+type NodeResolver struct {
+	node node
+}
+
+func (r NodeResolver) ID() graphql.ID {
+	return r.node.ID()
+}
+
+func (r NodeResolver) ToUserAccount() (*UserAccountResolver, bool) {
+	n, ok := r.node.(*UserAccountResolver)
+	return n, ok
+}
+
+func (r NodeResolver) ToSession() (*SessionResolver, bool) {
+	n, ok := r.node.(*SessionResolver)
+	return n, ok
+}
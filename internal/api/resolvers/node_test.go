@@ -0,0 +1,152 @@
+package resolvers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/brice-74/golang-base-api/internal/testutils/factory"
+	"github.com/brice-74/golang-base-api/internal/utils"
+	"github.com/twinj/uuid"
+)
+
+func TestNode(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	var queryString = func(id string) string {
+		return fmt.Sprintf(`{
+			node(id: "%s") {
+				id
+				__typename
+				... on UserAccount { email }
+				... on Session { userId }
+			}
+		}`, id)
+	}
+
+	u := fac.CreateUserAccount(nil)
+	other := fac.CreateUserAccount(nil)
+	admin := fac.CreateUserAccount(&user.User{Roles: user.Roles{user.RoleAdmin}})
+	s := fac.CreateUserSession(&user.Session{UserID: u.ID})
+
+	userID := utils.EncodeGlobalID("UserAccount", u.ID)
+	sessionID := utils.EncodeGlobalID("Session", s.ID)
+
+	tests := []struct {
+		title      string
+		client     *user.User
+		id         string
+		expectType string
+	}{
+		{title: "Should return own account", client: u, id: userID, expectType: "UserAccount"},
+		{title: "Should return own session", client: u, id: sessionID, expectType: "Session"},
+		{title: "Should return any account to admin", client: admin, id: userID, expectType: "UserAccount"},
+		{title: "Should return any session to admin", client: admin, id: sessionID, expectType: "Session"},
+		{title: "Should hide account of another user", client: other, id: userID},
+		{title: "Should hide session of another user", client: other, id: sessionID},
+		{title: "Should hide account to anonymous", client: user.AnonymousUser, id: userID},
+		{title: "Should return null for unknown id", client: admin, id: utils.EncodeGlobalID("UserAccount", uuid.NewV4().String())},
+		{title: "Should return null for unknown type", client: admin, id: utils.EncodeGlobalID("AccessToken", u.ID)},
+		{title: "Should return null for invalid id", client: admin, id: u.ID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			ctx := app.ContextWithClient(context.Background(), &application.ClientCtx{User: tt.client})
+			result := schema.Exec(ctx, queryString(tt.id), "", nil)
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			var res struct {
+				Node *struct {
+					ID       string
+					Typename string `json:"__typename"`
+					Email    string
+					UserID   string
+				}
+			}
+
+			if err := json.Unmarshal(result.Data, &res); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.expectType == "" {
+				if res.Node != nil {
+					t.Fatalf("got node: %+v, expect null", res.Node)
+				}
+				return
+			}
+
+			if res.Node == nil || res.Node.ID != tt.id || res.Node.Typename != tt.expectType {
+				t.Fatalf("got node: %+v, expect %s %s", res.Node, tt.expectType, tt.id)
+			}
+
+			switch {
+			case tt.expectType == "UserAccount" && res.Node.Email != u.Email,
+				tt.expectType == "Session" && res.Node.UserID != u.ID:
+				t.Fatalf("got node: %+v, expect fields of the user %s", res.Node, u.ID)
+			}
+		})
+	}
+}
+
+func TestNodes(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+	other := fac.CreateUserAccount(nil)
+	s := fac.CreateUserSession(&user.Session{UserID: u.ID})
+
+	ids := []string{
+		utils.EncodeGlobalID("Session", s.ID),
+		utils.EncodeGlobalID("UserAccount", other.ID),
+		utils.EncodeGlobalID("UserAccount", u.ID),
+	}
+
+	ctx := app.ContextWithClient(context.Background(), &application.ClientCtx{User: u})
+	result := schema.Exec(ctx, fmt.Sprintf(`{ nodes(ids: ["%s", "%s", "%s"]) { id } }`, ids[0], ids[1], ids[2]), "", nil)
+
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors[0])
+	}
+
+	var res struct {
+		Nodes []*struct {
+			ID string
+		}
+	}
+
+	if err := json.Unmarshal(result.Data, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, n := range res.Nodes {
+		if n == nil {
+			got = append(got, "")
+			continue
+		}
+		got = append(got, n.ID)
+	}
+	// the account of the other user is hidden
+	if expect := []string{ids[0], "", ids[2]}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got nodes: %v, expect: %v", got, expect)
+	}
+}
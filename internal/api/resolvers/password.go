@@ -0,0 +1,123 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/pkg/mailer"
+	"github.com/brice-74/golang-base-api/pkg/validator"
+)
+
+// RequestPasswordReset: send a password reset link by email, the result doesn't
+// tell whether the account exists
+func (r Root) RequestPasswordReset(_ context.Context, params RequestPasswordResetParams) (bool, error) {
+	uEntry := user.User{Email: params.Email}
+
+	v := validator.New()
+	uEntry.ValidateEmailEntry(v)
+	if !v.Valid() {
+		return false, validatorError{Errors: v.Errors}
+	}
+
+	u, err := r.App.Models.User.GetByEmail(uEntry.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFoundUser):
+			return true, nil
+		default:
+			return false, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	duration, err := time.ParseDuration(r.App.Config.PasswordReset.Expiration)
+	if err != nil {
+		return false, err
+	}
+
+	token, err := user.GenerateToken()
+	if err != nil {
+		return false, err
+	}
+
+	if err = r.App.Models.User.InsertPasswordReset(u.ID, user.HashToken(token), time.Now().Add(duration)); err != nil {
+		return false, resolverErrDatabaseOperation(err)
+	}
+
+	link, err := linkWithToken(r.App.Config.PasswordReset.URL, token)
+	if err != nil {
+		return false, err
+	}
+
+	if err = r.App.Mailer.Send(mailer.Message{
+		From:    r.App.Config.Mailer.From,
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nUse the following link to choose a new password, it expires in %s:\n%s\n\nIf you didn't request a password reset, you can ignore this email.\n",
+			u.ProfilName, duration, link,
+		),
+	}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+type RequestPasswordResetParams struct {
+	Email string
+}
+
+// ResetPassword: replace the password using a reset token, every session of the user is revoked
+func (r Root) ResetPassword(ctx context.Context, params ResetPasswordParams) (bool, error) {
+	uEntry := user.User{Password: params.NewPassword}
+
+	v := validator.New()
+	if err := r.validateNewPassword(v, uEntry); err != nil {
+		return false, err
+	}
+	if !v.Valid() {
+		return false, validatorError{Errors: v.Errors}
+	}
+
+	hash, err := r.App.Passwords.Hash(uEntry.Password)
+	if err != nil {
+		return false, err
+	}
+
+	userID, err := r.App.Models.User.ResetUserPassword(user.HashToken(params.Token), hash)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrInvalidResetToken):
+			return false, resolverErrUnauthorized(err)
+		default:
+			return false, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	r.audit(ctx, userID, user.AuditPasswordReset)
+
+	return true, nil
+}
+
+type ResetPasswordParams struct {
+	Token       string
+	NewPassword string
+}
+
+// linkWithToken adds the token to the query of a front-end page.
+func linkWithToken(page string, token string) (string, error) {
+	link, err := url.Parse(page)
+	if err != nil {
+		return "", err
+	}
+
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	return link.String(), nil
+}
@@ -0,0 +1,238 @@
+package resolvers_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/brice-74/golang-base-api/internal/testutils/factory"
+	"github.com/brice-74/golang-base-api/pkg/mailer"
+	"github.com/brice-74/golang-base-api/pkg/password"
+	"github.com/brice-74/golang-base-api/pkg/validator"
+	"github.com/graph-gophers/graphql-go/gqltesting"
+)
+
+func TestRequestPasswordReset(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+		outbox = app.Mailer.(*mailer.Memory)
+	)
+
+	var queryString = func(email string) string {
+		return fmt.Sprintf(`mutation { requestPasswordReset(email: "%s") }`, email)
+	}
+
+	u := fac.CreateUserAccount(nil)
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectMail  bool
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should send reset link",
+			gqltest: &gqltesting.Test{
+				Schema: schema,
+				Query:  queryString(u.Email),
+			},
+			expectMail: true,
+		},
+		{
+			title: "Should not tell that account doesn't exist",
+			gqltest: &gqltesting.Test{
+				Schema: schema,
+				Query:  queryString("unknow@email.com"),
+			},
+		},
+		{
+			title: "Should return validatorError",
+			gqltest: &gqltesting.Test{
+				Schema: schema,
+				Query:  queryString("bad email"),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "validation error [ValidatorError]",
+				Extensions: map[string]interface{}{
+					"code":       "ValidatorError",
+					"statusCode": 422,
+					"errors": validator.Errors{
+						"email": []string{"must be a valid address"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			sent := len(outbox.Messages())
+			result := tt.gqltest.Schema.Exec(context.Background(), tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			if got := len(outbox.Messages()) - sent; (got == 1) != tt.expectMail {
+				t.Fatalf("got %d sent emails, expect mail: %t", got, tt.expectMail)
+			}
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+		outbox = app.Mailer.(*mailer.Memory)
+	)
+
+	const newPassword = "NewPass123!"
+
+	var queryString = func(token, password string) string {
+		return fmt.Sprintf(`mutation { resetPassword(token: "%s", newPassword: "%s") }`, token, password)
+	}
+
+	u := fac.CreateUserAccount(nil)
+	s := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+
+	result := schema.Exec(context.Background(), fmt.Sprintf(`mutation { requestPasswordReset(email: "%s") }`, u.Email), "", nil)
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors[0])
+	}
+
+	token := tokenFromEmail(t, outbox, u.Email, app.Config.PasswordReset.URL)
+
+	invalidTokenError := &testutils.ExpectResolverError{
+		Msg: "error [Unauthorized]: Invalid or expired password reset token",
+		Extensions: map[string]interface{}{
+			"code":       "Unauthorized",
+			"statusCode": 401,
+			"message":    "Invalid or expired password reset token",
+		},
+	}
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should return validatorError",
+			gqltest: &gqltesting.Test{
+				Schema: schema,
+				Query:  queryString(token, "weak"),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "validation error [ValidatorError]",
+				Extensions: map[string]interface{}{
+					"code":       "ValidatorError",
+					"statusCode": 422,
+					"errors": validator.Errors{
+						"password": []string{
+							"must have minimum of 8 characters",
+							"must have minimum of 1 uppercase",
+							"must have minimum of 1 number",
+							"must have minimum of 1 special character",
+						},
+					},
+				},
+			},
+		},
+		{
+			title: "Should return invalid token",
+			gqltest: &gqltesting.Test{
+				Schema: schema,
+				Query:  queryString("unknown", newPassword),
+			},
+			expectError: invalidTokenError,
+		},
+		{
+			title: "Should reset password",
+			gqltest: &gqltesting.Test{
+				Schema: schema,
+				Query:  queryString(token, newPassword),
+			},
+		},
+		{
+			title: "Should refuse used token",
+			gqltest: &gqltesting.Test{
+				Schema: schema,
+				Query:  queryString(token, newPassword),
+			},
+			expectError: invalidTokenError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(context.Background(), tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			got, err := app.Models.User.GetById(u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok, _ := password.Verify(newPassword, got.Password); !ok {
+				t.Fatal("password should be replaced")
+			}
+
+			gs, err := app.Models.User.GetSessionByID(s.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if gs.DeactivatedAt.After(time.Now()) {
+				t.Fatal("session should be revoked")
+			}
+		})
+	}
+}
+
+// tokenFromEmail returns the token of the link to page sent in the last email to the recipient.
+func tokenFromEmail(t *testing.T, outbox *mailer.Memory, to string, page string) string {
+	msg, ok := outbox.Last(to)
+	if !ok {
+		t.Fatalf("expect email sent to %s", to)
+	}
+
+	for _, field := range strings.Fields(msg.Body) {
+		if strings.HasPrefix(field, page) {
+			link, err := url.Parse(field)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return link.Query().Get("token")
+		}
+	}
+
+	t.Fatalf("expect link to %s in email: %s", page, msg.Body)
+	return ""
+}
@@ -0,0 +1,49 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// UserByShortId: get the public profile of a user, profiles hidden to the client are not found
+func (r Root) UserByShortId(ctx context.Context, params UserByShortIdParams) (*PublicUserProfileResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	u, err := r.App.Models.User.GetByShortId(params.ShortId)
+	if err != nil {
+		return nil, userAccountError(err)
+	}
+	// a hidden profile can't be told apart from a missing one
+	if !u.ProfileVisibleTo(c.User) {
+		return nil, resolverErrNotFound(user.ErrNotFoundUser)
+	}
+
+	return &PublicUserProfileResolver{user: u.Redacted()}, nil
+}
+
+type UserByShortIdParams struct {
+	ShortId string
+}
+
+// PublicUserProfileResolver only exposes the fields of a user anyone may see.
+type PublicUserProfileResolver struct {
+	user user.User
+}
+
+func (r PublicUserProfileResolver) ShortId() string {
+	return r.user.ShortId
+}
+
+func (r PublicUserProfileResolver) ProfilName() string {
+	return r.user.ProfilName
+}
+
+func (r PublicUserProfileResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.user.CreatedAt}
+}
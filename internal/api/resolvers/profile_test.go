@@ -0,0 +1,104 @@
+package resolvers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/brice-74/golang-base-api/internal/testutils/factory"
+)
+
+func TestUserByShortId(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	var queryString = func(shortId string) string {
+		return fmt.Sprintf(`{ userByShortId(shortId: "%s") { shortId profilName createdAt } }`, shortId)
+	}
+
+	public := fac.CreateUserAccount(nil)
+	private := fac.CreateUserAccount(nil)
+
+	private.ProfileVisibility = user.ProfileVisibilityPrivate
+	if err := app.Models.User.UpdateUserProfile(private); err != nil {
+		t.Fatal(err)
+	}
+
+	notFound := &testutils.ExpectResolverError{
+		Msg: "error [NotFoundError]: User not found",
+		Extensions: map[string]interface{}{
+			"code":       "NotFoundError",
+			"statusCode": 404,
+			"message":    "User not found",
+		},
+	}
+
+	tests := []struct {
+		title       string
+		client      *user.User
+		profile     *user.User
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title:   "Should return public profile to anonymous",
+			client:  user.AnonymousUser,
+			profile: public,
+		},
+		{
+			title:       "Should hide private profile",
+			client:      public,
+			profile:     private,
+			expectError: notFound,
+		},
+		{
+			title:   "Should return private profile to owner",
+			client:  private,
+			profile: private,
+		},
+		{
+			title:       "Should return not found user",
+			client:      user.AnonymousUser,
+			profile:     &user.User{ShortId: "unknown"},
+			expectError: notFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			ctx := app.ContextWithClient(context.Background(), &application.ClientCtx{User: tt.client})
+			result := schema.Exec(ctx, queryString(tt.profile.ShortId), "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			var res struct {
+				UserByShortId struct {
+					ShortId    string
+					ProfilName string
+				}
+			}
+
+			if err := json.Unmarshal(result.Data, &res); err != nil {
+				t.Fatal(err)
+			}
+
+			if res.UserByShortId.ShortId != tt.profile.ShortId || res.UserByShortId.ProfilName != tt.profile.ProfilName {
+				t.Fatalf("got profile: %+v, expect: %s %s", res.UserByShortId, tt.profile.ShortId, tt.profile.ProfilName)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package resolvers_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/brice-74/golang-base-api/internal/testutils/factory"
+	"github.com/brice-74/golang-base-api/internal/utils"
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+// TestSensitiveFieldsNotExposed fails if any field of the schema reachable from a user
+// account returns the value of a sensitive user field.
+func TestSensitiveFieldsNotExposed(t *testing.T) {
+	var (
+		app    = &application.Application{}
+		schema = testutils.ParseTestSchema(app)
+	)
+
+	u := &user.User{ID: "id", Roles: user.Roles{user.RoleUser}, ProfileVisibility: user.ProfileVisibilityPublic}
+
+	secrets := map[string]string{}
+	for _, name := range user.SensitiveFields() {
+		f := reflect.ValueOf(u).Elem().FieldByName(name)
+		if f.Kind() != reflect.String {
+			t.Fatalf("sensitive field %s must be a string to be checked", name)
+		}
+
+		secrets[name] = "sensitive-value-of-" + name
+		f.SetString(secrets[name])
+	}
+
+	query := "{ me " + selectAllFields(schema.ASTSchema().Types["UserAccount"], 3, nil) + " }"
+
+	result := schema.Exec(app.ContextWithClient(context.Background(), &application.ClientCtx{User: u}), query, "", nil)
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors[0])
+	}
+
+	for name, secret := range secrets {
+		if strings.Contains(string(result.Data), secret) {
+			t.Errorf("sensitive field %s exposed by query: %s", name, query)
+		}
+	}
+}
+
+// TestSensitiveFieldsNotExposedByQueries runs every query down to the user accounts they
+// return, through lists, connections, nodes and sessions, and fails if the value of a
+// sensitive field of the stored accounts is returned.
+func TestSensitiveFieldsNotExposedByQueries(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	admin := fac.CreateUserAccount(&user.User{Roles: user.Roles{user.RoleAdmin}})
+	fac.EnableUserMfa(admin)
+	adminSession := fac.CreateUserSession(&user.Session{
+		UserID:        admin.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+
+	u := fac.CreateUserAccount(nil)
+	fac.EnableUserMfa(u)
+	s := fac.CreateUserSession(&user.Session{
+		UserID:        u.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+
+	userID := utils.EncodeGlobalID("UserAccount", u.ID)
+	sessionID := utils.EncodeGlobalID("Session", s.ID)
+
+	args := map[string][]string{
+		"Query.user":          {fmt.Sprintf(`(id: "%s")`, u.ID)},
+		"Query.node":          {fmt.Sprintf(`(id: "%s")`, userID), fmt.Sprintf(`(id: "%s")`, sessionID)},
+		"Query.nodes":         {fmt.Sprintf(`(ids: ["%s", "%s"])`, userID, sessionID)},
+		"Query.userByShortId": {fmt.Sprintf(`(shortId: "%s")`, u.ShortId)},
+		"Query.sessions":      {fmt.Sprintf(`(include: {userIds: ["%s"]})`, u.ID)},
+	}
+
+	query := selectAllFields(schema.ASTSchema().Types["Query"], 5, args)
+
+	ctx := app.ContextWithClient(context.Background(), &application.ClientCtx{
+		User:    admin,
+		Session: adminSession,
+		Agent:   &application.Agent{IP: "0.0.0.0", Agent: "agent"},
+	})
+
+	result := schema.Exec(ctx, query, "", nil)
+	if len(result.Errors) > 0 {
+		t.Fatalf("got error: %s, query: %s", result.Errors[0], query)
+	}
+
+	for _, stored := range []*user.User{admin, u} {
+		v := reflect.ValueOf(stored).Elem()
+		for _, name := range user.SensitiveFields() {
+			secret := v.FieldByName(name).String()
+			if secret == "" {
+				t.Fatalf("sensitive field %s of the stored account must be set to be checked", name)
+			}
+
+			if strings.Contains(string(result.Data), secret) {
+				t.Errorf("sensitive field %s exposed by query: %s", name, query)
+			}
+		}
+	}
+}
+
+// selectAllFields returns the selection of every field of a type down to depth nested
+// types, interfaces select every type implementing them. Fields with required arguments
+// are only selected with the arguments of args, keyed by "Type.field", once for each
+// arguments under an alias.
+func selectAllFields(t types.NamedType, depth int, args map[string][]string) string {
+	if depth == 0 {
+		return ""
+	}
+
+	switch t := t.(type) {
+	case *types.InterfaceTypeDefinition:
+		fragments := []string{"__typename"}
+		for _, object := range t.PossibleTypes {
+			if sub := selectAllFields(object, depth, args); sub != "" {
+				fragments = append(fragments, "... on "+object.Name+" "+sub)
+			}
+		}
+
+		return "{ " + strings.Join(fragments, " ") + " }"
+	case *types.ObjectTypeDefinition:
+		var fields []string
+		for _, f := range t.Fields {
+			calls := []string{f.Name}
+			if fieldArgs, ok := args[t.Name+"."+f.Name]; ok {
+				calls = nil
+				for i, a := range fieldArgs {
+					calls = append(calls, fmt.Sprintf("%s%d: %s%s", f.Name, i, f.Name, a))
+				}
+			} else if hasRequiredArgument(f) {
+				continue
+			}
+
+			var sub string
+			switch named := unwrapType(f.Type).(type) {
+			case *types.ScalarTypeDefinition, *types.EnumTypeDefinition:
+			case *types.ObjectTypeDefinition, *types.InterfaceTypeDefinition:
+				if sub = selectAllFields(named.(types.NamedType), depth-1, args); sub == "" {
+					continue
+				}
+			default:
+				continue
+			}
+
+			for _, call := range calls {
+				fields = append(fields, strings.TrimSpace(call+" "+sub))
+			}
+		}
+
+		if len(fields) == 0 {
+			return ""
+		}
+
+		return "{ " + strings.Join(fields, " ") + " }"
+	default:
+		return ""
+	}
+}
+
+func hasRequiredArgument(f *types.FieldDefinition) bool {
+	for _, arg := range f.Arguments {
+		if _, ok := arg.Type.(*types.NonNull); ok && arg.Default == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func unwrapType(t types.Type) types.Type {
+	for {
+		switch w := t.(type) {
+		case *types.NonNull:
+			t = w.OfType
+		case *types.List:
+			t = w.OfType
+		default:
+			return t
+		}
+	}
+}
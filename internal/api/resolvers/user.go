@@ -2,6 +2,7 @@ package resolvers
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/brice-74/golang-base-api/internal/api/application"
@@ -14,20 +15,45 @@ import (
 func (r Root) Me(ctx context.Context) (*UserAccountResolver, error) {
 	c := r.App.ClientFromContext(ctx)
 
-	if c.User.IsAnonymous() {
-		return nil, resolverErrUnauthorized(nil)
+	if err := requireScope(c, user.ScopeRead); err != nil {
+		return nil, err
 	}
 
-	return &UserAccountResolver{app: r.App, user: *c.User}, nil
+	return newUserAccountResolver(r.App, *c.User), nil
 }
 
+// UserAccountResolver only holds a redacted user, see newUserAccountResolver.
 type UserAccountResolver struct {
 	app  *application.Application
 	user user.User
 }
 
+// newUserAccountResolver removes the sensitive fields of the user, so no field resolver can expose them.
+func newUserAccountResolver(app *application.Application, u user.User) *UserAccountResolver {
+	return &UserAccountResolver{app: app, user: u.Redacted()}
+}
+
+// clientUser returns the user of the client, anonymous when not authenticated.
+func clientUser(c *application.ClientCtx) *user.User {
+	if c.User == nil {
+		return user.AnonymousUser
+	}
+
+	return c.User
+}
+
+// userAccountVisibleTo checks if the client may see the account of the id: its own account,
+// or any account with the user:read:any permission.
+func userAccountVisibleTo(client *user.User, id string) bool {
+	if id == client.ID && client.Roles.HasPermission(user.PermissionAccountReadOwn) {
+		return true
+	}
+
+	return client.Roles.HasPermission(user.PermissionUserReadAny)
+}
+
 func (r UserAccountResolver) ID() graphql.ID {
-	return graphql.ID(r.user.ID)
+	return globalID(userAccountNodeType, r.user.ID)
 }
 
 func (r UserAccountResolver) CreatedAt() graphql.Time {
@@ -46,14 +72,18 @@ func (r UserAccountResolver) Email() string {
 	return r.user.Email
 }
 
-func (r UserAccountResolver) Password() string {
-	return r.user.Password
-}
-
 func (r UserAccountResolver) Roles() user.Roles {
 	return r.user.Roles
 }
 
+func (r UserAccountResolver) Verified() bool {
+	return r.user.Verified()
+}
+
+func (r UserAccountResolver) MfaEnabled() bool {
+	return r.user.MfaEnabled()
+}
+
 func (r UserAccountResolver) ProfilName() string {
 	return r.user.ProfilName
 }
@@ -62,14 +92,39 @@ func (r UserAccountResolver) ShortId() string {
 	return r.user.ShortId
 }
 
+func (r UserAccountResolver) FirstName() *string {
+	if r.user.FirstName == "" {
+		return nil
+	}
+	return &r.user.FirstName
+}
+
+func (r UserAccountResolver) LastName() *string {
+	if r.user.LastName == "" {
+		return nil
+	}
+	return &r.user.LastName
+}
+
+func (r UserAccountResolver) BirthDate() *graphql.Time {
+	if r.user.BirthDate.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: r.user.BirthDate}
+}
+
+func (r UserAccountResolver) ProfileVisibility() user.ProfileVisibility {
+	return r.user.ProfileVisibility
+}
+
 func (r Root) SessionsFromAuth(
 	ctx context.Context,
 	params SessionListParams,
 ) (*SessionListResolver, error) {
 	c := r.App.ClientFromContext(ctx)
 
-	if c.User.IsAnonymous() {
-		return nil, resolverErrUnauthorized(nil)
+	if err := requireScope(c, user.ScopeRead); err != nil {
+		return nil, err
 	}
 
 	v := validator.New()
@@ -107,6 +162,86 @@ func (r Root) SessionsFromAuth(
 	return &SessionListResolver{total: total, resolvers: sr}, nil
 }
 
+// SessionConnectionFromAuth: get the sessions of the logged user, paginated by cursor
+func (r Root) SessionConnectionFromAuth(
+	ctx context.Context,
+	params SessionConnectionParams,
+) (*SessionConnectionResolver, error) {
+	c := r.App.ClientFromContext(ctx)
+
+	if err := requireScope(c, user.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	kp := params.keysetParams(user.SessionKeysetSortableFields)
+
+	v := validator.New()
+	if kp.Validate(v); !v.Valid() {
+		return nil, validatorError{Errors: v.Errors}
+	}
+
+	sessions, info, err := r.App.Models.User.GetAllSessionKeyset(
+		kp,
+		user.GetAllSessionIncludeFilters{
+			States:  params.Include.States,
+			UserIds: []string{c.User.ID},
+		},
+	)
+	if err != nil {
+		return nil, resolverErrDatabaseOperation(err)
+	}
+
+	res := &SessionConnectionResolver{
+		edges:    make([]SessionEdgeResolver, len(sessions)),
+		pageInfo: PageInfoResolver{info: info},
+	}
+
+	for i, s := range sessions {
+		res.edges[i] = SessionEdgeResolver{
+			cursor: s.Cursor(kp.Sort),
+			node:   SessionResolver{app: r.App, session: *s},
+		}
+	}
+
+	if len(res.edges) > 0 {
+		res.pageInfo.startCursor = res.edges[0].cursor
+		res.pageInfo.endCursor = res.edges[len(res.edges)-1].cursor
+	}
+
+	return res, nil
+}
+
+type SessionConnectionParams struct {
+	ConnectionParams
+	Include SessionListIncludeFiltersInput
+}
+
+type SessionConnectionResolver struct {
+	edges    []SessionEdgeResolver
+	pageInfo PageInfoResolver
+}
+
+func (r SessionConnectionResolver) Edges() []SessionEdgeResolver {
+	return r.edges
+}
+
+func (r SessionConnectionResolver) PageInfo() PageInfoResolver {
+	return r.pageInfo
+}
+
+type SessionEdgeResolver struct {
+	cursor string
+	node   SessionResolver
+}
+
+func (r SessionEdgeResolver) Cursor() string {
+	return r.cursor
+}
+
+func (r SessionEdgeResolver) Node() SessionResolver {
+	return r.node
+}
+
 type SessionListParams struct {
 	ResolverParams
 	Include SessionListIncludeFiltersInput
@@ -135,7 +270,7 @@ type SessionResolver struct {
 }
 
 func (r SessionResolver) ID() graphql.ID {
-	return graphql.ID(r.session.ID)
+	return globalID(sessionNodeType, r.session.ID)
 }
 
 func (r SessionResolver) CreatedAt() graphql.Time {
@@ -158,6 +293,24 @@ func (r SessionResolver) Agent() string {
 	return r.session.Agent
 }
 
+// UserID: the database id of the account, not its global id, see User
 func (r SessionResolver) UserID() string {
 	return r.session.UserID
 }
+
+// User: the account of the session, null if the client isn't allowed to see it
+func (r SessionResolver) User(ctx context.Context) (*UserAccountResolver, error) {
+	if !userAccountVisibleTo(clientUser(r.app.ClientFromContext(ctx)), r.session.UserID) {
+		return nil, nil
+	}
+
+	u, err := r.app.LoadersFromContext(ctx).User(r.session.UserID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFoundUser) {
+			return nil, nil
+		}
+		return nil, resolverErrDatabaseOperation(err)
+	}
+
+	return newUserAccountResolver(r.app, *u), nil
+}
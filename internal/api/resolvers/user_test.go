@@ -100,7 +100,7 @@ func TestMe(t *testing.T) {
 					field string
 					err   bool
 				}{
-					{field: "ID", err: u.ID != uRes.ID},
+					{field: "ID", err: utils.EncodeGlobalID("UserAccount", u.ID) != uRes.ID},
 					{field: "CreatedAt", err: u.CreatedAt.Format(time.RFC3339) != uRes.CreatedAt.Format(time.RFC3339)},
 					{field: "UpdatedAt", err: u.UpdatedAt.Format(time.RFC3339) != uRes.UpdatedAt.Format(time.RFC3339)},
 					{field: "DeactivatedAt", err: !uRes.Active},
@@ -144,13 +144,13 @@ func TestSessionsFromAuth(t *testing.T) {
 
 	var sessionToSessionResponse = func(s user.Session) SessionResponse {
 		return SessionResponse{
-			ID:        s.ID,
+			ID:        utils.EncodeGlobalID("Session", s.ID),
 			CreatedAt: s.CreatedAt,
 			UpdatedAt: s.UpdatedAt,
 			Active:    s.DeactivatedAt.After(time.Now()),
 			IP:        s.IP,
 			Agent:     s.Agent,
-			UserID:    s.UserID,
+			UserID:    utils.EncodeGlobalID("UserAccount", s.UserID),
 		}
 	}
 
@@ -300,6 +300,205 @@ type SessionsFromAuthResponse struct {
 	SessionsFromAuth SessionListResponse
 }
 
+func TestSessionConnectionFromAuth(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+	fac.CreateUserSession(&user.Session{UserID: fac.CreateUserAccount(nil).ID})
+
+	var ids []string
+	for i := 1; i <= 5; i++ {
+		s := fac.CreateUserSession(&user.Session{
+			UserID:        u.ID,
+			DeactivatedAt: time.Now().Add(time.Duration(i) * time.Hour),
+		})
+		ids = append(ids, utils.EncodeGlobalID("Session", s.ID))
+	}
+
+	ctx := app.ContextWithClient(context.Background(), &application.ClientCtx{User: u})
+
+	var query = func(args string) SessionConnectionResponse {
+		result := schema.Exec(ctx, fmt.Sprintf(`
+			{
+				sessionConnectionFromAuth(%s) {
+					edges { cursor node { id } }
+					pageInfo { hasNextPage hasPreviousPage startCursor endCursor }
+				}
+			}`, args), "", nil)
+		if len(result.Errors) > 0 {
+			t.Fatal(result.Errors[0])
+		}
+
+		var res struct {
+			SessionConnectionFromAuth SessionConnectionResponse
+		}
+
+		if err := json.Unmarshal(result.Data, &res); err != nil {
+			t.Fatal(err)
+		}
+
+		return res.SessionConnectionFromAuth
+	}
+
+	var expectPage = func(t *testing.T, got SessionConnectionResponse, ids []string, next, previous bool) {
+		var gotIDs []string
+		for _, e := range got.Edges {
+			gotIDs = append(gotIDs, e.Node.ID)
+		}
+
+		if !reflect.DeepEqual(gotIDs, ids) {
+			t.Fatalf("got sessions: %v, expect: %v", gotIDs, ids)
+		}
+
+		if got.PageInfo.HasNextPage != next || got.PageInfo.HasPreviousPage != previous {
+			t.Fatalf("got page info: %+v, expect next: %t previous: %t", got.PageInfo, next, previous)
+		}
+	}
+
+	t.Run("Should paginate forward", func(t *testing.T) {
+		page := query("first: 2")
+		expectPage(t, page, ids[0:2], true, false)
+
+		page = query(fmt.Sprintf(`first: 2, after: "%s"`, page.PageInfo.EndCursor))
+		expectPage(t, page, ids[2:4], true, true)
+
+		page = query(fmt.Sprintf(`first: 2, after: "%s"`, page.PageInfo.EndCursor))
+		expectPage(t, page, ids[4:5], false, true)
+	})
+
+	t.Run("Should paginate backward", func(t *testing.T) {
+		page := query("last: 2")
+		expectPage(t, page, ids[3:5], false, true)
+
+		page = query(fmt.Sprintf(`last: 2, before: "%s"`, page.PageInfo.StartCursor))
+		expectPage(t, page, ids[1:3], true, true)
+	})
+
+	t.Run("Should paginate in descending order", func(t *testing.T) {
+		page := query(`first: 3, sort: "-deactivatedAt"`)
+		expectPage(t, page, []string{ids[4], ids[3], ids[2]}, true, false)
+	})
+
+	t.Run("Should return validatorError", func(t *testing.T) {
+		result := schema.Exec(ctx, `{ sessionConnectionFromAuth(after: "invalid") { pageInfo { hasNextPage } } }`, "", nil)
+
+		testutils.TestGqlError(t, result.Errors[0], &testutils.ExpectResolverError{
+			Msg: "validation error [ValidatorError]",
+			Extensions: map[string]interface{}{
+				"code":       "ValidatorError",
+				"statusCode": 422,
+				"errors": validator.Errors{
+					"after": []string{"invalid cursor"},
+				},
+			},
+		})
+	})
+
+	t.Run("Should refuse cursor of another sort", func(t *testing.T) {
+		page := query("first: 2")
+
+		result := schema.Exec(ctx, fmt.Sprintf(
+			`{ sessionConnectionFromAuth(after: "%s", sort: "-deactivatedAt") { pageInfo { hasNextPage } } }`,
+			page.PageInfo.EndCursor,
+		), "", nil)
+
+		testutils.TestGqlError(t, result.Errors[0], &testutils.ExpectResolverError{
+			Msg: "validation error [ValidatorError]",
+			Extensions: map[string]interface{}{
+				"code":       "ValidatorError",
+				"statusCode": 422,
+				"errors": validator.Errors{
+					"after": []string{"cursor of another sort"},
+				},
+			},
+		})
+	})
+}
+
+func TestSessionUser(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+	other := fac.CreateUserAccount(nil)
+	admin := fac.CreateUserAccount(&user.User{Roles: user.Roles{user.RoleAdmin}})
+	for _, owner := range []*user.User{u, u, other} {
+		fac.CreateUserSession(&user.Session{UserID: owner.ID})
+	}
+
+	var query = func(client *user.User, q string) []SessionResponse {
+		ctx := app.ContextWithClient(context.Background(), &application.ClientCtx{User: client})
+		result := schema.Exec(ctx, q, "", nil)
+		if len(result.Errors) > 0 {
+			t.Fatal(result.Errors[0])
+		}
+
+		var res struct {
+			SessionConnectionFromAuth struct {
+				Edges []struct {
+					Node SessionResponse
+				}
+			}
+			Sessions struct {
+				List []SessionResponse
+			}
+		}
+
+		if err := json.Unmarshal(result.Data, &res); err != nil {
+			t.Fatal(err)
+		}
+
+		sessions := res.Sessions.List
+		for _, e := range res.SessionConnectionFromAuth.Edges {
+			sessions = append(sessions, e.Node)
+		}
+
+		return sessions
+	}
+
+	var expectUsers = func(t *testing.T, sessions []SessionResponse, count int) {
+		if len(sessions) != count {
+			t.Fatalf("got %d sessions, expect %d", len(sessions), count)
+		}
+
+		for _, s := range sessions {
+			if s.User == nil || s.User.ID != utils.EncodeGlobalID("UserAccount", s.UserID) {
+				t.Fatalf("got session user: %+v, expect user %s", s.User, s.UserID)
+			}
+		}
+	}
+
+	t.Run("Should return own account", func(t *testing.T) {
+		expectUsers(t, query(u, `{ sessionConnectionFromAuth { edges { node { userId user { id } } } } }`), 2)
+	})
+
+	t.Run("Should return account of any session to admin", func(t *testing.T) {
+		expectUsers(t, query(admin, `{ sessions(include: { userIds: ["`+u.ID+`", "`+other.ID+`"] }) { list { userId user { id } } } }`), 3)
+	})
+}
+
+type SessionConnectionResponse struct {
+	Edges []struct {
+		Cursor string
+		Node   SessionResponse
+	}
+	PageInfo struct {
+		HasNextPage     bool
+		HasPreviousPage bool
+		StartCursor     string
+		EndCursor       string
+	}
+}
+
 type SessionListResponse struct {
 	Total int
 	List  []*SessionResponse
@@ -313,6 +512,7 @@ type SessionResponse struct {
 	IP        string
 	Agent     string
 	UserID    string
+	User      *UserResponse
 }
 
 type UserResponse struct {
@@ -321,7 +521,6 @@ type UserResponse struct {
 	UpdatedAt  time.Time
 	Active     bool
 	Email      string
-	Password   string
 	Roles      user.Roles
 	ProfilName string
 	ShortId    string
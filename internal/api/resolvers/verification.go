@@ -0,0 +1,105 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/pkg/mailer"
+	"github.com/brice-74/golang-base-api/pkg/validator"
+)
+
+var errEmailNotVerified = errors.New("Email address not verified")
+
+// VerifyEmail: mark the email address of a user as verified using the token sent by email
+func (r Root) VerifyEmail(_ context.Context, params VerifyEmailParams) (bool, error) {
+	claims, err := r.App.VerifyEmailVerificationToken(params.Token)
+	if err != nil {
+		return false, resolverErrUnauthorized(err)
+	}
+	// the token is refused if the user has changed his email since it was sent
+	if err = r.App.Models.User.VerifyUserEmail(claims[application.UserIdClaim], claims[application.EmailClaim]); err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFoundUser):
+			return false, resolverErrNotFound(err)
+		default:
+			return false, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	return true, nil
+}
+
+type VerifyEmailParams struct {
+	Token string
+}
+
+// ResendVerificationEmail: send the verification email again, the result doesn't
+// tell whether the account exists
+func (r Root) ResendVerificationEmail(_ context.Context, params ResendVerificationEmailParams) (bool, error) {
+	uEntry := user.User{Email: params.Email}
+
+	v := validator.New()
+	uEntry.ValidateEmailEntry(v)
+	if !v.Valid() {
+		return false, validatorError{Errors: v.Errors}
+	}
+
+	u, err := r.App.Models.User.GetByEmail(uEntry.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFoundUser):
+			return true, nil
+		default:
+			return false, resolverErrDatabaseOperation(err)
+		}
+	}
+
+	if u.Verified() {
+		return true, nil
+	}
+
+	if err = r.sendVerificationEmail(u); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+type ResendVerificationEmailParams struct {
+	Email string
+}
+
+// sendVerificationEmail sends the verification link to the user email address.
+func (r Root) sendVerificationEmail(u *user.User) error {
+	token, _, err := r.App.CreateEmailVerificationToken(u.ID, u.Email)
+	if err != nil {
+		return err
+	}
+
+	link, err := linkWithToken(r.App.Config.EmailVerification.URL, token)
+	if err != nil {
+		return err
+	}
+
+	return r.App.Mailer.Send(mailer.Message{
+		From:    r.App.Config.Mailer.From,
+		To:      u.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nUse the following link to verify your email address:\n%s\n",
+			u.ProfilName, link,
+		),
+	})
+}
+
+// requireVerifiedEmail refuses sensitive operations to unverified accounts, unless verification is optional.
+func (r Root) requireVerifiedEmail(u *user.User) error {
+	if r.App.Config.EmailVerification.Mode != application.EmailVerificationOptional && !u.Verified() {
+		return resolverErrForbidden(errEmailNotVerified)
+	}
+
+	return nil
+}
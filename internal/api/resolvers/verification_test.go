@@ -0,0 +1,263 @@
+package resolvers_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/brice-74/golang-base-api/internal/testutils/factory"
+	"github.com/brice-74/golang-base-api/pkg/mailer"
+	"github.com/graph-gophers/graphql-go/gqltesting"
+)
+
+func TestVerifyEmail(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		outbox = app.Mailer.(*mailer.Memory)
+	)
+
+	const email = "test@test.com"
+
+	result := schema.Exec(context.Background(), fmt.Sprintf(`
+		mutation {
+			registerUserAccount(input: {
+				email: "%s",
+				password: "passWORD123!",
+				profilName: "name"
+			}) {
+				id
+			}
+		}`, email,
+	), "", nil)
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors[0])
+	}
+
+	token := tokenFromEmail(t, outbox, email, app.Config.EmailVerification.URL)
+
+	u, err := app.Models.User.GetByEmail(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a token sent to a previous address of the user
+	oldAddressToken, _, err := app.CreateEmailVerificationToken(u.ID, "old@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var queryString = func(token string) string {
+		return fmt.Sprintf(`mutation { verifyEmail(token: "%s") }`, token)
+	}
+
+	tests := []struct {
+		title       string
+		gqltest     *gqltesting.Test
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should return invalid token",
+			gqltest: &gqltesting.Test{
+				Schema: schema,
+				Query:  queryString("invalid"),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [Unauthorized]: token contains an invalid number of segments",
+				Extensions: map[string]interface{}{
+					"code":       "Unauthorized",
+					"statusCode": 401,
+					"message":    "token contains an invalid number of segments",
+				},
+			},
+		},
+		{
+			title: "Should refuse token of previous address",
+			gqltest: &gqltesting.Test{
+				Schema: schema,
+				Query:  queryString(oldAddressToken),
+			},
+			expectError: &testutils.ExpectResolverError{
+				Msg: "error [NotFoundError]: User not found",
+				Extensions: map[string]interface{}{
+					"code":       "NotFoundError",
+					"statusCode": 404,
+					"message":    "User not found",
+				},
+			},
+		},
+		{
+			title: "Should verify email",
+			gqltest: &gqltesting.Test{
+				Schema: schema,
+				Query:  queryString(token),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			result := tt.gqltest.Schema.Exec(context.Background(), tt.gqltest.Query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			got, err := app.Models.User.GetById(u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !got.Verified() {
+				t.Fatal("expect verified user")
+			}
+		})
+	}
+}
+
+func TestResendVerificationEmail(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+		outbox = app.Mailer.(*mailer.Memory)
+	)
+
+	var queryString = func(email string) string {
+		return fmt.Sprintf(`mutation { resendVerificationEmail(email: "%s") }`, email)
+	}
+
+	unverified := fac.CreateUserAccount(nil)
+	verified := fac.CreateUserAccount(&user.User{VerifiedAt: time.Now()})
+
+	tests := []struct {
+		title      string
+		email      string
+		expectMail bool
+	}{
+		{
+			title:      "Should send verification email",
+			email:      unverified.Email,
+			expectMail: true,
+		},
+		{
+			title: "Should not send email to verified user",
+			email: verified.Email,
+		},
+		{
+			title: "Should not tell that account doesn't exist",
+			email: "unknow@email.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			sent := len(outbox.Messages())
+			result := schema.Exec(context.Background(), queryString(tt.email), "", nil)
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+
+			if got := len(outbox.Messages()) - sent; (got == 1) != tt.expectMail {
+				t.Fatalf("got %d sent emails, expect mail: %t", got, tt.expectMail)
+			}
+		})
+	}
+}
+
+func TestEmailVerificationMode(t *testing.T) {
+	var (
+		db     = testutils.PrepareDB(t)
+		app    = testutils.NewApplication(db)
+		schema = testutils.ParseTestSchema(app)
+		fac    = factory.New(t, db)
+	)
+
+	const strPass = "Test123!"
+	u := fac.CreateUserAccount(&user.User{Password: strPass})
+
+	notVerifiedError := &testutils.ExpectResolverError{
+		Msg: "error [Forbidden]: Email address not verified",
+		Extensions: map[string]interface{}{
+			"code":       "Forbidden",
+			"statusCode": 403,
+			"message":    "Email address not verified",
+		},
+	}
+
+	var queryContext = app.ContextWithClient(context.Background(), &application.ClientCtx{
+		User: u,
+		Agent: &application.Agent{
+			IP:    "0.0.0.0",
+			Agent: "agent",
+		},
+	})
+
+	var loginQuery = fmt.Sprintf(`
+		mutation {
+			loginUserAccount(email: "%s", password: "%s") {
+				... on Tokens {
+					access
+				}
+			}
+		}`, u.Email, strPass,
+	)
+
+	tests := []struct {
+		title       string
+		mode        application.EmailVerificationMode
+		query       string
+		expectError *testutils.ExpectResolverError
+	}{
+		{
+			title: "Should login unverified account in optional mode",
+			mode:  application.EmailVerificationOptional,
+			query: loginQuery,
+		},
+		{
+			title: "Should login unverified account in restricted mode",
+			mode:  application.EmailVerificationRestricted,
+			query: loginQuery,
+		},
+		{
+			title:       "Should refuse sensitive operation in restricted mode",
+			mode:        application.EmailVerificationRestricted,
+			query:       `mutation { enrollMfa { secret } }`,
+			expectError: notVerifiedError,
+		},
+		{
+			title:       "Should refuse login in required mode",
+			mode:        application.EmailVerificationRequired,
+			query:       loginQuery,
+			expectError: notVerifiedError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			app.Config.EmailVerification.Mode = tt.mode
+
+			result := schema.Exec(queryContext, tt.query, "", nil)
+
+			if tt.expectError != nil {
+				testutils.TestGqlError(t, result.Errors[0], tt.expectError)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors[0])
+			}
+		})
+	}
+}
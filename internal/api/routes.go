@@ -24,6 +24,8 @@ func Routes(app *application.Application) http.Handler {
 		router.HandlerFunc(http.MethodGet, "/check/token", handler.AuthToken(app))
 	}
 
+	router.HandlerFunc(http.MethodGet, "/.well-known/jwks.json", handler.JWKS(app))
+
 	//-------------------//
 	//			GraphQL			 //
 	//-------------------//
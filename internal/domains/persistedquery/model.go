@@ -0,0 +1,82 @@
+package persistedquery
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFoundPersistedQuery = errors.New("Persisted query not found")
+	ErrPersistedQueryLimit    = errors.New("Persisted query limit reached")
+)
+
+// PersistedQuery is the text of a GraphQL query registered by a client, so it can be sent
+// again by its hash only.
+type PersistedQuery struct {
+	Hash      string
+	CreatedAt time.Time
+	Query     string
+}
+
+// Hash returns the hexadecimal SHA-256 hash of a query, the hash clients send.
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+type Model struct {
+	DB *sql.DB
+}
+
+func (m Model) GetByHash(hash string) (*PersistedQuery, error) {
+	query := `
+		SELECT hash, created_at, query
+		FROM persisted_query
+		WHERE hash = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var q PersistedQuery
+
+	err := m.DB.QueryRowContext(ctx, query, hash).Scan(&q.Hash, &q.CreatedAt, &q.Query)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFoundPersistedQuery
+		default:
+			return nil, err
+		}
+	}
+
+	return &q, nil
+}
+
+// Insert registers a query unless limit queries are already registered, a query already
+// registered is left unchanged. ErrPersistedQueryLimit is returned once the limit is
+// reached, concurrent insertions can exceed it slightly.
+func (m Model) Insert(q *PersistedQuery, limit int) error {
+	query := `
+		INSERT INTO persisted_query (hash, query)
+		SELECT $1, $2
+		WHERE (SELECT COUNT(*) FROM persisted_query) < $3
+		ON CONFLICT (hash) DO NOTHING
+		RETURNING created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, q.Hash, q.Query, limit).Scan(&q.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// nothing inserted: the query is already registered, or the limit is reached
+		if _, err = m.GetByHash(q.Hash); errors.Is(err, ErrNotFoundPersistedQuery) {
+			return ErrPersistedQueryLimit
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,66 @@
+package persistedquery_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brice-74/golang-base-api/internal/domains/persistedquery"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+)
+
+func TestHash(t *testing.T) {
+	// printf "{__typename}" | sha256sum
+	expect := "ecf4edb46db40b5132295c0291d62fb65d6759a9eedfa4d5d612dd5ec54a6b38"
+
+	if got := persistedquery.Hash("{__typename}"); got != expect {
+		t.Fatalf("got hash: %s, expect: %s", got, expect)
+	}
+}
+
+func TestInsertAndGetByHash(t *testing.T) {
+	var (
+		db = testutils.PrepareDB(t)
+		m  = persistedquery.Model{DB: db}
+	)
+
+	q := &persistedquery.PersistedQuery{Hash: persistedquery.Hash("{queryCheck}"), Query: "{queryCheck}"}
+
+	t.Run("should not find unknown query", func(t *testing.T) {
+		if _, err := m.GetByHash(q.Hash); !errors.Is(err, persistedquery.ErrNotFoundPersistedQuery) {
+			t.Fatalf("got error: %v, expect: %v", err, persistedquery.ErrNotFoundPersistedQuery)
+		}
+	})
+
+	t.Run("should insert query", func(t *testing.T) {
+		if err := m.Insert(q, 2); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := m.GetByHash(q.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Query != q.Query || got.CreatedAt.IsZero() {
+			t.Fatalf("got query: %+v, expect: %+v", got, q)
+		}
+	})
+
+	t.Run("should ignore query already registered", func(t *testing.T) {
+		if err := m.Insert(q, 1); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("should refuse query over the limit", func(t *testing.T) {
+		other := &persistedquery.PersistedQuery{Hash: persistedquery.Hash("{__typename}"), Query: "{__typename}"}
+
+		if err := m.Insert(other, 1); !errors.Is(err, persistedquery.ErrPersistedQueryLimit) {
+			t.Fatalf("got error: %v, expect: %v", err, persistedquery.ErrPersistedQueryLimit)
+		}
+
+		if _, err := m.GetByHash(other.Hash); !errors.Is(err, persistedquery.ErrNotFoundPersistedQuery) {
+			t.Fatalf("got error: %v, expect: %v", err, persistedquery.ErrNotFoundPersistedQuery)
+		}
+	})
+}
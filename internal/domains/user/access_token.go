@@ -0,0 +1,75 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AccessTokenPrefix starts every personal access token, to tell them apart from JWT.
+const AccessTokenPrefix = "pat_"
+
+const (
+	ScopeRead  Scope = "READ"
+	ScopeWrite Scope = "WRITE"
+)
+
+// Scope limits what a personal access token is allowed to do.
+type Scope string
+type Scopes []Scope
+
+var AllScopes = Scopes{ScopeRead, ScopeWrite}
+
+// Scan allows custom type to be Scanned by databases, by implementing the Scanner interface.
+func (s *Scope) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		*s = Scope(v)
+	default:
+		return fmt.Errorf("cannot scan type Scope with type %T", v)
+	}
+
+	return nil
+}
+
+// Has checks if the scope is part of the list.
+func (ss Scopes) Has(scope Scope) bool {
+	for _, s := range ss {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AccessToken is a long-lived token for machine clients, only its hash is stored.
+type AccessToken struct {
+	ID         string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	Name       string
+	Scopes     Scopes
+	UserID     string
+}
+
+// Expired checks if the token can no longer be used, a zero ExpiresAt means never.
+func (t *AccessToken) Expired() bool {
+	return !t.ExpiresAt.IsZero() && time.Now().After(t.ExpiresAt)
+}
+
+// GenerateAccessToken creates the value of a personal access token.
+func GenerateAccessToken() (string, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	return AccessTokenPrefix + token, nil
+}
+
+// IsAccessToken checks if a bearer value is a personal access token.
+func IsAccessToken(bearer string) bool {
+	return strings.HasPrefix(bearer, AccessTokenPrefix)
+}
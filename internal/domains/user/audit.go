@@ -0,0 +1,28 @@
+package user
+
+import "time"
+
+const (
+	AuditLogin           AuditAction = "LOGIN"
+	AuditPasswordChange  AuditAction = "PASSWORD_CHANGE"
+	AuditPasswordReset   AuditAction = "PASSWORD_RESET"
+	AuditEmailChange     AuditAction = "EMAIL_CHANGE"
+	AuditMfaEnable       AuditAction = "MFA_ENABLE"
+	AuditMfaDisable      AuditAction = "MFA_DISABLE"
+	AuditSessionRevoke   AuditAction = "SESSION_REVOKE"
+	AuditDeletionRequest AuditAction = "DELETION_REQUEST"
+	AuditDataExport      AuditAction = "DATA_EXPORT"
+)
+
+// AuditAction is a security relevant action done on an account, kept in its audit log.
+type AuditAction string
+
+// AuditEntry records an action of the audit log of an account.
+type AuditEntry struct {
+	ID        string
+	CreatedAt time.Time
+	Action    AuditAction
+	// IP is the address of the client doing the action, empty when unknown.
+	IP     string
+	UserID string
+}
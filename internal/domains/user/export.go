@@ -0,0 +1,131 @@
+package user
+
+import "time"
+
+// DataExport is the archive of the personal data held about a user, returned to the
+// user on request. Secrets such as the password hash or token hashes are left out.
+type DataExport struct {
+	ExportedAt    time.Time              `json:"exportedAt"`
+	Account       ExportedAccount        `json:"account"`
+	Sessions      []ExportedSession      `json:"sessions"`
+	AccessTokens  []ExportedAccessToken  `json:"accessTokens"`
+	AuditEntries  []ExportedAuditEntry   `json:"auditEntries"`
+	LoginFailures []ExportedLoginFailure `json:"loginFailures"`
+}
+
+type ExportedAccount struct {
+	ID            string     `json:"id"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	DeactivatedAt *time.Time `json:"deactivatedAt"`
+	Email         string     `json:"email"`
+	VerifiedAt    *time.Time `json:"verifiedAt"`
+	Roles         Roles      `json:"roles"`
+	ProfilName    string     `json:"profilName"`
+	ShortId       string     `json:"shortId"`
+	FirstName     string     `json:"firstName"`
+	LastName      string     `json:"lastName"`
+	BirthDate     *time.Time `json:"birthDate"`
+	MfaEnabledAt  *time.Time `json:"mfaEnabledAt"`
+}
+
+type ExportedSession struct {
+	ID            string     `json:"id"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	DeactivatedAt time.Time  `json:"deactivatedAt"`
+	RevokedAt     *time.Time `json:"revokedAt"`
+	IP            string     `json:"ip"`
+	Agent         string     `json:"agent"`
+}
+
+type ExportedAccessToken struct {
+	ID         string     `json:"id"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	Name       string     `json:"name"`
+	Scopes     Scopes     `json:"scopes"`
+}
+
+type ExportedAuditEntry struct {
+	CreatedAt time.Time   `json:"createdAt"`
+	Action    AuditAction `json:"action"`
+	IP        string      `json:"ip"`
+}
+
+type ExportedLoginFailure struct {
+	CreatedAt time.Time `json:"createdAt"`
+	IP        string    `json:"ip"`
+}
+
+// NewDataExport builds the export of a user and of the records attached to the account.
+func NewDataExport(u User, sessions []*Session, tokens []*AccessToken, audit []*AuditEntry, failures []ExportedLoginFailure) DataExport {
+	export := DataExport{
+		ExportedAt: time.Now().UTC(),
+		Account: ExportedAccount{
+			ID:            u.ID,
+			CreatedAt:     u.CreatedAt,
+			UpdatedAt:     u.UpdatedAt,
+			DeactivatedAt: nullableTime(u.DeactivatedAt),
+			Email:         u.Email,
+			VerifiedAt:    nullableTime(u.VerifiedAt),
+			Roles:         u.Roles,
+			ProfilName:    u.ProfilName,
+			ShortId:       u.ShortId,
+			FirstName:     u.FirstName,
+			LastName:      u.LastName,
+			BirthDate:     nullableTime(u.BirthDate),
+			MfaEnabledAt:  nullableTime(u.MfaEnabledAt),
+		},
+		Sessions:      make([]ExportedSession, len(sessions)),
+		AccessTokens:  make([]ExportedAccessToken, len(tokens)),
+		AuditEntries:  make([]ExportedAuditEntry, len(audit)),
+		LoginFailures: failures,
+	}
+
+	for i, s := range sessions {
+		export.Sessions[i] = ExportedSession{
+			ID:            s.ID,
+			CreatedAt:     s.CreatedAt,
+			UpdatedAt:     s.UpdatedAt,
+			DeactivatedAt: s.DeactivatedAt,
+			RevokedAt:     nullableTime(s.RevokedAt),
+			IP:            s.IP,
+			Agent:         s.Agent,
+		}
+	}
+
+	for i, t := range tokens {
+		export.AccessTokens[i] = ExportedAccessToken{
+			ID:         t.ID,
+			CreatedAt:  t.CreatedAt,
+			ExpiresAt:  nullableTime(t.ExpiresAt),
+			LastUsedAt: nullableTime(t.LastUsedAt),
+			Name:       t.Name,
+			Scopes:     t.Scopes,
+		}
+	}
+
+	for i, e := range audit {
+		export.AuditEntries[i] = ExportedAuditEntry{
+			CreatedAt: e.CreatedAt,
+			Action:    e.Action,
+			IP:        e.IP,
+		}
+	}
+
+	if export.LoginFailures == nil {
+		export.LoginFailures = []ExportedLoginFailure{}
+	}
+
+	return export
+}
+
+// nullableTime returns nil for a zero time, so that it is exported as null.
+func nullableTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
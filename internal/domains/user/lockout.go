@@ -0,0 +1,38 @@
+package user
+
+import "time"
+
+// LoginFailures counts the recent failed logins of an account and of an IP address.
+type LoginFailures struct {
+	Account     int
+	LastAccount time.Time
+	IP          int
+	LastIP      time.Time
+}
+
+// Lockout defines when failed logins lock further attempts.
+type Lockout struct {
+	// Threshold is the number of failures locking logins, 0 disables the lockout.
+	Threshold int
+	// BaseDelay is the duration of the first lock, doubled by each new failure.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// LockedUntil returns the end of the lock caused by failures, the last one at last.
+// The returned time is zero when failures don't lock logins.
+func (l Lockout) LockedUntil(failures int, last time.Time) time.Time {
+	if l.Threshold <= 0 || failures < l.Threshold {
+		return time.Time{}
+	}
+
+	delay := l.MaxDelay
+	// stop doubling before it overflows, the max delay is reached long before
+	if exp := failures - l.Threshold; exp < 32 {
+		if d := l.BaseDelay << exp; d > 0 && d < l.MaxDelay {
+			delay = d
+		}
+	}
+
+	return last.Add(delay)
+}
@@ -0,0 +1,63 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+)
+
+func TestLockedUntil(t *testing.T) {
+	var (
+		last = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+		l    = user.Lockout{
+			Threshold: 3,
+			BaseDelay: time.Minute,
+			MaxDelay:  time.Hour,
+		}
+	)
+
+	tests := []struct {
+		title    string
+		lockout  user.Lockout
+		failures int
+		expect   time.Time
+	}{
+		{
+			title:    "Should not lock under threshold",
+			lockout:  l,
+			failures: 2,
+		},
+		{
+			title:    "Should lock for base delay at threshold",
+			lockout:  l,
+			failures: 3,
+			expect:   last.Add(time.Minute),
+		},
+		{
+			title:    "Should double delay for each new failure",
+			lockout:  l,
+			failures: 5,
+			expect:   last.Add(4 * time.Minute),
+		},
+		{
+			title:    "Should cap delay",
+			lockout:  l,
+			failures: 100,
+			expect:   last.Add(time.Hour),
+		},
+		{
+			title:    "Should not lock when disabled",
+			lockout:  user.Lockout{BaseDelay: time.Minute, MaxDelay: time.Hour},
+			failures: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := tt.lockout.LockedUntil(tt.failures, last); !got.Equal(tt.expect) {
+				t.Fatalf("got: %s, expect: %s", got, tt.expect)
+			}
+		})
+	}
+}
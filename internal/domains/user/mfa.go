@@ -0,0 +1,36 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// RecoveryCodesCount is the number of recovery codes generated when confirming 2FA.
+const RecoveryCodesCount = 10
+
+// GenerateRecoveryCodes creates one-time recovery codes formatted as "xxxxx-xxxxx",
+// only their hashes must be stored.
+func GenerateRecoveryCodes() (codes []string, hashes []string, err error) {
+	for i := 0; i < RecoveryCodesCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := hex.EncodeToString(b)
+		code = code[:5] + "-" + code[5:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode returns the stored form of a recovery code.
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/brice-74/golang-base-api/internal/utils"
@@ -14,8 +15,18 @@ import (
 var (
 	ErrNotFoundUserAndSession = errors.New("User or user session not found")
 	ErrNotFoundSession        = errors.New("User session not found")
+	ErrSessionExpired         = errors.New("Session expired")
+	ErrSessionRevoked         = errors.New("Session revoked")
 	ErrNotFoundUser           = errors.New("User not found")
 	ErrDuplicateEmail         = errors.New("Duplicate email")
+	ErrRefreshTokenReused     = errors.New("Refresh token already used")
+	ErrMfaAlreadyEnabled      = errors.New("Two-factor authentication already enabled")
+	ErrMfaNotEnrolled         = errors.New("Two-factor authentication enrollment not started")
+	ErrInvalidRecoveryCode    = errors.New("Invalid recovery code")
+	ErrMfaCodeReused          = errors.New("Two-factor authentication code already used")
+	ErrInvalidResetToken      = errors.New("Invalid or expired password reset token")
+	ErrNotFoundAccessToken    = errors.New("Access token not found")
+	ErrDeactivatedUser        = errors.New("User account deactivated")
 )
 
 type Model struct {
@@ -49,132 +60,1469 @@ func (m Model) GetByEmail(email string) (*User, error) {
 	return m.getBy("email", email)
 }
 
-func (m Model) getBy(column string, value interface{}) (*User, error) {
-	query := fmt.Sprintf(`
-		SELECT 
-			id,
-			created_at,
-			updated_at,
-			deactivated_at,
-			email,
-			password,
-			roles,
-			profil_name, 
-			short_id
-		FROM "user_account"
-		WHERE %s = $1`, column)
+func (m Model) GetByShortId(shortId string) (*User, error) {
+	return m.getBy("short_id", shortId)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// userColumns are the columns of user_account, aliased u, read by scanUser, followed by
+// userSecretColumns when the secrets are read.
+const (
+	userColumns = `
+		u.id,
+		u.created_at,
+		u.updated_at,
+		u.deactivated_at,
+		u.email,
+		u.roles,
+		u.profil_name,
+		u.short_id,
+		u.verified_at,
+		u.mfa_enabled_at,
+		u.first_name,
+		u.last_name,
+		u.birth_date,
+		u.profile_visibility`
+	userSecretColumns = `
+		u.password,
+		u.mfa_secret`
+)
+
+// scanner reads the columns of a row, as *sql.Row and *sql.Rows do.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
 
+// scanUser reads a user from the userColumns of a row, followed by the userSecretColumns
+// when secrets is true, the next columns are read into dest.
+func scanUser(row scanner, secrets bool, dest ...interface{}) (*User, error) {
 	var (
 		user          User
 		deactivatedAt pq.NullTime
+		verifiedAt    pq.NullTime
+		mfaSecret     sql.NullString
+		mfaEnabledAt  pq.NullTime
+		firstName     sql.NullString
+		lastName      sql.NullString
+		birthDate     pq.NullTime
 	)
 
-	err := m.DB.QueryRowContext(ctx, query, value).Scan(
+	columns := []interface{}{
 		&user.ID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&deactivatedAt,
 		&user.Email,
-		&user.Password,
 		pq.Array(&user.Roles),
 		&user.ProfilName,
 		&user.ShortId,
+		&verifiedAt,
+		&mfaEnabledAt,
+		&firstName,
+		&lastName,
+		&birthDate,
+		&user.ProfileVisibility,
+	}
+
+	if secrets {
+		columns = append(columns, &user.Password, &mfaSecret)
+	}
+
+	if err := row.Scan(append(columns, dest...)...); err != nil {
+		return nil, err
+	}
+
+	user.DeactivatedAt = deactivatedAt.Time
+	user.VerifiedAt = verifiedAt.Time
+	user.MfaSecret = mfaSecret.String
+	user.MfaEnabledAt = mfaEnabledAt.Time
+	user.FirstName = firstName.String
+	user.LastName = lastName.String
+	user.BirthDate = birthDate.Time
+
+	return &user, nil
+}
+
+// GetByIds returns the users of the ids in any order, missing users are left out.
+func (m Model) GetByIds(ids []string) ([]*User, error) {
+	query := fmt.Sprintf(`
+		SELECT %s, %s
+		FROM "user_account" AS u
+		WHERE u.id = ANY($1)`, userColumns, userSecretColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var us []*User
+
+	for rows.Next() {
+		u, err := scanUser(rows, true)
+		if err != nil {
+			return nil, err
+		}
+
+		us = append(us, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return us, nil
+}
+
+func (m Model) getBy(column string, value interface{}) (*User, error) {
+	query := fmt.Sprintf(`
+		SELECT %s, %s
+		FROM "user_account" AS u
+		WHERE u.%s = $1`, userColumns, userSecretColumns, column)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	user, err := scanUser(m.DB.QueryRowContext(ctx, query, value), true)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFoundUser
+		default:
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func (m Model) InsertRegisteredUserAccount(user *User) error {
+	query := `
+		INSERT INTO "user_account" (
+			email,
+			password,
+			roles,
+			profil_name, 
+			short_id,
+			verified_at
+		) 
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at, deactivated_at, profile_visibility`
+
+	args := []interface{}{
+		user.Email,
+		user.Password,
+		pq.Array(user.Roles),
+		user.ProfilName,
+		user.ShortId,
+		pq.NullTime{Time: user.VerifiedAt, Valid: user.Verified()},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var deactivatedAt pq.NullTime
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&deactivatedAt,
+		&user.ProfileVisibility,
 	)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "user_account_email_key"`:
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+
+	user.DeactivatedAt = deactivatedAt.Time
+
+	return nil
+}
+
+// InsertUserSession inserts a new session, the session ID must be generated by the server.
+func (m Model) InsertUserSession(session *Session) error {
+	query := `
+		INSERT INTO "user_session" (
+			id,
+			deactivated_at,
+			ip,
+			agent,
+			user_id,
+			refresh_token_id
+		) 
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, updated_at`
+
+	args := []interface{}{
+		session.ID,
+		session.DeactivatedAt,
+		session.IP,
+		session.Agent,
+		session.UserID,
+		session.RefreshTokenID,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&session.CreatedAt, &session.UpdatedAt)
+}
+
+// UpdateUserSession reopens an existing session, only if it belongs to the session user.
+// A revoked session stays revoked: ErrSessionRevoked is returned instead.
+func (m Model) UpdateUserSession(session *Session) error {
+	query := `
+		UPDATE user_session SET
+			updated_at = NOW(),
+			deactivated_at = $3,
+			ip = $4,
+			agent = $5,
+			refresh_token_id = $6
+		WHERE id = $1
+		AND user_id = $2
+		AND revoked_at IS NULL
+		RETURNING created_at, updated_at`
+
+	args := []interface{}{
+		session.ID,
+		session.UserID,
+		session.DeactivatedAt,
+		session.IP,
+		session.Agent,
+		session.RefreshTokenID,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return m.notUpdatedSessionError(session)
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// notUpdatedSessionError tells apart a revoked session of the user from a session that
+// doesn't exist or belongs to another user.
+func (m Model) notUpdatedSessionError(session *Session) error {
+	existing, err := m.GetSessionByID(session.ID)
+	if err != nil {
+		return err
+	}
+
+	if existing.UserID != session.UserID {
+		return ErrNotFoundSession
+	}
+
+	return ErrSessionRevoked
+}
+
+// RotateSessionRefreshToken replaces the refresh token id of a session with the one
+// carried by the session, only if previousID is still the current one. It returns
+// ErrRefreshTokenReused when the previous refresh token has already been exchanged.
+func (m Model) RotateSessionRefreshToken(session *Session, previousID string) error {
+	query := `
+		UPDATE user_session SET
+			updated_at = NOW(),
+			deactivated_at = $3,
+			ip = $4,
+			agent = $5,
+			refresh_token_id = $6
+		WHERE id = $1
+		AND refresh_token_id = $2
+		RETURNING created_at, updated_at`
+
+	args := []interface{}{
+		session.ID,
+		previousID,
+		session.DeactivatedAt,
+		session.IP,
+		session.Agent,
+		session.RefreshTokenID,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRefreshTokenReused
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RevokeUserSession deactivates a session immediately.
+func (m Model) RevokeUserSession(id string) error {
+	query := `
+		UPDATE user_session SET
+			updated_at = NOW(),
+			deactivated_at = NOW(),
+			revoked_at = NOW()
+		WHERE id = $1
+		AND deactivated_at > NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+
+	return err
+}
+
+// RevokeAllUserSessions deactivates every active session of a user immediately
+// and returns the number of revoked sessions.
+func (m Model) RevokeAllUserSessions(userID string) (int, error) {
+	query := `
+		UPDATE user_session SET
+			updated_at = NOW(),
+			deactivated_at = NOW(),
+			revoked_at = NOW()
+		WHERE user_id = $1
+		AND deactivated_at > NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// RevokeOwnedUserSession deactivates a session immediately, only if it belongs to the user.
+func (m Model) RevokeOwnedUserSession(id string, userID string) error {
+	query := `
+		UPDATE user_session SET
+			updated_at = NOW(),
+			deactivated_at = LEAST(deactivated_at, NOW()),
+			revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1
+		AND user_id = $2
+		RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFoundSession
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RevokeOtherUserSessions deactivates every active session of a user except keptID immediately
+// and returns the number of revoked sessions.
+func (m Model) RevokeOtherUserSessions(userID string, keptID string) (int, error) {
+	query := `
+		UPDATE user_session SET
+			updated_at = NOW(),
+			deactivated_at = NOW(),
+			revoked_at = NOW()
+		WHERE user_id = $1
+		AND id <> $2
+		AND deactivated_at > NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, userID, keptID)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// DeactivateUserAccount deactivates a user account and revokes its active sessions,
+// an account already deactivated keeps its deactivation date.
+func (m Model) DeactivateUserAccount(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE user_account SET
+			updated_at = NOW(),
+			deactivated_at = COALESCE(deactivated_at, NOW())
+		WHERE id = $1
+		RETURNING id`, id).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFoundUser
+		default:
+			return err
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE user_session SET
+			updated_at = NOW(),
+			deactivated_at = NOW(),
+			revoked_at = NOW()
+		WHERE user_id = $1
+		AND deactivated_at > NOW()`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ScheduleUserAccountDeletion deactivates a user account, revokes its active sessions
+// and schedules its deletion at deleteAt, see PurgeDeletedUserAccounts.
+func (m Model) ScheduleUserAccountDeletion(id string, deleteAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE user_account SET
+			updated_at = NOW(),
+			deactivated_at = COALESCE(deactivated_at, NOW()),
+			deletion_scheduled_at = $2
+		WHERE id = $1
+		RETURNING id`, id, deleteAt).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFoundUser
+		default:
+			return err
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE user_session SET
+			updated_at = NOW(),
+			deactivated_at = NOW(),
+			revoked_at = NOW()
+		WHERE user_id = $1
+		AND deactivated_at > NOW()`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeDeletedUserAccounts deletes the user accounts whose scheduled deletion has come,
+// their sessions, tokens and login failures are deleted in cascade.
+// Returns the number of deleted accounts.
+func (m Model) PurgeDeletedUserAccounts() (int, error) {
+	query := `
+		DELETE FROM user_account
+		WHERE deletion_scheduled_at <= NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// ReactivateUserAccount clears the deactivation of a user account, a scheduled deletion is canceled.
+func (m Model) ReactivateUserAccount(id string) error {
+	query := `
+		UPDATE user_account SET
+			updated_at = NOW(),
+			deactivated_at = NULL,
+			deletion_scheduled_at = NULL
+		WHERE id = $1
+		RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFoundUser
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateUserRoles replaces the roles of a user.
+func (m Model) UpdateUserRoles(id string, roles Roles) error {
+	query := `
+		UPDATE user_account SET
+			updated_at = NOW(),
+			roles = $2
+		WHERE id = $1
+		RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, pq.Array(roles)).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFoundUser
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// VerifyUserEmail marks the email of a user as verified, only if the user still has this email.
+func (m Model) VerifyUserEmail(userID string, email string) error {
+	query := `
+		UPDATE user_account SET
+			updated_at = NOW(),
+			verified_at = COALESCE(verified_at, NOW())
+		WHERE id = $1
+		AND email = $2
+		RETURNING verified_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var verifiedAt time.Time
+
+	err := m.DB.QueryRowContext(ctx, query, userID, email).Scan(&verifiedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFoundUser
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetUserMfaSecret starts a 2FA enrollment, the secret replaces any unconfirmed one.
+func (m Model) SetUserMfaSecret(userID string, secret string) error {
+	query := `
+		UPDATE user_account SET
+			updated_at = NOW(),
+			mfa_secret = $2,
+			mfa_last_step = 0
+		WHERE id = $1
+		AND mfa_enabled_at IS NULL
+		RETURNING updated_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var updatedAt time.Time
+
+	err := m.DB.QueryRowContext(ctx, query, userID, secret).Scan(&updatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrMfaAlreadyEnabled
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EnableUserMfa confirms a 2FA enrollment and replaces the recovery codes by the given hashes.
+func (m Model) EnableUserMfa(userID string, recoveryCodeHashes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var enabledAt time.Time
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE user_account SET
+			updated_at = NOW(),
+			mfa_enabled_at = NOW()
+		WHERE id = $1
+		AND mfa_secret IS NOT NULL
+		AND mfa_enabled_at IS NULL
+		RETURNING mfa_enabled_at`, userID).Scan(&enabledAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrMfaNotEnrolled
+		default:
+			return err
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM user_recovery_code WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		INSERT INTO user_recovery_code (user_id, code_hash)
+		SELECT $1, UNNEST($2::TEXT[])`, userID, pq.Array(recoveryCodeHashes)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DisableUserMfa removes the 2FA secret and the recovery codes of a user.
+func (m Model) DisableUserMfa(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE user_account SET
+			updated_at = NOW(),
+			mfa_secret = NULL,
+			mfa_enabled_at = NULL
+		WHERE id = $1`, userID); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM user_recovery_code WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseMfaStep records the time step of an accepted TOTP code of a user, it returns
+// ErrMfaCodeReused when a code of this step or of a newer one has already been accepted.
+func (m Model) UseMfaStep(userID string, step int64) error {
+	query := `
+		UPDATE user_account SET
+			mfa_last_step = $2
+		WHERE id = $1
+		AND mfa_last_step < $2
+		RETURNING mfa_last_step`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var lastStep int64
+
+	err := m.DB.QueryRowContext(ctx, query, userID, step).Scan(&lastStep)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrMfaCodeReused
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode consumes an unused recovery code of a user.
+func (m Model) UseRecoveryCode(userID string, codeHash string) error {
+	query := `
+		UPDATE user_recovery_code SET
+			used_at = NOW()
+		WHERE user_id = $1
+		AND code_hash = $2
+		AND used_at IS NULL
+		RETURNING used_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var usedAt time.Time
+
+	err := m.DB.QueryRowContext(ctx, query, userID, codeHash).Scan(&usedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrInvalidRecoveryCode
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InsertPasswordReset stores the hash of a password reset token.
+func (m Model) InsertPasswordReset(userID string, tokenHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO user_password_reset (
+			user_id,
+			token_hash,
+			expires_at
+		)
+		VALUES ($1, $2, $3)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, tokenHash, expiresAt)
+
+	return err
+}
+
+// UpdateUserPassword replaces the password hash of a user.
+func (m Model) UpdateUserPassword(userID string, passwordHash string) error {
+	query := `
+		UPDATE user_account SET
+			updated_at = NOW(),
+			password = $2
+		WHERE id = $1
+		RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID, passwordHash).Scan(&userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFoundUser
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateUserEmail replaces the email of a user, only if the user still has currentEmail.
+// The change is confirmed from the new address, so the user becomes verified.
+func (m Model) UpdateUserEmail(userID string, currentEmail string, newEmail string) error {
+	query := `
+		UPDATE user_account SET
+			updated_at = NOW(),
+			email = $3,
+			verified_at = NOW()
+		WHERE id = $1
+		AND email = $2
+		RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID, currentEmail, newEmail).Scan(&userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFoundUser
+		case err.Error() == `pq: duplicate key value violates unique constraint "user_account_email_key"`:
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateUserProfile replaces the profile fields of a user, empty optional fields are cleared.
+func (m Model) UpdateUserProfile(user *User) error {
+	query := `
+		UPDATE user_account SET
+			updated_at = NOW(),
+			first_name = $2,
+			last_name = $3,
+			birth_date = $4,
+			profile_visibility = $5
+		WHERE id = $1
+		RETURNING updated_at`
+
+	args := []interface{}{
+		user.ID,
+		sql.NullString{String: user.FirstName, Valid: user.FirstName != ""},
+		sql.NullString{String: user.LastName, Valid: user.LastName != ""},
+		pq.NullTime{Time: user.BirthDate, Valid: !user.BirthDate.IsZero()},
+		user.ProfileVisibility,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFoundUser
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ResetUserPassword consumes a password reset token to replace the password of its user,
+// every pending reset token, active session and access token of the user are revoked. Receiving the
+// token proves the ownership of the email, so the user becomes verified and unlocked.
+func (m Model) ResetUserPassword(tokenHash string, passwordHash string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var userID string
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE user_password_reset SET
+			used_at = NOW()
+		WHERE token_hash = $1
+		AND used_at IS NULL
+		AND expires_at > NOW()
+		RETURNING user_id`, tokenHash).Scan(&userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", ErrInvalidResetToken
+		default:
+			return "", err
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE user_account SET
+			updated_at = NOW(),
+			password = $2,
+			verified_at = COALESCE(verified_at, NOW())
+		WHERE id = $1`, userID, passwordHash); err != nil {
+		return "", err
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE user_password_reset SET
+			used_at = NOW()
+		WHERE user_id = $1
+		AND used_at IS NULL`, userID); err != nil {
+		return "", err
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE user_session SET
+			updated_at = NOW(),
+			deactivated_at = NOW(),
+			revoked_at = NOW()
+		WHERE user_id = $1
+		AND deactivated_at > NOW()`, userID); err != nil {
+		return "", err
+	}
+
+	// access tokens created by someone who took over the account are revoked too
+	if _, err = tx.ExecContext(ctx, `
+		DELETE FROM user_access_token
+		WHERE user_id = $1`, userID); err != nil {
+		return "", err
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		DELETE FROM user_login_failure
+		WHERE user_id = $1`, userID); err != nil {
+		return "", err
+	}
+
+	return userID, tx.Commit()
+}
+
+// InsertAccessToken stores a personal access token with the hash of its value.
+func (m Model) InsertAccessToken(token *AccessToken, tokenHash string) error {
+	query := `
+		INSERT INTO user_access_token (
+			expires_at,
+			name,
+			scopes,
+			token_hash,
+			user_id
+		)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	args := []interface{}{
+		pq.NullTime{Time: token.ExpiresAt, Valid: !token.ExpiresAt.IsZero()},
+		token.Name,
+		pq.Array(token.Scopes),
+		tokenHash,
+		token.UserID,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetAllAccessTokens returns the personal access tokens of a user, the most recent first.
+func (m Model) GetAllAccessTokens(userID string) ([]*AccessToken, error) {
+	query := `
+		SELECT
+			id,
+			created_at,
+			expires_at,
+			last_used_at,
+			name,
+			scopes,
+			user_id
+		FROM user_access_token
+		WHERE user_id = $1
+		ORDER BY created_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*AccessToken{}
+
+	for rows.Next() {
+		var (
+			t          AccessToken
+			expiresAt  pq.NullTime
+			lastUsedAt pq.NullTime
+		)
+
+		if err = rows.Scan(
+			&t.ID,
+			&t.CreatedAt,
+			&expiresAt,
+			&lastUsedAt,
+			&t.Name,
+			pq.Array(&t.Scopes),
+			&t.UserID,
+		); err != nil {
+			return nil, err
+		}
+
+		t.ExpiresAt = expiresAt.Time
+		t.LastUsedAt = lastUsedAt.Time
+
+		tokens = append(tokens, &t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// DeleteAccessToken revokes a personal access token, only if it belongs to the user.
+func (m Model) DeleteAccessToken(id string, userID string) error {
+	query := `
+		DELETE FROM user_access_token
+		WHERE id = $1
+		AND user_id = $2
+		RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFoundAccessToken
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetUserByAccessToken returns an unexpired personal access token with its user,
+// and records that the token was used.
+func (m Model) GetUserByAccessToken(tokenHash string) (*User, *AccessToken, error) {
+	query := fmt.Sprintf(`
+		WITH t AS (
+			UPDATE user_access_token SET
+				last_used_at = NOW()
+			WHERE token_hash = $1
+			AND (expires_at IS NULL OR expires_at > NOW())
+			RETURNING id, created_at, expires_at, last_used_at, name, scopes, user_id
+		)
+		SELECT %s, %s,
+			t.id,
+			t.created_at,
+			t.expires_at,
+			t.last_used_at,
+			t.name,
+			t.scopes,
+			t.user_id
+		FROM t
+		INNER JOIN user_account AS u
+			ON u.id = t.user_id`, userColumns, userSecretColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var (
+		token     AccessToken
+		expiresAt pq.NullTime
+	)
+
+	user, err := scanUser(m.DB.QueryRowContext(ctx, query, tokenHash), true,
+		&token.ID,
+		&token.CreatedAt,
+		&expiresAt,
+		&token.LastUsedAt,
+		&token.Name,
+		pq.Array(&token.Scopes),
+		&token.UserID,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrNotFoundAccessToken
+		default:
+			return nil, nil, err
+		}
+	}
+
+	token.ExpiresAt = expiresAt.Time
+
+	return user, &token, nil
+}
+
+// InsertLoginFailure records a failed login from ip, userID is empty when no account matches.
+func (m Model) InsertLoginFailure(userID string, ip string) error {
+	query := `
+		INSERT INTO user_login_failure (
+			ip,
+			user_id
+		)
+		VALUES ($1, $2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, ip, sql.NullString{String: userID, Valid: userID != ""})
+	return err
+}
+
+// GetLoginFailures counts the failed logins of an account and of an ip since a time.
+func (m Model) GetLoginFailures(userID string, ip string, since time.Time) (*LoginFailures, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getLoginFailures(ctx, m.DB, userID, ip, since)
+}
+
+// rowQuerier runs queries on a database or in a transaction.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func getLoginFailures(ctx context.Context, q rowQuerier, userID string, ip string, since time.Time) (*LoginFailures, error) {
+	query := `
+		SELECT
+			COUNT(1) FILTER (WHERE user_id = $1),
+			MAX(created_at) FILTER (WHERE user_id = $1),
+			COUNT(1) FILTER (WHERE ip = $2),
+			MAX(created_at) FILTER (WHERE ip = $2)
+		FROM user_login_failure
+		WHERE created_at > $3
+		AND (user_id = $1 OR ip = $2)`
+
+	var (
+		f           LoginFailures
+		lastAccount pq.NullTime
+		lastIP      pq.NullTime
+	)
+
+	err := q.QueryRowContext(
+		ctx,
+		query,
+		sql.NullString{String: userID, Valid: userID != ""},
+		ip,
+		since,
+	).Scan(
+		&f.Account,
+		&lastAccount,
+		&f.IP,
+		&lastIP,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	f.LastAccount = lastAccount.Time
+	f.LastIP = lastIP.Time
+
+	return &f, nil
+}
+
+// InsertLoginAttempt records a login attempt of an account from ip as a failure, before
+// its credentials are checked, unless the failures since a time lock the account or the
+// ip. It returns the failures recorded before the attempt, and the id of the attempt or
+// an empty id if it's locked. Attempts of the same account or ip are serialized, so
+// concurrent attempts can't get past the lockouts. A successful attempt is forgotten with
+// DeleteLoginFailure. userID is empty when no account matches.
+func (m Model) InsertLoginAttempt(
+	userID string,
+	ip string,
+	since time.Time,
+	accountLockout Lockout,
+	ipLockout Lockout,
+) (*LoginFailures, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	// the locks are released at the end of the transaction, always taken in the same
+	// order so concurrent attempts can't deadlock
+	keys := []string{"user_login_failure:ip:" + ip}
+	if userID != "" {
+		keys = []string{"user_login_failure:user:" + userID, keys[0]}
+	}
+	for _, key := range keys {
+		if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, key); err != nil {
+			return nil, "", err
+		}
+	}
+
+	f, err := getLoginFailures(ctx, tx, userID, ip, since)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	if accountLockout.LockedUntil(f.Account, f.LastAccount).After(now) || ipLockout.LockedUntil(f.IP, f.LastIP).After(now) {
+		return f, "", nil
+	}
+
+	var id string
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO user_login_failure (
+			ip,
+			user_id
+		)
+		VALUES ($1, $2)
+		RETURNING id`, ip, sql.NullString{String: userID, Valid: userID != ""}).Scan(&id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return f, id, tx.Commit()
+}
+
+// DeleteLoginFailure forgets a failed login, the attempt of a login which succeeded.
+func (m Model) DeleteLoginFailure(id string) error {
+	query := `
+		DELETE FROM user_login_failure
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+// InsertAuditEntry records an action in the audit log of an account.
+func (m Model) InsertAuditEntry(e *AuditEntry) error {
+	query := `
+		INSERT INTO user_audit_log (
+			action,
+			ip,
+			user_id
+		)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, e.Action, e.IP, e.UserID).Scan(&e.ID, &e.CreatedAt)
+}
+
+// GetAuditEntries returns the audit log of an account, most recent first.
+func (m Model) GetAuditEntries(userID string) ([]*AuditEntry, error) {
+	query := `
+		SELECT
+			id,
+			created_at,
+			action,
+			ip,
+			user_id
+		FROM user_audit_log
+		WHERE user_id = $1
+		ORDER BY created_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*AuditEntry{}
+
+	for rows.Next() {
+		var e AuditEntry
 
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return nil, ErrNotFoundUser
-		default:
+		if err = rows.Scan(
+			&e.ID,
+			&e.CreatedAt,
+			&e.Action,
+			&e.IP,
+			&e.UserID,
+		); err != nil {
 			return nil, err
 		}
+
+		entries = append(entries, &e)
 	}
 
-	user.DeactivatedAt = deactivatedAt.Time
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return &user, nil
+	return entries, nil
 }
 
-func (m Model) InsertRegisteredUserAccount(user *User) error {
-	query := `
-		INSERT INTO "user_account" (
-			email,
-			password,
-			roles,
-			profil_name, 
-			short_id
-		) 
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at, updated_at, deactivated_at`
+// GetDataExport gathers the personal data of a user: the account, every session,
+// the personal access tokens, the audit log and the failed logins of the account.
+func (m Model) GetDataExport(userID string) (*DataExport, error) {
+	u, err := m.GetById(userID)
+	if err != nil {
+		return nil, err
+	}
 
-	args := []interface{}{
-		user.Email,
-		user.Password,
-		pq.Array(user.Roles),
-		user.ProfilName,
-		user.ShortId,
+	tokens, err := m.GetAllAccessTokens(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	audit, err := m.GetAuditEntries(userID)
+	if err != nil {
+		return nil, err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	var deactivatedAt pq.NullTime
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT
+			id,
+			created_at,
+			updated_at,
+			deactivated_at,
+			ip,
+			agent,
+			revoked_at
+		FROM user_session
+		WHERE user_id = $1
+		ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+
+	for rows.Next() {
+		var (
+			s         Session
+			revokedAt pq.NullTime
+		)
+
+		if err = rows.Scan(
+			&s.ID,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+			&s.DeactivatedAt,
+			&s.IP,
+			&s.Agent,
+			&revokedAt,
+		); err != nil {
+			return nil, err
+		}
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &deactivatedAt)
+		s.RevokedAt = revokedAt.Time
+
+		sessions = append(sessions, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	failureRows, err := m.DB.QueryContext(ctx, `
+		SELECT
+			created_at,
+			ip
+		FROM user_login_failure
+		WHERE user_id = $1
+		ORDER BY created_at DESC`, userID)
 	if err != nil {
-		switch {
-		case err.Error() == `pq: duplicate key value violates unique constraint "user_account_email_key"`:
-			return ErrDuplicateEmail
-		default:
-			return err
+		return nil, err
+	}
+	defer failureRows.Close()
+
+	var failures []ExportedLoginFailure
+
+	for failureRows.Next() {
+		var f ExportedLoginFailure
+
+		if err = failureRows.Scan(&f.CreatedAt, &f.IP); err != nil {
+			return nil, err
 		}
+
+		failures = append(failures, f)
 	}
 
-	user.DeactivatedAt = deactivatedAt.Time
+	if err = failureRows.Err(); err != nil {
+		return nil, err
+	}
 
-	return nil
+	export := NewDataExport(*u, sessions, tokens, audit, failures)
+
+	return &export, nil
 }
 
-func (m Model) InsertOrUpdateUserSession(session *Session) error {
+// UnlockUserAccount forgets the failed logins of an account.
+func (m Model) UnlockUserAccount(userID string) error {
 	query := `
-		INSERT INTO "user_session" (
+		DELETE FROM user_login_failure
+		WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID)
+	return err
+}
+
+func (m Model) GetSessionByID(id string) (*Session, error) {
+	return m.getSessionBy("id", id)
+}
+
+// GetSessionsByIds returns the sessions of the ids in any order, missing sessions are left out.
+func (m Model) GetSessionsByIds(ids []string) ([]*Session, error) {
+	query := `
+		SELECT 
 			id,
+			created_at,
+			updated_at,
 			deactivated_at,
 			ip,
 			agent,
-			user_id
-		) 
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (id) DO UPDATE SET  
-			deactivated_at = $2,
-			ip = $3,
-			agent = $4
-		RETURNING created_at, updated_at`
-
-	args := []interface{}{
-		session.ID,
-		session.DeactivatedAt,
-		session.IP,
-		session.Agent,
-		session.UserID,
-	}
+			user_id,
+			refresh_token_id,
+			revoked_at
+		FROM user_session
+		WHERE id = ANY($1)`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&session.CreatedAt, &session.UpdatedAt)
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	return nil
-}
+	var sessions []*Session
 
-func (m Model) GetSessionByID(id string) (*Session, error) {
-	return m.getSessionBy("id", id)
+	for rows.Next() {
+		var (
+			s         Session
+			revokedAt pq.NullTime
+		)
+
+		err := rows.Scan(
+			&s.ID,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+			&s.DeactivatedAt,
+			&s.IP,
+			&s.Agent,
+			&s.UserID,
+			&s.RefreshTokenID,
+			&revokedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		s.RevokedAt = revokedAt.Time
+
+		sessions = append(sessions, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
 }
 
 func (m Model) getSessionBy(column string, value interface{}) (*Session, error) {
@@ -186,7 +1534,9 @@ func (m Model) getSessionBy(column string, value interface{}) (*Session, error)
 			deactivated_at,
 			ip,
 			agent,
-			user_id
+			user_id,
+			refresh_token_id,
+			revoked_at
 		FROM user_session
 		WHERE %s = $1`, column)
 
@@ -194,7 +1544,8 @@ func (m Model) getSessionBy(column string, value interface{}) (*Session, error)
 	defer cancel()
 
 	var (
-		session Session
+		session   Session
+		revokedAt pq.NullTime
 	)
 
 	err := m.DB.QueryRowContext(ctx, query, value).Scan(
@@ -205,6 +1556,8 @@ func (m Model) getSessionBy(column string, value interface{}) (*Session, error)
 		&session.IP,
 		&session.Agent,
 		&session.UserID,
+		&session.RefreshTokenID,
+		&revokedAt,
 	)
 
 	if err != nil {
@@ -216,54 +1569,39 @@ func (m Model) getSessionBy(column string, value interface{}) (*Session, error)
 		}
 	}
 
+	session.RevokedAt = revokedAt.Time
+
 	return &session, nil
 }
 
 func (m Model) GetUserAndSession(userID, sessionID string) (*User, *Session, error) {
-	query := `
-		SELECT 
-			u.id,
-			u.created_at,
-			u.updated_at,
-			u.deactivated_at,
-			u.email,
-			u.password,
-			u.roles,
-			u.profil_name, 
-			u.short_id,
+	query := fmt.Sprintf(`
+		SELECT %s, %s,
 			s.id,
 			s.created_at,
 			s.updated_at,
 			s.deactivated_at,
 			s.ip,
 			s.agent,
-			s.user_id
+			s.user_id,
+			s.refresh_token_id,
+			s.revoked_at
 		FROM user_account AS u
 		INNER JOIN user_session AS s
 			ON s.user_id = u.id
 		WHERE u.id = $1
 		AND s.id = $2
-	`
+	`, userColumns, userSecretColumns)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	var (
-		session           Session
-		user              User
-		userDeactivatedAt pq.NullTime
+		session          Session
+		sessionRevokedAt pq.NullTime
 	)
 
-	err := m.DB.QueryRowContext(ctx, query, userID, sessionID).Scan(
-		&user.ID,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&userDeactivatedAt,
-		&user.Email,
-		&user.Password,
-		pq.Array(&user.Roles),
-		&user.ProfilName,
-		&user.ShortId,
+	user, err := scanUser(m.DB.QueryRowContext(ctx, query, userID, sessionID), true,
 		&session.ID,
 		&session.CreatedAt,
 		&session.UpdatedAt,
@@ -271,8 +1609,9 @@ func (m Model) GetUserAndSession(userID, sessionID string) (*User, *Session, err
 		&session.IP,
 		&session.Agent,
 		&session.UserID,
+		&session.RefreshTokenID,
+		&sessionRevokedAt,
 	)
-
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -282,9 +1621,9 @@ func (m Model) GetUserAndSession(userID, sessionID string) (*User, *Session, err
 		}
 	}
 
-	user.DeactivatedAt = userDeactivatedAt.Time
+	session.RevokedAt = sessionRevokedAt.Time
 
-	return &user, &session, nil
+	return user, &session, nil
 }
 
 func (m Model) GetAllSession(
@@ -300,7 +1639,9 @@ func (m Model) GetAllSession(
 			s.deactivated_at,
 			s.ip,
 			s.agent,
-			s.user_id
+			s.user_id,
+			s.refresh_token_id,
+			s.revoked_at
 		FROM user_session AS s
 		LEFT JOIN user_account AS u ON u.id = s.user_id
 		WHERE (s.user_id = ANY($1) OR COALESCE($1, '{}') = '{}')
@@ -334,7 +1675,10 @@ func (m Model) GetAllSession(
 	)
 
 	for rows.Next() {
-		var s Session
+		var (
+			s         Session
+			revokedAt pq.NullTime
+		)
 
 		err := rows.Scan(
 			&total,
@@ -345,11 +1689,15 @@ func (m Model) GetAllSession(
 			&s.IP,
 			&s.Agent,
 			&s.UserID,
+			&s.RefreshTokenID,
+			&revokedAt,
 		)
 		if err != nil {
 			return nil, 0, err
 		}
 
+		s.RevokedAt = revokedAt.Time
+
 		ss = append(ss, &s)
 	}
 
@@ -360,6 +1708,95 @@ func (m Model) GetAllSession(
 	return ss, total, nil
 }
 
+// GetAllSessionKeyset returns a page of sessions after or before a cursor, see utils.KeysetParams.
+func (m Model) GetAllSessionKeyset(
+	params utils.KeysetParams,
+	include GetAllSessionIncludeFilters,
+) ([]*Session, utils.PageInfo, error) {
+	column := "s." + params.SortColumn()
+	where, whereArgs := params.Where(column, "s.id", 4)
+
+	query := fmt.Sprintf(`
+		SELECT
+			s.id,
+			s.created_at,
+			s.updated_at,
+			s.deactivated_at,
+			s.ip,
+			s.agent,
+			s.user_id,
+			s.refresh_token_id,
+			s.revoked_at
+		FROM user_session AS s
+		WHERE (s.user_id = ANY($1) OR COALESCE($1, '{}') = '{}')
+			AND (
+				('EXPIRED' = ANY($2) AND s.deactivated_at < NOW())
+				OR ('ACTIVE' = ANY($2) AND s.deactivated_at > NOW())
+				OR COALESCE($2, '{}') = '{}'
+			)
+			AND %s
+		ORDER BY %s
+		LIMIT $3`, where, params.OrderBy(column, "s.id"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := append([]interface{}{
+		pq.Array(include.UserIds),
+		pq.Array(include.States),
+		params.Limit(),
+	}, whereArgs...)
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	defer rows.Close()
+
+	var ss []*Session
+
+	for rows.Next() {
+		var (
+			s         Session
+			revokedAt pq.NullTime
+		)
+
+		err := rows.Scan(
+			&s.ID,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+			&s.DeactivatedAt,
+			&s.IP,
+			&s.Agent,
+			&s.UserID,
+			&s.RefreshTokenID,
+			&revokedAt,
+		)
+		if err != nil {
+			return nil, utils.PageInfo{}, err
+		}
+
+		s.RevokedAt = revokedAt.Time
+
+		ss = append(ss, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	info, keep := params.PageInfo(len(ss))
+	ss = ss[:keep]
+	// backward pages are fetched in reverse order
+	if params.Backward() {
+		for i, j := 0, len(ss)-1; i < j; i, j = i+1, j-1 {
+			ss[i], ss[j] = ss[j], ss[i]
+		}
+	}
+
+	return ss, info, nil
+}
+
 type SessionActivityState string
 
 const (
@@ -371,3 +1808,89 @@ type GetAllSessionIncludeFilters struct {
 	States  []SessionActivityState
 	UserIds []string
 }
+
+// GetAllUsers returns a page of the users matching the filters and their total, the
+// password hash and the 2FA secret are left out.
+func (m Model) GetAllUsers(
+	params utils.QueryParams,
+	include GetAllUsersIncludeFilters,
+) ([]*User, int, error) {
+	query := fmt.Sprintf(`
+		SELECT %s,
+			count(*) OVER()
+		FROM user_account AS u
+		WHERE (
+				$1 = ''
+				OR u.email ILIKE '%%' || $6 || '%%' ESCAPE '\'
+				OR u.profil_name ILIKE '%%' || $6 || '%%' ESCAPE '\'
+				OR u.short_id = $1
+			)
+			AND (u.roles && $2::TEXT[] OR COALESCE($2, '{}') = '{}')
+			AND (
+				('DEACTIVATED' = ANY($3) AND u.deactivated_at IS NOT NULL)
+				OR ('ACTIVE' = ANY($3) AND u.deactivated_at IS NULL)
+				OR COALESCE($3, '{}') = '{}'
+			)
+		ORDER BY %s %s
+		LIMIT $4 OFFSET $5`, userColumns, params.SortColumn(), params.SortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(
+		ctx,
+		query,
+		include.Search,
+		pq.Array(include.Roles),
+		pq.Array(include.States),
+		params.Limit,
+		params.Offset,
+		escapeLike(include.Search),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var (
+		us    []*User
+		total int
+	)
+
+	for rows.Next() {
+		u, err := scanUser(rows, false, &total)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		us = append(us, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return us, total, nil
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns, with the escape character '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike returns s matching itself in a LIKE pattern using ESCAPE '\'.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+type UserActivityState string
+
+const (
+	UserActive      UserActivityState = "ACTIVE"
+	UserDeactivated UserActivityState = "DEACTIVATED"
+)
+
+type GetAllUsersIncludeFilters struct {
+	// Search matches a part of the email or the profil name, or the short id.
+	Search string
+	Roles  Roles
+	States []UserActivityState
+}
@@ -1,6 +1,7 @@
 package user_test
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/brice-74/golang-base-api/internal/testutils/factory"
 	"github.com/brice-74/golang-base-api/internal/utils"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/twinj/uuid"
 )
 
@@ -75,33 +77,163 @@ func TestInsertRegisteredUserAccount(t *testing.T) {
 	})
 }
 
-func TestInsertOrUpdateUserSession(t *testing.T) {
+func TestInsertUserSession(t *testing.T) {
 	var (
 		db  = testutils.PrepareDB(t)
 		m   = user.Model{DB: db}
 		fac = factory.New(t, db)
 	)
 
-	u := fac.CreateUserAccount(nil)
+	s := fac.CreateUserSession(nil)
 
-	s := &user.Session{
-		ID:            uuid.NewV4().String(),
-		DeactivatedAt: time.Now(),
-		IP:            "0.0.0.0",
-		Agent:         "agent",
-		UserID:        u.ID,
-	}
+	t.Run("should insert session", func(t *testing.T) {
+		ns := &user.Session{
+			ID:             uuid.NewV4().String(),
+			DeactivatedAt:  time.Now(),
+			IP:             "0.0.0.0",
+			Agent:          "agent",
+			UserID:         s.UserID,
+			RefreshTokenID: uuid.NewV4().String(),
+		}
+
+		if err := m.InsertUserSession(ns); err != nil {
+			t.Fatalf("got an error during insert user session execution: %s", err)
+		}
+
+		if ns.CreatedAt.IsZero() {
+			t.Error("got CreatedAt zero value instead of a real date")
+		}
+	})
+
+	t.Run("should refuse an existing session id", func(t *testing.T) {
+		if err := m.InsertUserSession(s); err == nil {
+			t.Fatal("got nil error, expect available error")
+		}
+	})
+}
+
+func TestUpdateUserSession(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		m   = user.Model{DB: db}
+		fac = factory.New(t, db)
+	)
+
+	s := fac.CreateUserSession(nil)
+
+	t.Run("should update session", func(t *testing.T) {
+		s.DeactivatedAt = time.Now().Add(time.Hour)
+		s.RefreshTokenID = uuid.NewV4().String()
+
+		if err := m.UpdateUserSession(s); err != nil {
+			t.Fatalf("got an error during update user session execution: %s", err)
+		}
+
+		got, err := m.GetSessionByID(s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.RefreshTokenID != s.RefreshTokenID {
+			t.Fatalf("got refresh token id: %s, expect: %s", got.RefreshTokenID, s.RefreshTokenID)
+		}
+	})
+
+	t.Run("should return not found session of another user", func(t *testing.T) {
+		other := *s
+		other.UserID = fac.CreateUserAccount(nil).ID
+
+		err := m.UpdateUserSession(&other)
+		if err == nil {
+			t.Fatal("got nil error, expect available error")
+		}
+
+		if err.Error() != user.ErrNotFoundSession.Error() {
+			t.Fatalf("got: %s, expect: %s", err.Error(), user.ErrNotFoundSession.Error())
+		}
+	})
+
+	t.Run("should not reopen revoked session", func(t *testing.T) {
+		if err := m.RevokeUserSession(s.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := m.UpdateUserSession(s); !errors.Is(err, user.ErrSessionRevoked) {
+			t.Fatalf("got: %v, expect: %v", err, user.ErrSessionRevoked)
+		}
+
+		got, err := m.GetSessionByID(s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.CheckActive() != user.ErrSessionRevoked {
+			t.Fatal("expect session still revoked")
+		}
+	})
+}
+
+func TestRotateSessionRefreshToken(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		m   = user.Model{DB: db}
+		fac = factory.New(t, db)
+	)
+
+	s := fac.CreateUserSession(nil)
+	previousID := s.RefreshTokenID
+
+	t.Run("should rotate refresh token id", func(t *testing.T) {
+		s.RefreshTokenID = uuid.NewV4().String()
+
+		if err := m.RotateSessionRefreshToken(s, previousID); err != nil {
+			t.Fatalf("got an error during refresh token rotation: %s", err)
+		}
+
+		got, err := m.GetSessionByID(s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.RefreshTokenID != s.RefreshTokenID {
+			t.Fatalf("got refresh token id: %s, expect: %s", got.RefreshTokenID, s.RefreshTokenID)
+		}
+	})
+
+	t.Run("should return refresh token reused error", func(t *testing.T) {
+		err := m.RotateSessionRefreshToken(s, previousID)
+		if err == nil {
+			t.Fatal("got nil error, expect available error")
+		}
+
+		if err.Error() != user.ErrRefreshTokenReused.Error() {
+			t.Fatalf("got: %s, expect: %s", err.Error(), user.ErrRefreshTokenReused.Error())
+		}
+	})
+}
+
+func TestRevokeUserSession(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		m   = user.Model{DB: db}
+		fac = factory.New(t, db)
+	)
 
-	if err := m.InsertOrUpdateUserSession(s); err != nil {
-		t.Fatalf("got an error during insert user session execution: %s", err)
+	s := fac.CreateUserSession(&user.Session{
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+
+	if err := m.RevokeUserSession(s.ID); err != nil {
+		t.Fatalf("got an error during session revocation: %s", err)
 	}
 
-	if s.CreatedAt.IsZero() {
-		t.Error("got CreatedAt zero value instead of a real date")
+	got, err := m.GetSessionByID(s.ID)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if s.UpdatedAt.IsZero() {
-		t.Error("got UpdatedAt zero value instead of a real date")
+	if err := got.CheckActive(); err != user.ErrSessionRevoked {
+		t.Fatalf("got: %v, expect: %v", err, user.ErrSessionRevoked)
 	}
 }
 
@@ -138,6 +270,29 @@ func TestGetById(t *testing.T) {
 	})
 }
 
+func TestGetByIds(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		m   = user.Model{DB: db}
+		fac = factory.New(t, db)
+	)
+
+	u1 := fac.CreateUserAccount(nil)
+	u2 := fac.CreateUserAccount(nil)
+	fac.CreateUserAccount(nil)
+
+	got, err := m.GetByIds([]string{u1.ID, u2.ID, uuid.NewV4().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byID := cmpopts.SortSlices(func(a, b *user.User) bool { return a.ID < b.ID })
+
+	if diff := cmp.Diff([]*user.User{u1, u2}, got, byID); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
 func TestGetByEmail(t *testing.T) {
 	var (
 		db  = testutils.PrepareDB(t)
@@ -190,6 +345,26 @@ func TestGetSessionByID(t *testing.T) {
 	}
 }
 
+func TestGetSessionsByIds(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		m   = user.Model{DB: db}
+		fac = factory.New(t, db)
+	)
+
+	s := fac.CreateUserSession(nil)
+	fac.CreateUserSession(nil)
+
+	got, err := m.GetSessionsByIds([]string{s.ID, uuid.NewV4().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]*user.Session{s}, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
 func TestGetUserAndSession(t *testing.T) {
 	var (
 		db  = testutils.PrepareDB(t)
@@ -295,3 +470,354 @@ func TestGetAllSession(t *testing.T) {
 	}
 
 }
+
+func TestResetUserPassword(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		m   = user.Model{DB: db}
+		fac = factory.New(t, db)
+	)
+
+	s := fac.CreateUserSession(&user.Session{
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+	_, accessToken := fac.CreateAccessToken(&user.AccessToken{UserID: s.UserID})
+
+	const (
+		token        = "token"
+		expiredToken = "expired token"
+	)
+
+	if err := m.InsertPasswordReset(s.UserID, user.HashToken(token), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.InsertPasswordReset(s.UserID, user.HashToken(expiredToken), time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should refuse expired token", func(t *testing.T) {
+		_, err := m.ResetUserPassword(user.HashToken(expiredToken), "hash")
+		if err != user.ErrInvalidResetToken {
+			t.Fatalf("got: %v, expect: %s", err, user.ErrInvalidResetToken)
+		}
+	})
+
+	t.Run("should reset password and revoke sessions", func(t *testing.T) {
+		userID, err := m.ResetUserPassword(user.HashToken(token), "hash")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if userID != s.UserID {
+			t.Fatalf("got user id: %s, expect: %s", userID, s.UserID)
+		}
+
+		u, err := m.GetById(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if u.Password != "hash" {
+			t.Fatalf("got password: %s, expect: hash", u.Password)
+		}
+
+		got, err := m.GetSessionByID(s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.DeactivatedAt.After(time.Now()) {
+			t.Fatal("session should be revoked")
+		}
+
+		if _, _, err := m.GetUserByAccessToken(user.HashToken(accessToken)); err != user.ErrNotFoundAccessToken {
+			t.Fatalf("got: %v, expect access token revoked", err)
+		}
+	})
+
+	t.Run("should refuse used token", func(t *testing.T) {
+		_, err := m.ResetUserPassword(user.HashToken(token), "hash")
+		if err != user.ErrInvalidResetToken {
+			t.Fatalf("got: %v, expect: %s", err, user.ErrInvalidResetToken)
+		}
+	})
+}
+
+func TestVerifyUserEmail(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		m   = user.Model{DB: db}
+		fac = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+
+	t.Run("should return not found user of another email", func(t *testing.T) {
+		if err := m.VerifyUserEmail(u.ID, "other@test.com"); err != user.ErrNotFoundUser {
+			t.Fatalf("got: %v, expect: %s", err, user.ErrNotFoundUser)
+		}
+	})
+
+	t.Run("should verify user", func(t *testing.T) {
+		if err := m.VerifyUserEmail(u.ID, u.Email); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := m.GetById(u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !got.Verified() {
+			t.Fatal("expect verified user")
+		}
+	})
+}
+
+func TestUpdateUserEmail(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		m   = user.Model{DB: db}
+		fac = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+	other := fac.CreateUserAccount(nil)
+
+	t.Run("should return not found user of another email", func(t *testing.T) {
+		if err := m.UpdateUserEmail(u.ID, "other@test.com", "new@test.com"); err != user.ErrNotFoundUser {
+			t.Fatalf("got: %v, expect: %s", err, user.ErrNotFoundUser)
+		}
+	})
+
+	t.Run("should return duplicate email", func(t *testing.T) {
+		if err := m.UpdateUserEmail(u.ID, u.Email, other.Email); err != user.ErrDuplicateEmail {
+			t.Fatalf("got: %v, expect: %s", err, user.ErrDuplicateEmail)
+		}
+	})
+
+	t.Run("should update email", func(t *testing.T) {
+		if err := m.UpdateUserEmail(u.ID, u.Email, "new@test.com"); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := m.GetById(u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Email != "new@test.com" || !got.Verified() {
+			t.Fatalf("got email: %s verified: %t, expect new@test.com verified", got.Email, got.Verified())
+		}
+	})
+}
+
+func TestPurgeDeletedUserAccounts(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		m   = user.Model{DB: db}
+		fac = factory.New(t, db)
+	)
+
+	deleted := fac.CreateUserAccount(nil)
+	s := fac.CreateUserSession(&user.Session{
+		UserID:        deleted.ID,
+		DeactivatedAt: time.Now().Add(time.Hour),
+	})
+	kept := fac.CreateUserAccount(nil)
+
+	if err := m.ScheduleUserAccountDeletion(deleted.ID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.ScheduleUserAccountDeletion(kept.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := m.PurgeDeletedUserAccounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 1 {
+		t.Fatalf("got %d deleted accounts, expect 1", n)
+	}
+
+	if _, err = m.GetById(deleted.ID); err != user.ErrNotFoundUser {
+		t.Fatalf("got: %v, expect: %s", err, user.ErrNotFoundUser)
+	}
+
+	if _, err = m.GetSessionByID(s.ID); err != user.ErrNotFoundSession {
+		t.Fatalf("got: %v, expect: %s", err, user.ErrNotFoundSession)
+	}
+
+	if _, err = m.GetById(kept.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetUserByAccessToken(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		m   = user.Model{DB: db}
+		fac = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+	token, value := fac.CreateAccessToken(&user.AccessToken{UserID: u.ID})
+	_, expiredValue := fac.CreateAccessToken(&user.AccessToken{
+		UserID:    u.ID,
+		ExpiresAt: time.Now().Add(time.Millisecond),
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	t.Run("should return not found expired token", func(t *testing.T) {
+		if _, _, err := m.GetUserByAccessToken(user.HashToken(expiredValue)); err != user.ErrNotFoundAccessToken {
+			t.Fatalf("got: %v, expect: %s", err, user.ErrNotFoundAccessToken)
+		}
+	})
+
+	t.Run("should return user and record usage", func(t *testing.T) {
+		gotUser, gotToken, err := m.GetUserByAccessToken(user.HashToken(value))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if gotUser.ID != u.ID || gotToken.ID != token.ID {
+			t.Fatalf("got user: %s and token: %s, expect: %s and %s", gotUser.ID, gotToken.ID, u.ID, token.ID)
+		}
+
+		if gotToken.LastUsedAt.IsZero() {
+			t.Fatal("expect last usage recorded")
+		}
+	})
+}
+
+func TestGetLoginFailures(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		m   = user.Model{DB: db}
+		fac = factory.New(t, db)
+	)
+
+	u := fac.CreateUserAccount(nil)
+
+	for _, failure := range []struct{ userID, ip string }{
+		{u.ID, "1.1.1.1"},
+		{u.ID, "2.2.2.2"},
+		{"", "1.1.1.1"},
+	} {
+		if err := m.InsertLoginFailure(failure.userID, failure.ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("should count failures of account and ip", func(t *testing.T) {
+		got, err := m.GetLoginFailures(u.ID, "1.1.1.1", time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Account != 2 || got.IP != 2 {
+			t.Fatalf("got %d account and %d ip failures, expect 2 and 2", got.Account, got.IP)
+		}
+	})
+
+	t.Run("should forget failures of unlocked account", func(t *testing.T) {
+		if err := m.UnlockUserAccount(u.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := m.GetLoginFailures(u.ID, "1.1.1.1", time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Account != 0 || got.IP != 1 {
+			t.Fatalf("got %d account and %d ip failures, expect 0 and 1", got.Account, got.IP)
+		}
+	})
+}
+
+func TestGetAllUsers(t *testing.T) {
+	var (
+		db  = testutils.PrepareDB(t)
+		m   = user.Model{DB: db}
+		fac = factory.New(t, db)
+	)
+
+	admin := fac.CreateUserAccount(&user.User{Roles: user.Roles{user.RoleAdmin}})
+	u := fac.CreateUserAccount(nil)
+	if err := m.DeactivateUserAccount(u.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	params := utils.QueryParams{
+		Limit:          20,
+		Sort:           "createdAt",
+		SortableFields: []string{"createdAt"},
+	}
+
+	tests := []struct {
+		title   string
+		include user.GetAllUsersIncludeFilters
+		expect  []string
+	}{
+		{
+			title:  "should return all users",
+			expect: []string{admin.ID, u.ID},
+		},
+		{
+			title:   "should search by email",
+			include: user.GetAllUsersIncludeFilters{Search: u.Email},
+			expect:  []string{u.ID},
+		},
+		{
+			title:   "should match wildcards literally",
+			include: user.GetAllUsersIncludeFilters{Search: "%"},
+			expect:  []string{},
+		},
+		{
+			title:   "should filter by role",
+			include: user.GetAllUsersIncludeFilters{Roles: user.Roles{user.RoleAdmin}},
+			expect:  []string{admin.ID},
+		},
+		{
+			title:   "should filter by state",
+			include: user.GetAllUsersIncludeFilters{States: []user.UserActivityState{user.UserDeactivated}},
+			expect:  []string{u.ID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			us, total, err := m.GetAllUsers(params, tt.include)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if total != len(tt.expect) || len(us) != len(tt.expect) {
+				t.Fatalf("got %d users, expect %d", total, len(tt.expect))
+			}
+
+			// users created in the same second have no defined order
+			got := map[string]bool{}
+			for _, u := range us {
+				got[u.ID] = true
+			}
+
+			for _, id := range tt.expect {
+				if !got[id] {
+					t.Fatalf("expect user: %s", id)
+				}
+			}
+
+			for _, u := range us {
+				if u.Password != "" || u.MfaSecret != "" {
+					t.Fatalf("got secrets of user: %s", u.ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package user
+
+// Permission is named "<resource>:<action>:<own|any>", own permissions only apply
+// to the resources of the user.
+type Permission string
+
+const (
+	PermissionAccountReadOwn  Permission = "account:read:own"
+	PermissionAccountWriteOwn Permission = "account:write:own"
+	PermissionSessionReadOwn  Permission = "session:read:own"
+	PermissionSessionWriteOwn Permission = "session:write:own"
+	PermissionUserReadAny     Permission = "user:read:any"
+	PermissionUserWriteAny    Permission = "user:write:any"
+	PermissionSessionReadAny  Permission = "session:read:any"
+	PermissionSessionWriteAny Permission = "session:write:any"
+)
+
+var ownPermissions = []Permission{
+	PermissionAccountReadOwn,
+	PermissionAccountWriteOwn,
+	PermissionSessionReadOwn,
+	PermissionSessionWriteOwn,
+}
+
+// RolePermissions is the registry of the permissions granted by each role.
+var RolePermissions = map[Role][]Permission{
+	RoleAnonymous: {},
+	RoleUser:      ownPermissions,
+	RoleAdmin: append([]Permission{
+		PermissionUserReadAny,
+		PermissionUserWriteAny,
+		PermissionSessionReadAny,
+		PermissionSessionWriteAny,
+	}, ownPermissions...),
+}
+
+// Known checks if the permission is granted by at least one role.
+func (p Permission) Known() bool {
+	for _, ps := range RolePermissions {
+		for _, granted := range ps {
+			if granted == p {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -5,11 +5,15 @@ import "fmt"
 const (
 	RoleAnonymous Role = "ROLE_ANONYMOUS"
 	RoleUser      Role = "ROLE_USER"
+	RoleAdmin     Role = "ROLE_ADMIN"
 )
 
 type Role string
 type Roles []Role
 
+// AssignableRoles can be given to registered users.
+var AssignableRoles = Roles{RoleUser, RoleAdmin}
+
 // Scan allows custom type to be Scanned by databases, by implementing the Scanner interface.
 func (r *Role) Scan(src interface{}) error {
 	switch v := src.(type) {
@@ -21,3 +25,27 @@ func (r *Role) Scan(src interface{}) error {
 
 	return nil
 }
+
+// Has checks if the role is part of the list.
+func (rs Roles) Has(role Role) bool {
+	for _, r := range rs {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPermission checks if one of the roles grants the permission.
+func (rs Roles) HasPermission(p Permission) bool {
+	for _, r := range rs {
+		for _, granted := range RolePermissions[r] {
+			if granted == p {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -1,15 +1,52 @@
 package user
 
 import (
+	"strings"
 	"time"
+
+	"github.com/brice-74/golang-base-api/internal/utils"
 )
 
+// SessionKeysetSortableFields are the fields sessions can be sorted by with keyset pagination.
+var SessionKeysetSortableFields = []string{
+	"deactivatedAt",
+	"-deactivatedAt",
+	"createdAt",
+	"-createdAt",
+}
+
 type Session struct {
-	ID            string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	DeactivatedAt time.Time
-	IP            string
-	Agent         string
-	UserID        string
+	ID             string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeactivatedAt  time.Time
+	IP             string
+	Agent          string
+	UserID         string
+	RefreshTokenID string
+	RevokedAt      time.Time
+}
+
+// CheckActive returns ErrSessionRevoked if the session has been closed before its end,
+// ErrSessionExpired if it has reached its end, nil otherwise.
+func (s Session) CheckActive() error {
+	switch {
+	case !s.RevokedAt.IsZero():
+		return ErrSessionRevoked
+	case !s.DeactivatedAt.After(time.Now()):
+		return ErrSessionExpired
+	}
+
+	return nil
+}
+
+// Cursor returns the position of the session in a list sorted by the sort field, see
+// utils.KeysetParams.
+func (s Session) Cursor(sort string) string {
+	value := s.DeactivatedAt
+	if strings.TrimPrefix(sort, "-") == "createdAt" {
+		value = s.CreatedAt
+	}
+
+	return utils.EncodeCursor(sort, value, s.ID)
 }
@@ -0,0 +1,42 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brice-74/golang-base-api/internal/domains/user"
+)
+
+func TestSessionCheckActive(t *testing.T) {
+	tests := []struct {
+		title   string
+		session user.Session
+		expect  error
+	}{
+		{
+			title:   "Should be active",
+			session: user.Session{DeactivatedAt: time.Now().Add(time.Hour)},
+		},
+		{
+			title:   "Should be expired",
+			session: user.Session{DeactivatedAt: time.Now().Add(-time.Hour)},
+			expect:  user.ErrSessionExpired,
+		},
+		{
+			title: "Should be revoked",
+			session: user.Session{
+				DeactivatedAt: time.Now().Add(-time.Hour),
+				RevokedAt:     time.Now().Add(-time.Hour),
+			},
+			expect: user.ErrSessionRevoked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if err := tt.session.CheckActive(); err != tt.expect {
+				t.Fatalf("got: %v, expect: %v", err, tt.expect)
+			}
+		})
+	}
+}
@@ -1,29 +1,118 @@
 package user
 
 import (
+	"reflect"
 	"time"
 )
 
 var AnonymousUser = &User{Roles: Roles{RoleAnonymous}}
 
+// User is a user account, fields tagged `sensitive:"true"` are secrets which must
+// never leave the API, see Redacted.
 type User struct {
 	ID            string
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	DeactivatedAt time.Time
 	Email         string
-	Password      string
-	Roles         Roles
-	ProfilName    string
-	ShortId       string
+	// Password is the encoded hash of the password, see pkg/password.
+	Password   string `sensitive:"true"`
+	Roles      Roles
+	ProfilName string
+	ShortId    string
+	VerifiedAt time.Time
+	// MfaSecret is the TOTP secret, set during enrollment and kept once confirmed.
+	MfaSecret    string `sensitive:"true"`
+	MfaEnabledAt time.Time
+	// FirstName, LastName and BirthDate are optional, empty when unset.
+	FirstName string
+	LastName  string
+	BirthDate time.Time
+	// ProfileVisibility defines who can see the public profile of the user.
+	ProfileVisibility ProfileVisibility
 }
 
+type ProfileVisibility string
+
+const (
+	// ProfileVisibilityPublic lets anyone see the public profile.
+	ProfileVisibilityPublic ProfileVisibility = "PUBLIC"
+	// ProfileVisibilityPrivate hides the public profile to everyone but the user.
+	ProfileVisibilityPrivate ProfileVisibility = "PRIVATE"
+)
+
+// Redacted returns a copy of the user without its sensitive fields, to give to code
+// exposing the user to clients.
+func (u User) Redacted() User {
+	v := reflect.ValueOf(&u).Elem()
+	for _, i := range sensitiveFields {
+		v.Field(i).Set(reflect.Zero(v.Field(i).Type()))
+	}
+
+	return u
+}
+
+// SensitiveFields returns the names of the sensitive fields of User.
+func SensitiveFields() []string {
+	t := reflect.TypeOf(User{})
+
+	names := make([]string, len(sensitiveFields))
+	for i, f := range sensitiveFields {
+		names[i] = t.Field(f).Name
+	}
+
+	return names
+}
+
+// sensitiveFields are the indexes of the User fields tagged as sensitive.
+var sensitiveFields = func() []int {
+	var indexes []int
+
+	t := reflect.TypeOf(User{})
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("sensitive") == "true" {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
+}()
+
 // IsAnonymous checks if a user instance is anonymous.
 func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+// Deactivated checks if the account has been deactivated, it can no longer authenticate.
+func (u *User) Deactivated() bool {
+	return !u.DeactivatedAt.IsZero()
+}
+
+// Verified checks if the user has proven to own his email address.
+func (u *User) Verified() bool {
+	return !u.VerifiedAt.IsZero()
+}
+
+// ProfileVisibleTo checks if the public profile of the user can be seen by a client.
+// Profiles of deactivated accounts are hidden.
+func (u *User) ProfileVisibleTo(client *User) bool {
+	switch {
+	case client != nil && client.ID == u.ID:
+		return true
+	case u.Deactivated():
+		return false
+	default:
+		return u.ProfileVisibility == ProfileVisibilityPublic
+	}
+}
+
+// MfaEnabled checks if the user must give a TOTP code to authenticate.
+func (u *User) MfaEnabled() bool {
+	return !u.MfaEnabledAt.IsZero()
+}
+
 type Tokens struct {
-	Access  string
-	Refresh string
+	Access    string
+	Refresh   string
+	SessionID string
 }
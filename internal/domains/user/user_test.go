@@ -2,6 +2,7 @@ package user_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/brice-74/golang-base-api/internal/domains/user"
 )
@@ -13,3 +14,77 @@ func TestIsAnonymous(t *testing.T) {
 		t.Fatalf("User should be anonymous: %+v", u.Roles)
 	}
 }
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		title      string
+		roles      user.Roles
+		permission user.Permission
+		expect     bool
+	}{
+		{"Should grant own permission to user", user.Roles{user.RoleUser}, user.PermissionAccountReadOwn, true},
+		{"Should not grant any permission to user", user.Roles{user.RoleUser}, user.PermissionUserReadAny, false},
+		{"Should grant any permission to admin", user.Roles{user.RoleAdmin}, user.PermissionUserReadAny, true},
+		{"Should grant own permission to admin", user.Roles{user.RoleAdmin}, user.PermissionSessionWriteOwn, true},
+		{"Should not grant permission to anonymous", user.Roles{user.RoleAnonymous}, user.PermissionAccountReadOwn, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := tt.roles.HasPermission(tt.permission); got != tt.expect {
+				t.Fatalf("got: %t, expect: %t", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	u := user.User{
+		ID:        "id",
+		Email:     "test@test.com",
+		Password:  "hash",
+		MfaSecret: "secret",
+	}
+
+	got := u.Redacted()
+
+	if got.Password != "" || got.MfaSecret != "" {
+		t.Fatalf("got sensitive fields: %q %q", got.Password, got.MfaSecret)
+	}
+
+	if got.ID != u.ID || got.Email != u.Email {
+		t.Fatalf("got user: %+v, expect other fields kept", got)
+	}
+
+	if u.Password != "hash" {
+		t.Fatal("original user should be kept")
+	}
+}
+
+func TestProfileVisibleTo(t *testing.T) {
+	var (
+		public      = &user.User{ID: "public", ProfileVisibility: user.ProfileVisibilityPublic}
+		private     = &user.User{ID: "private", ProfileVisibility: user.ProfileVisibilityPrivate}
+		deactivated = &user.User{ID: "deactivated", ProfileVisibility: user.ProfileVisibilityPublic, DeactivatedAt: time.Now()}
+	)
+
+	tests := []struct {
+		title   string
+		profile *user.User
+		client  *user.User
+		expect  bool
+	}{
+		{"Should show public profile to anonymous", public, user.AnonymousUser, true},
+		{"Should hide private profile to others", private, public, false},
+		{"Should show private profile to owner", private, private, true},
+		{"Should hide deactivated profile", deactivated, public, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := tt.profile.ProfileVisibleTo(tt.client); got != tt.expect {
+				t.Fatalf("got: %t, expect: %t", got, tt.expect)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package user
 
 import (
+	"fmt"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/brice-74/golang-base-api/pkg/validator"
 )
@@ -23,9 +26,76 @@ func (user User) ValidatePasswordEntry(v *validator.Validator) {
 	v.Check(validator.SpecialCharRX(1, 255).MatchString(pass), "password", "must have minimum of 1 special character")
 }
 
+// ValidatePasswordNotBreachedEntry refuses a password part of the list, an error is
+// returned when the list can't be read.
+func (user User) ValidatePasswordNotBreachedEntry(v *validator.Validator, list validator.PasswordList) error {
+	found, err := list.Contains(user.Password)
+	if err != nil {
+		return err
+	}
+	v.Check(!found, "password", "must not be a common or breached password")
+	return nil
+}
+
 func (user User) ValidateEmailEntry(v *validator.Validator) {
 	email := strings.TrimSpace(user.Email)
 	v.Check(email != "", "email", "must be provided")
 	v.Check(len(email) <= 255, "email", "must have maximum of 255 characters")
 	v.Check(validator.EmailRX.MatchString(email), "email", "must be a valid address")
 }
+
+// Bounds of the age of a user, computed from his birth date.
+const (
+	MinAge = 13
+	MaxAge = 120
+)
+
+func (user User) ValidateFirstNameEntry(v *validator.Validator) {
+	validateNameEntry(v, "first name", user.FirstName)
+}
+
+func (user User) ValidateLastNameEntry(v *validator.Validator) {
+	validateNameEntry(v, "last name", user.LastName)
+}
+
+// validateNameEntry checks an optional name, an empty name clears it.
+func validateNameEntry(v *validator.Validator, key string, name string) {
+	if name == "" {
+		return
+	}
+	v.Check(utf8.RuneCountInString(name) <= 64, key, "must have maximum of 64 characters")
+	v.Check(validator.NameRX.MatchString(name), key, "must only contain letters, spaces, hyphens and apostrophes")
+}
+
+func (user User) ValidateBirthDateEntry(v *validator.Validator) {
+	if user.BirthDate.IsZero() {
+		return
+	}
+	now := time.Now()
+	v.Check(!user.BirthDate.After(now.AddDate(-MinAge, 0, 0)), "birth date", fmt.Sprintf("must be at least %d years old", MinAge))
+	v.Check(user.BirthDate.After(now.AddDate(-MaxAge, 0, 0)), "birth date", fmt.Sprintf("must be at most %d years old", MaxAge))
+}
+
+func (user User) ValidateRolesEntry(v *validator.Validator) {
+	v.Check(len(user.Roles) > 0, "roles", "must be provided")
+	for _, r := range user.Roles {
+		v.Check(AssignableRoles.Has(r), "roles", fmt.Sprintf("can't assign role %s", r))
+	}
+}
+
+func (t AccessToken) ValidateNameEntry(v *validator.Validator) {
+	name := strings.TrimSpace(t.Name)
+	v.Check(name != "", "name", "must be provided")
+	v.Check(len(name) <= 64, "name", "must have maximum of 64 characters")
+}
+
+func (t AccessToken) ValidateScopesEntry(v *validator.Validator) {
+	v.Check(len(t.Scopes) > 0, "scopes", "must be provided")
+	for _, s := range t.Scopes {
+		v.Check(AllScopes.Has(s), "scopes", fmt.Sprintf("unknown scope %s", s))
+	}
+}
+
+func (t AccessToken) ValidateExpiresAtEntry(v *validator.Validator) {
+	v.Check(t.ExpiresAt.IsZero() || t.ExpiresAt.After(time.Now()), "expires at", "must be in the future")
+}
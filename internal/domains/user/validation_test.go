@@ -1,7 +1,9 @@
 package user_test
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/brice-74/golang-base-api/internal/domains/user"
 	"github.com/brice-74/golang-base-api/pkg/validator"
@@ -115,6 +117,40 @@ func TestValidatePasswordEntry(t *testing.T) {
 	})
 }
 
+func TestValidatePasswordNotBreachedEntry(t *testing.T) {
+	var (
+		v      = validator.New()
+		u      = user.User{}
+		errKey = "password"
+	)
+
+	t.Run("not common or breached", func(t *testing.T) {
+		u.Password = "Password1!"
+		if err := u.ValidatePasswordNotBreachedEntry(v, validator.CommonPasswords); err != nil {
+			t.Fatal(err)
+		}
+
+		got := v.Errors[errKey][0]
+		expect := "must not be a common or breached password"
+
+		if got != expect {
+			t.Fatalf("unexpected error got: %s, expected: %s", got, expect)
+		}
+	})
+
+	v.Errors = make(validator.Errors)
+	t.Run("should be ok", func(t *testing.T) {
+		u.Password = "Gr4nite-Lantern?"
+		if err := u.ValidatePasswordNotBreachedEntry(v, validator.CommonPasswords); err != nil {
+			t.Fatal(err)
+		}
+
+		if !v.Valid() {
+			t.Fatalf("validator should be valid, got: %v", v.Errors)
+		}
+	})
+}
+
 func TestValidateEmailEntry(t *testing.T) {
 	var (
 		v      = validator.New()
@@ -167,3 +203,93 @@ func TestValidateEmailEntry(t *testing.T) {
 		}
 	})
 }
+
+func TestValidateFirstNameEntry(t *testing.T) {
+	var (
+		v      = validator.New()
+		u      = user.User{}
+		errKey = "first name"
+	)
+
+	t.Run("max 64 characters", func(t *testing.T) {
+		u.FirstName = strings.Repeat("é", 65)
+		u.ValidateFirstNameEntry(v)
+
+		got := v.Errors[errKey][0]
+		expect := "must have maximum of 64 characters"
+
+		if got != expect {
+			t.Fatalf("unexpected error got: %s, expected: %s", got, expect)
+		}
+	})
+
+	v.Errors = make(validator.Errors)
+	t.Run("only letters, spaces, hyphens and apostrophes", func(t *testing.T) {
+		u.FirstName = "J0hn"
+		u.ValidateFirstNameEntry(v)
+
+		got := v.Errors[errKey][0]
+		expect := "must only contain letters, spaces, hyphens and apostrophes"
+
+		if got != expect {
+			t.Fatalf("unexpected error got: %s, expected: %s", got, expect)
+		}
+	})
+
+	v.Errors = make(validator.Errors)
+	t.Run("should be ok", func(t *testing.T) {
+		for _, name := range []string{"", "Jean-Éric", "Mary Ann", "O'Neil"} {
+			u.FirstName = name
+			u.ValidateFirstNameEntry(v)
+		}
+
+		if !v.Valid() {
+			t.Fatalf("validator should be valid, got: %v", v.Errors)
+		}
+	})
+}
+
+func TestValidateBirthDateEntry(t *testing.T) {
+	var (
+		v      = validator.New()
+		u      = user.User{}
+		errKey = "birth date"
+	)
+
+	t.Run("min age", func(t *testing.T) {
+		u.BirthDate = time.Now().AddDate(-user.MinAge+1, 0, 0)
+		u.ValidateBirthDateEntry(v)
+
+		got := v.Errors[errKey][0]
+		expect := "must be at least 13 years old"
+
+		if got != expect {
+			t.Fatalf("unexpected error got: %s, expected: %s", got, expect)
+		}
+	})
+
+	v.Errors = make(validator.Errors)
+	t.Run("max age", func(t *testing.T) {
+		u.BirthDate = time.Now().AddDate(-user.MaxAge-1, 0, 0)
+		u.ValidateBirthDateEntry(v)
+
+		got := v.Errors[errKey][0]
+		expect := "must be at most 120 years old"
+
+		if got != expect {
+			t.Fatalf("unexpected error got: %s, expected: %s", got, expect)
+		}
+	})
+
+	v.Errors = make(validator.Errors)
+	t.Run("should be ok", func(t *testing.T) {
+		for _, date := range []time.Time{{}, time.Date(1990, time.June, 15, 0, 0, 0, 0, time.UTC)} {
+			u.BirthDate = date
+			u.ValidateBirthDateEntry(v)
+		}
+
+		if !v.Valid() {
+			t.Fatalf("validator should be valid, got: %v", v.Errors)
+		}
+	})
+}
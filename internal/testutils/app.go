@@ -4,16 +4,52 @@ import (
 	"database/sql"
 
 	"github.com/brice-74/golang-base-api/internal/api/application"
+	"github.com/brice-74/golang-base-api/internal/testutils/mocks"
+	"github.com/brice-74/golang-base-api/pkg/mailer"
+	"github.com/brice-74/golang-base-api/pkg/password"
 )
 
+// PasswordHasher uses cheap argon2id parameters to keep tests fast.
+var PasswordHasher = password.Argon2id{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
 func NewApplication(db *sql.DB) *application.Application {
 	app := &application.Application{
-		Models: application.NewModels(db),
+		Models:    application.NewModels(db),
+		Logger:    mocks.NewLogger(),
+		Mailer:    mailer.NewMemory(),
+		Passwords: PasswordHasher,
 	}
 	app.Config.JWT.Access.Secret = "secret access"
 	app.Config.JWT.Access.Expiration = "3m"
 	app.Config.JWT.Refresh.Secret = "secret refresh"
 	app.Config.JWT.Refresh.Expiration = "10m"
+	app.Config.MFA.Issuer = "test"
+	app.Config.MFA.ChallengeExpiration = "1m"
+	app.Config.Mailer.From = "no-reply@test.com"
+	app.Config.PasswordReset.Expiration = "5m"
+	app.Config.PasswordReset.URL = "http://localhost:3000/reset-password"
+	app.Config.EmailVerification.Mode = application.EmailVerificationOptional
+	app.Config.EmailVerification.Expiration = "1h"
+	app.Config.EmailVerification.URL = "http://localhost:3000/verify-email"
+	app.Config.EmailChange.Expiration = "1h"
+	app.Config.EmailChange.URL = "http://localhost:3000/confirm-email-change"
+	app.Config.AccountDeletion.GracePeriod = "720h"
+	app.Config.AccountDeletion.PurgeInterval = "1h"
+	app.Config.GraphQL.MaxDepth = 10
+	app.Config.GraphQL.MaxComplexity = 5000
+	app.Config.GraphQL.PersistedQueryCacheSize = 10
+	app.Config.GraphQL.PersistedQueryLimit = 100
+	app.Config.Lockout.AccountThreshold = 3
+	app.Config.Lockout.IPThreshold = 10
+	app.Config.Lockout.Window = "1h"
+	app.Config.Lockout.BaseDelay = "1m"
+	app.Config.Lockout.MaxDelay = "1h"
 
 	return app
 }
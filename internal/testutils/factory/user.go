@@ -1,10 +1,13 @@
 package factory
 
 import (
+	"time"
+
 	"github.com/brice-74/golang-base-api/internal/domains/user"
+	"github.com/brice-74/golang-base-api/internal/testutils"
+	"github.com/brice-74/golang-base-api/pkg/totp"
 	"github.com/twinj/uuid"
 	"github.com/ventu-io/go-shortid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func (f Factory) CreateUserAccount(props *user.User) *user.User {
@@ -23,15 +26,15 @@ func (f Factory) CreateUserAccount(props *user.User) *user.User {
 		u.Password = f.faker.Internet().Password()
 	}
 
-	if hash, err := bcrypt.GenerateFromPassword([]byte(u.Password), 14); err != nil {
+	if hash, err := testutils.PasswordHasher.Hash(u.Password); err != nil {
 		f.T.Fatalf("error during hash password: %s", err)
 		return nil
 	} else {
-		u.Password = string(hash)
+		u.Password = hash
 	}
 
 	if len(u.Roles) == 0 {
-		u.Roles = user.Roles{user.RoleAnonymous}
+		u.Roles = user.Roles{user.RoleUser}
 	}
 
 	if u.ProfilName == "" {
@@ -49,6 +52,34 @@ func (f Factory) CreateUserAccount(props *user.User) *user.User {
 	return u
 }
 
+// EnableUserMfa enables 2FA for the user and returns the recovery codes.
+func (f Factory) EnableUserMfa(u *user.User) []string {
+	model := user.Model{DB: f.DB}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		f.T.Fatalf("error during totp secret generation: %s", err)
+	}
+
+	if err := model.SetUserMfaSecret(u.ID, secret); err != nil {
+		f.T.Fatalf("error during user mfa secret update: %s", err)
+	}
+
+	codes, hashes, err := user.GenerateRecoveryCodes()
+	if err != nil {
+		f.T.Fatalf("error during recovery codes generation: %s", err)
+	}
+
+	if err := model.EnableUserMfa(u.ID, hashes); err != nil {
+		f.T.Fatalf("error during user mfa activation: %s", err)
+	}
+
+	u.MfaSecret = secret
+	u.MfaEnabledAt = time.Now()
+
+	return codes
+}
+
 func (f Factory) CreateUserSession(props *user.Session) *user.Session {
 	model := user.Model{DB: f.DB}
 
@@ -73,9 +104,46 @@ func (f Factory) CreateUserSession(props *user.Session) *user.Session {
 		s.UserID = f.CreateUserAccount(nil).ID
 	}
 
-	if err := model.InsertOrUpdateUserSession(s); err != nil {
+	if s.RefreshTokenID == "" {
+		s.RefreshTokenID = uuid.NewV4().String()
+	}
+
+	if err := model.InsertUserSession(s); err != nil {
 		f.T.Fatalf("error during session factory insertion: %s", err)
 	}
 
 	return s
 }
+
+// CreateAccessToken creates a personal access token and returns it with its value.
+func (f Factory) CreateAccessToken(props *user.AccessToken) (*user.AccessToken, string) {
+	model := user.Model{DB: f.DB}
+
+	t := &user.AccessToken{}
+	if props != nil {
+		t = props
+	}
+
+	if t.Name == "" {
+		t.Name = f.faker.Lorem().Word()
+	}
+
+	if len(t.Scopes) == 0 {
+		t.Scopes = user.AllScopes
+	}
+
+	if t.UserID == "" {
+		t.UserID = f.CreateUserAccount(nil).ID
+	}
+
+	value, err := user.GenerateAccessToken()
+	if err != nil {
+		f.T.Fatalf("error during access token generation: %s", err)
+	}
+
+	if err := model.InsertAccessToken(t, user.HashToken(value)); err != nil {
+		f.T.Fatalf("error during access token factory insertion: %s", err)
+	}
+
+	return t, value
+}
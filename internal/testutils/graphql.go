@@ -5,23 +5,13 @@ import (
 
 	"github.com/brice-74/golang-base-api/internal/api/application"
 	"github.com/brice-74/golang-base-api/internal/api/handler"
-	"github.com/brice-74/golang-base-api/internal/api/resolvers"
-	"github.com/brice-74/golang-base-api/internal/api/schema"
 	"github.com/google/go-cmp/cmp"
 	"github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/errors"
 )
 
 func ParseTestSchema(app *application.Application) *graphql.Schema {
-	opts := []graphql.SchemaOpt{graphql.Logger(handler.Logger{App: app})}
-
-	return graphql.MustParseSchema(
-		schema.String(),
-		&resolvers.Root{
-			App: app,
-		},
-		opts...,
-	)
+	return handler.MustParseSchema(app)
 }
 
 func TestGqlError(t *testing.T, qerr *errors.QueryError, expect *ExpectResolverError) {
@@ -11,7 +11,7 @@ func NewLogger() *Logger {
 }
 
 func (l *Logger) PrintInfo(_ string, _ map[string]string) {
-	l.PrintFatalCalled = true
+	l.PrintInfoCalled = true
 }
 
 func (l *Logger) PrintError(_ error, _ map[string]string) {
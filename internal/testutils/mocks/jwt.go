@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
 	"time"
 
@@ -24,3 +26,17 @@ func CreateClaims(userID, sessionID string, time time.Time) jwt.MapClaims {
 		string(application.ExpireClaim):    time.Unix(),
 	}
 }
+
+func CreateRefreshClaims(userID, sessionID, refreshID string, time time.Time) jwt.MapClaims {
+	claims := CreateClaims(userID, sessionID, time)
+	claims[string(application.RefreshIdClaim)] = refreshID
+	return claims
+}
+
+func MarshalPKCS8PEM(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("error during private key marshalling: %s", err.Error())
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// EncodeGlobalID returns the opaque id, unique across all types, of the object of the
+// type identified by id. It's the base64 of "<type>:<id>".
+func EncodeGlobalID(typeName, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(typeName + ":" + id))
+}
+
+// DecodeGlobalID returns the type and the id of an id returned by EncodeGlobalID.
+func DecodeGlobalID(s string) (typeName string, id string, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return "", "", err
+	}
+
+	parts := strings.SplitN(string(data), ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("global id without type or id")
+	}
+
+	return parts[0], parts[1], nil
+}
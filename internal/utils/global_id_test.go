@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestGlobalID(t *testing.T) {
+	typeName, id, err := DecodeGlobalID(EncodeGlobalID("Session", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if typeName != "Session" || id != "6ba7b810-9dad-11d1-80b4-00c04fd430c8" {
+		t.Fatalf("got type: %s, id: %s", typeName, id)
+	}
+
+	invalid := []string{
+		"not base64!",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		base64.RawURLEncoding.EncodeToString([]byte("Session")),
+		base64.RawURLEncoding.EncodeToString([]byte("Session:")),
+	}
+	for _, s := range invalid {
+		if _, _, err := DecodeGlobalID(s); err == nil {
+			t.Errorf("%q: expect an invalid global id", s)
+		}
+	}
+}
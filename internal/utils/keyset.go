@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/brice-74/golang-base-api/pkg/validator"
+	"github.com/twinj/uuid"
+)
+
+// KeysetParams paginates a list after or before a cursor, following the Relay cursor
+// connections specification. Rows are ordered by the sort column then by their id, so
+// each row has a unique position which stays valid while rows are inserted or deleted.
+type KeysetParams struct {
+	First          int
+	After          string
+	Last           int
+	Before         string
+	Sort           string
+	SortableFields []string
+}
+
+// Cursor is the position of a row in a list: the sort of the list, the row value of the
+// sort column, which is a time, and the row id, which is a uuid.
+type Cursor struct {
+	Sort  string    `json:"s"`
+	Value time.Time `json:"v"`
+	ID    string    `json:"id"`
+}
+
+// EncodeCursor returns the opaque string given to clients for a cursor.
+func EncodeCursor(sort string, value time.Time, id string) string {
+	data, _ := json.Marshal(Cursor{Sort: sort, Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by EncodeCursor, the time and the uuid are
+// checked so that a forged cursor can't reach the database.
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+
+	if err = json.Unmarshal(data, &c); err != nil {
+		return c, err
+	}
+
+	if c.Value.IsZero() {
+		return c, fmt.Errorf("cursor without value")
+	}
+
+	if _, err = uuid.Parse(c.ID); err != nil {
+		return c, fmt.Errorf("cursor with invalid id: %w", err)
+	}
+
+	return c, nil
+}
+
+// Validate KeysetParams values. Should be called before using values.
+func (p KeysetParams) Validate(v *validator.Validator) {
+	v.Check(p.First == 0 || p.Last == 0, "first", "can't be used with last")
+	v.Check(p.First >= 0 && p.First <= 100, "first", "must be between 0 and 100")
+	v.Check(p.Last >= 0 && p.Last <= 100, "last", "must be between 0 and 100")
+	v.Check(p.After == "" || p.Before == "", "after", "can't be used with before")
+	v.Check(validator.In(p.Sort, p.SortableFields...), "sort", "invalid sort value")
+
+	if p.After != "" {
+		p.validateCursor(v, "after", p.After)
+	}
+	if p.Before != "" {
+		p.validateCursor(v, "before", p.Before)
+	}
+}
+
+// validateCursor checks that a cursor is valid and comes from a list of the same sort.
+func (p KeysetParams) validateCursor(v *validator.Validator, key, cursor string) {
+	c, err := DecodeCursor(cursor)
+	if err != nil {
+		v.AddError(key, "invalid cursor")
+		return
+	}
+
+	v.Check(c.Sort == p.Sort, key, "cursor of another sort")
+}
+
+// Backward checks if the page is taken before a cursor, or at the end of the list.
+// Rows are then fetched in reverse order, and must be reversed once fetched.
+func (p KeysetParams) Backward() bool {
+	return p.Last > 0 || p.Before != ""
+}
+
+// PageSize returns the number of rows of a page, 20 when not given.
+func (p KeysetParams) PageSize() int {
+	switch {
+	case p.First > 0:
+		return p.First
+	case p.Last > 0:
+		return p.Last
+	default:
+		return 20
+	}
+}
+
+// Limit returns the number of rows to fetch, the extra row tells if another page follows.
+func (p KeysetParams) Limit() int {
+	return p.PageSize() + 1
+}
+
+// SortColumn returns the column of the Sort value, see QueryParams.SortColumn.
+func (p KeysetParams) SortColumn() string {
+	return QueryParams{Sort: p.Sort, SortableFields: p.SortableFields}.SortColumn()
+}
+
+// fetchDirection returns the SQL direction rows are fetched in.
+func (p KeysetParams) fetchDirection() string {
+	direction := QueryParams{Sort: p.Sort}.SortDirection()
+	if !p.Backward() {
+		return direction
+	}
+
+	if direction == "ASC" {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// Where returns the condition keeping the rows past the cursor, comparing the columns
+// of the sort value and of the id, the cursor must have been validated. The condition uses the arguments $n and $n+1, it is
+// "TRUE" without cursor.
+func (p KeysetParams) Where(column, idColumn string, n int) (string, []interface{}) {
+	cursor := p.After
+	if p.Before != "" {
+		cursor = p.Before
+	}
+
+	if cursor == "" {
+		return "TRUE", nil
+	}
+
+	c, _ := DecodeCursor(cursor)
+
+	operator := ">"
+	if p.fetchDirection() == "DESC" {
+		operator = "<"
+	}
+
+	return fmt.Sprintf("(%s, %s) %s ($%d, $%d)", column, idColumn, operator, n, n+1), []interface{}{c.Value, c.ID}
+}
+
+// OrderBy returns the order rows are fetched in.
+func (p KeysetParams) OrderBy(column, idColumn string) string {
+	direction := p.fetchDirection()
+	return fmt.Sprintf("%s %s, %s %s", column, direction, idColumn, direction)
+}
+
+// PageInfo tells if pages exist around the fetched rows.
+type PageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+}
+
+// PageInfo returns the page info from the number of fetched rows, and the number of rows
+// to keep. A page following a cursor is assumed to have a page on the other side.
+func (p KeysetParams) PageInfo(fetched int) (PageInfo, int) {
+	more := fetched > p.PageSize()
+
+	keep := fetched
+	if more {
+		keep = p.PageSize()
+	}
+
+	if p.Backward() {
+		return PageInfo{HasPreviousPage: more, HasNextPage: p.Before != ""}, keep
+	}
+
+	return PageInfo{HasNextPage: more, HasPreviousPage: p.After != ""}, keep
+}
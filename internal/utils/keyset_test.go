@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/brice-74/golang-base-api/pkg/validator"
+)
+
+var sortable = []string{"createdAt", "-createdAt"}
+
+var (
+	cursorTime = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	cursorID   = "a1f5ccd2-9f06-4c0e-8a9c-4fa1d4b7d7e1"
+)
+
+func TestCursor(t *testing.T) {
+	c, err := DecodeCursor(EncodeCursor("createdAt", cursorTime, cursorID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Sort != "createdAt" || !c.Value.Equal(cursorTime) || c.ID != cursorID {
+		t.Fatalf("got cursor: %+v", c)
+	}
+
+	for _, s := range []string{
+		"not base64!",
+		EncodeCursor("createdAt", cursorTime, ""),
+		EncodeCursor("createdAt", cursorTime, "id"),
+		EncodeCursor("createdAt", time.Time{}, cursorID),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"s":"createdAt","v":"value","id":"` + cursorID + `"}`)),
+	} {
+		if _, err := DecodeCursor(s); err == nil {
+			t.Errorf("%q: expect an invalid cursor", s)
+		}
+	}
+}
+
+func TestKeysetParamsValidate(t *testing.T) {
+	cursor := EncodeCursor("createdAt", cursorTime, cursorID)
+
+	tests := []struct {
+		title  string
+		params KeysetParams
+		expect []string
+	}{
+		{title: "should be valid", params: KeysetParams{First: 10, After: cursor, Sort: "createdAt"}},
+		{title: "should refuse first and last", params: KeysetParams{First: 10, Last: 10, Sort: "createdAt"}, expect: []string{"first"}},
+		{title: "should refuse page over 100", params: KeysetParams{Last: 101, Sort: "createdAt"}, expect: []string{"last"}},
+		{title: "should refuse invalid cursor", params: KeysetParams{Before: "invalid", Sort: "createdAt"}, expect: []string{"before"}},
+		{title: "should refuse cursor of another sort", params: KeysetParams{After: cursor, Sort: "-createdAt"}, expect: []string{"after"}},
+		{title: "should refuse unknown sort", params: KeysetParams{Sort: "email"}, expect: []string{"sort"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			tt.params.SortableFields = sortable
+
+			v := validator.New()
+			tt.params.Validate(v)
+
+			if len(v.Errors) != len(tt.expect) {
+				t.Fatalf("got errors: %v, expect errors on: %v", v.Errors, tt.expect)
+			}
+
+			for _, key := range tt.expect {
+				if _, ok := v.Errors[key]; !ok {
+					t.Fatalf("got errors: %v, expect error on: %s", v.Errors, key)
+				}
+			}
+		})
+	}
+}
+
+func TestKeysetParamsQuery(t *testing.T) {
+	cursor := EncodeCursor("createdAt", cursorTime, cursorID)
+
+	tests := []struct {
+		title       string
+		params      KeysetParams
+		expectWhere string
+		expectOrder string
+	}{
+		{
+			title:       "should start list",
+			params:      KeysetParams{First: 10, Sort: "createdAt"},
+			expectWhere: "TRUE",
+			expectOrder: "created_at ASC, id ASC",
+		},
+		{
+			title:       "should fetch after cursor",
+			params:      KeysetParams{First: 10, After: cursor, Sort: "createdAt"},
+			expectWhere: "(created_at, id) > ($3, $4)",
+			expectOrder: "created_at ASC, id ASC",
+		},
+		{
+			title:       "should fetch after cursor in descending order",
+			params:      KeysetParams{First: 10, After: cursor, Sort: "-createdAt"},
+			expectWhere: "(created_at, id) < ($3, $4)",
+			expectOrder: "created_at DESC, id DESC",
+		},
+		{
+			title:       "should fetch before cursor in reverse order",
+			params:      KeysetParams{Last: 10, Before: cursor, Sort: "createdAt"},
+			expectWhere: "(created_at, id) < ($3, $4)",
+			expectOrder: "created_at DESC, id DESC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			tt.params.SortableFields = sortable
+			column := tt.params.SortColumn()
+
+			where, args := tt.params.Where(column, "id", 3)
+			if where != tt.expectWhere {
+				t.Fatalf("got where: %s, expect: %s", where, tt.expectWhere)
+			}
+
+			if where != "TRUE" && (len(args) != 2 || args[0] != cursorTime || args[1] != cursorID) {
+				t.Fatalf("got args: %v, expect cursor values", args)
+			}
+
+			if order := tt.params.OrderBy(column, "id"); order != tt.expectOrder {
+				t.Fatalf("got order: %s, expect: %s", order, tt.expectOrder)
+			}
+		})
+	}
+}
+
+func TestKeysetParamsPageInfo(t *testing.T) {
+	cursor := EncodeCursor("createdAt", cursorTime, cursorID)
+
+	tests := []struct {
+		title      string
+		params     KeysetParams
+		fetched    int
+		expectInfo PageInfo
+		expectKeep int
+	}{
+		{
+			title:      "should have next page",
+			params:     KeysetParams{First: 2},
+			fetched:    3,
+			expectInfo: PageInfo{HasNextPage: true},
+			expectKeep: 2,
+		},
+		{
+			title:      "should be last page after cursor",
+			params:     KeysetParams{First: 2, After: cursor},
+			fetched:    2,
+			expectInfo: PageInfo{HasPreviousPage: true},
+			expectKeep: 2,
+		},
+		{
+			title:      "should have previous page before cursor",
+			params:     KeysetParams{Last: 2, Before: cursor},
+			fetched:    3,
+			expectInfo: PageInfo{HasNextPage: true, HasPreviousPage: true},
+			expectKeep: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			info, keep := tt.params.PageInfo(tt.fetched)
+
+			if info != tt.expectInfo || keep != tt.expectKeep {
+				t.Fatalf("got: %+v and %d rows, expect: %+v and %d rows", info, keep, tt.expectInfo, tt.expectKeep)
+			}
+		})
+	}
+}
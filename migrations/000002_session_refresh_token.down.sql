@@ -0,0 +1,2 @@
+ALTER TABLE user_session
+  DROP COLUMN IF EXISTS "refresh_token_id";
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE user_session
+  ADD COLUMN IF NOT EXISTS "refresh_token_id" uuid NOT NULL DEFAULT (uuid_generate_v4());
\ No newline at end of file
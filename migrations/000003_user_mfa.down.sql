@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_recovery_code;
+
+ALTER TABLE user_account
+  DROP COLUMN IF EXISTS "mfa_secret",
+  DROP COLUMN IF EXISTS "mfa_enabled_at";
\ No newline at end of file
@@ -0,0 +1,13 @@
+ALTER TABLE user_account
+  ADD COLUMN IF NOT EXISTS "mfa_secret" TEXT,
+  ADD COLUMN IF NOT EXISTS "mfa_enabled_at" TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS user_recovery_code (
+  "id" uuid PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "created_at" TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  "used_at" TIMESTAMP(0) WITH TIME ZONE,
+  "code_hash" TEXT NOT NULL,
+  "user_id" uuid NOT NULL REFERENCES user_account ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_recovery_code_user_id_idx ON user_recovery_code ("user_id");
\ No newline at end of file
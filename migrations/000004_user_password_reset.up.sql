@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_password_reset (
+  "id" uuid PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "created_at" TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  "expires_at" TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+  "used_at" TIMESTAMP(0) WITH TIME ZONE,
+  "token_hash" TEXT UNIQUE NOT NULL,
+  "user_id" uuid NOT NULL REFERENCES user_account ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE user_account
+  DROP COLUMN IF EXISTS "verified_at";
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE user_account ADD COLUMN IF NOT EXISTS "verified_at" TIMESTAMP(0) WITH TIME ZONE;
+
+-- accounts registered before email verification are trusted
+UPDATE user_account SET verified_at = created_at WHERE verified_at IS NULL;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_access_token;
\ No newline at end of file
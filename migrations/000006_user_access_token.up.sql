@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS user_access_token (
+  "id" uuid PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "created_at" TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  "expires_at" TIMESTAMP(0) WITH TIME ZONE,
+  "last_used_at" TIMESTAMP(0) WITH TIME ZONE,
+  "name" TEXT NOT NULL,
+  "scopes" TEXT [ ] NOT NULL DEFAULT '{}',
+  "token_hash" TEXT UNIQUE NOT NULL,
+  "user_id" uuid NOT NULL REFERENCES user_account ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_access_token_user_id_idx ON user_access_token ("user_id");
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_login_failure;
\ No newline at end of file
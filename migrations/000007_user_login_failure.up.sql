@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_login_failure (
+  "id" uuid PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "created_at" TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  "ip" TEXT NOT NULL,
+  "user_id" uuid REFERENCES user_account ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_login_failure_user_id_idx ON user_login_failure ("user_id", "created_at");
+CREATE INDEX IF NOT EXISTS user_login_failure_ip_idx ON user_login_failure ("ip", "created_at");
\ No newline at end of file
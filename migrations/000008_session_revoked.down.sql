@@ -0,0 +1,2 @@
+ALTER TABLE user_session
+  DROP COLUMN IF EXISTS "revoked_at";
\ No newline at end of file
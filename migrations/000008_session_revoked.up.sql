@@ -0,0 +1,2 @@
+ALTER TABLE user_session
+  ADD COLUMN IF NOT EXISTS "revoked_at" TIMESTAMP(0) WITH TIME ZONE;
\ No newline at end of file
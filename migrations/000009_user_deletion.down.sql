@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS user_account_deletion_scheduled_at_idx;
+
+ALTER TABLE user_account
+  DROP COLUMN IF EXISTS "deletion_scheduled_at";
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE user_account
+  ADD COLUMN IF NOT EXISTS "deletion_scheduled_at" TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS user_account_deletion_scheduled_at_idx ON user_account ("deletion_scheduled_at");
\ No newline at end of file
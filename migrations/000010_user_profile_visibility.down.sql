@@ -0,0 +1,2 @@
+ALTER TABLE user_account
+  DROP COLUMN IF EXISTS "profile_visibility";
\ No newline at end of file
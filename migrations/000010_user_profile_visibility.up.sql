@@ -0,0 +1,2 @@
+ALTER TABLE user_account
+  ADD COLUMN IF NOT EXISTS "profile_visibility" TEXT NOT NULL DEFAULT 'PUBLIC';
\ No newline at end of file
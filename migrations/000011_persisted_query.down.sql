@@ -0,0 +1 @@
+DROP TABLE IF EXISTS persisted_query;
\ No newline at end of file
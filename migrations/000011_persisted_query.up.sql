@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS persisted_query (
+  "hash" TEXT PRIMARY KEY,
+  "created_at" TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  "query" TEXT NOT NULL
+);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE user_account
+  DROP COLUMN IF EXISTS "mfa_last_step";
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE user_account
+  ADD COLUMN IF NOT EXISTS "mfa_last_step" BIGINT NOT NULL DEFAULT 0;
\ No newline at end of file
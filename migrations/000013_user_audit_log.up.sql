@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_audit_log (
+  "id" uuid PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "created_at" TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  "action" TEXT NOT NULL,
+  "ip" TEXT NOT NULL,
+  "user_id" uuid NOT NULL REFERENCES user_account ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_audit_log_user_id_idx ON user_audit_log ("user_id", "created_at");
\ No newline at end of file
@@ -0,0 +1,356 @@
+// Package complexity computes the cost of GraphQL queries before their execution, so
+// queries too expensive for the server can be refused. The depth of the queries is
+// limited by the schema, see graphql.MaxDepth.
+package complexity
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/scanner"
+
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+// CostDirective overrides the cost of a schema field:
+//
+//	directive @cost(value: Int, multipliers: [String!]) on FIELD_DEFINITION
+//
+// value is the cost of the field itself, 1 by default. The cost of the subfields is
+// multiplied by the value of the multipliers arguments, or by their length for lists.
+// Without directive, the subfields are multiplied by the limit, first or last argument.
+const CostDirective = "cost"
+
+// DefaultMultipliers are the arguments giving the number of items of a list field.
+var DefaultMultipliers = []string{"limit", "first", "last"}
+
+// maxCost keeps the computations from overflowing, costs saturate at this value.
+const maxCost = 1 << 40
+
+var (
+	ErrUnknownOperation = errors.New("unknown operation")
+	ErrUnknownFragment  = errors.New("unknown fragment")
+	ErrFragmentCycle    = errors.New("fragment spreads form a cycle")
+)
+
+// Analyzer computes the cost of the queries of a schema.
+type Analyzer struct {
+	schema *types.Schema
+	// DefaultListSize is the number of items of a list whose multiplier arguments aren't
+	// given or aren't positive, like the default page size of the connections.
+	DefaultListSize int
+}
+
+func NewAnalyzer(schema *types.Schema, defaultListSize int) *Analyzer {
+	return &Analyzer{schema: schema, DefaultListSize: defaultListSize}
+}
+
+// Result is the total cost of an operation. Every field costs 1 unless its @cost
+// directive says otherwise, and the cost of the subfields of a list is multiplied by
+// the number of items it can return. Introspection fields are free: their cost is
+// bounded by the size of the schema.
+type Result struct {
+	OperationName string
+	Complexity    int
+}
+
+// Analyze computes the cost of the operation of the query. The query is parsed like
+// the schema does, and should have been validated by the schema: an error is returned
+// for the invalid queries met.
+func (a *Analyzer) Analyze(query, operationName string, variables map[string]interface{}) (Result, error) {
+	doc, err := parse(query)
+	if err != nil {
+		return Result{}, err
+	}
+
+	op, err := operation(doc, operationName)
+	if err != nil {
+		return Result{}, err
+	}
+
+	root, ok := a.schema.EntryPoints[strings.ToLower(string(op.Type))]
+	if !ok {
+		return Result{}, fmt.Errorf("%w: no %s type", ErrUnknownOperation, strings.ToLower(string(op.Type)))
+	}
+
+	w := &walker{
+		analyzer:  a,
+		doc:       doc,
+		vars:      variables,
+		defaults:  map[string]types.Value{},
+		fragments: map[string]fragmentCost{},
+	}
+	for _, v := range op.Vars {
+		if v.Default != nil {
+			w.defaults[v.Name.Name] = v.Default
+		}
+	}
+
+	cost, err := w.selections(op.Selections, root)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{OperationName: op.Name.Name, Complexity: cost}, nil
+}
+
+// operation returns the operation of the name, the only operation of the document
+// without name.
+func operation(doc *types.ExecutableDefinition, name string) (*types.OperationDefinition, error) {
+	if name == "" {
+		if len(doc.Operations) != 1 {
+			return nil, fmt.Errorf("%w: the operation name is required", ErrUnknownOperation)
+		}
+		return doc.Operations[0], nil
+	}
+
+	if op := doc.Operations.Get(name); op != nil {
+		return op, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownOperation, name)
+}
+
+// walker computes the cost of the selections of an operation.
+type walker struct {
+	analyzer *Analyzer
+	doc      *types.ExecutableDefinition
+	vars     map[string]interface{}
+	// defaults are the default values of the variables of the operation.
+	defaults map[string]types.Value
+	// fragments caches the cost of the fragments by name, so a fragment spread
+	// many times isn't walked each time.
+	fragments map[string]fragmentCost
+}
+
+type fragmentCost struct {
+	cost    int
+	walking bool
+}
+
+// selections returns the cost of the selections on the type.
+func (w *walker) selections(sels []types.Selection, t types.NamedType) (cost int, err error) {
+	for _, sel := range sels {
+		var c int
+
+		switch sel := sel.(type) {
+		case *types.Field:
+			c, err = w.field(sel, t)
+		case *types.InlineFragment:
+			ft := t
+			if sel.On.Name != "" {
+				ft = w.analyzer.schema.Types[sel.On.Name]
+			}
+			c, err = w.selections(sel.Selections, ft)
+		case *types.FragmentSpread:
+			c, err = w.fragmentSpread(sel.Name.Name)
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		cost = add(cost, c)
+	}
+
+	return cost, nil
+}
+
+func (w *walker) fragmentSpread(name string) (int, error) {
+	if fc, ok := w.fragments[name]; ok {
+		if fc.walking {
+			return 0, fmt.Errorf("%w: %q", ErrFragmentCycle, name)
+		}
+		return fc.cost, nil
+	}
+
+	f := w.doc.Fragments.Get(name)
+	if f == nil {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownFragment, name)
+	}
+
+	w.fragments[name] = fragmentCost{walking: true}
+
+	cost, err := w.selections(f.Selections, w.analyzer.schema.Types[f.On.Name])
+	if err != nil {
+		return 0, err
+	}
+
+	w.fragments[name] = fragmentCost{cost: cost}
+
+	return cost, nil
+}
+
+func (w *walker) field(f *types.Field, t types.NamedType) (int, error) {
+	switch f.Name.Name {
+	case "__typename", "__schema", "__type":
+		return 0, nil
+	}
+
+	def := fieldDefinition(t, f.Name.Name)
+	if def == nil {
+		return 0, fmt.Errorf("unknown field %q", f.Name.Name)
+	}
+
+	if len(f.SelectionSet) == 0 {
+		return w.cost(def), nil
+	}
+
+	cost, err := w.selections(f.SelectionSet, namedType(def.Type))
+	if err != nil {
+		return 0, err
+	}
+
+	return add(w.cost(def), mul(w.multiplier(def, f.Arguments), cost)), nil
+}
+
+// cost returns the value of the @cost directive of the field, 1 without directive.
+func (w *walker) cost(def *types.FieldDefinition) int {
+	d := def.Directives.Get(CostDirective)
+	if d == nil {
+		return 1
+	}
+
+	// arguments without value are listed with a nil value
+	v, ok := d.Arguments.Get("value")
+	if !ok || v == nil {
+		return 1
+	}
+
+	n, _ := w.count(v)
+	return n
+}
+
+// multiplier returns the number of items the field can return, 1 if it has no
+// multiplier argument. Like utils.KeysetParams.PageSize, a list without a positive
+// multiplier returns the default list size.
+func (w *walker) multiplier(def *types.FieldDefinition, args types.ArgumentList) int {
+	names := DefaultMultipliers
+	if d := def.Directives.Get(CostDirective); d != nil {
+		if v, ok := d.Arguments.Get("multipliers"); ok && v != nil {
+			names = nil
+			list, _ := v.Deserialize(nil).([]interface{})
+			for _, name := range list {
+				if s, ok := name.(string); ok {
+					names = append(names, s)
+				}
+			}
+		}
+	}
+
+	multiplier, found := 0, false
+	for _, name := range names {
+		argDef := def.Arguments.Get(name)
+		if argDef == nil {
+			continue
+		}
+		found = true
+
+		v, ok := args.Get(name)
+		if !ok {
+			v = argDef.Default
+		}
+
+		if n, ok := w.count(v); ok && n > multiplier {
+			multiplier = n
+		}
+	}
+
+	switch {
+	case !found:
+		return 1
+	case multiplier <= 0:
+		return w.analyzer.DefaultListSize
+	default:
+		return multiplier
+	}
+}
+
+// count returns the number of a value, or the length of a list. The values of the
+// variables are taken from the request, or from their default value.
+func (w *walker) count(v types.Value) (int, bool) {
+	switch v := v.(type) {
+	case *types.Variable:
+		if value, ok := w.vars[v.Name]; ok {
+			return toInt(value)
+		}
+		if value, ok := w.defaults[v.Name]; ok {
+			return w.count(value)
+		}
+		return 0, false
+	case *types.PrimitiveValue:
+		if v.Type != scanner.Int {
+			return 0, false
+		}
+		n, err := strconv.ParseInt(v.Text, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return toInt(float64(n))
+	case *types.ListValue:
+		return len(v.Values), true
+	default:
+		return 0, false
+	}
+}
+
+func fieldDefinition(t types.NamedType, name string) *types.FieldDefinition {
+	switch t := t.(type) {
+	case *types.ObjectTypeDefinition:
+		return t.Fields.Get(name)
+	case *types.InterfaceTypeDefinition:
+		return t.Fields.Get(name)
+	default:
+		return nil
+	}
+}
+
+// namedType returns the type of the items of lists and of non null types.
+func namedType(t types.Type) types.NamedType {
+	for {
+		switch wrapper := t.(type) {
+		case *types.List:
+			t = wrapper.OfType
+		case *types.NonNull:
+			t = wrapper.OfType
+		default:
+			named, _ := t.(types.NamedType)
+			return named
+		}
+	}
+}
+
+// toInt converts the numbers and the lists of JSON variables.
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return toInt(float64(n))
+	case float64:
+		if n > maxCost {
+			return maxCost, true
+		}
+		return int(n), true
+	case []interface{}:
+		return len(n), true
+	default:
+		return 0, false
+	}
+}
+
+func add(a, b int) int {
+	if a+b > maxCost {
+		return maxCost
+	}
+	return a + b
+}
+
+func mul(a, b int) int {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	if a > maxCost/b {
+		return maxCost
+	}
+	return a * b
+}
@@ -0,0 +1,215 @@
+package complexity
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+const testSchema = `
+	directive @cost(value: Int, multipliers: [String!]) on FIELD_DEFINITION
+
+	schema {
+		query: Query
+		mutation: Mutation
+	}
+
+	type Query {
+		me: User!
+		users(limit: Int = 20): [User!]!
+		search(first: Int, last: Int): [User!]!
+		nodes(ids: [ID!]!): [Node]! @cost(multipliers: ["ids"])
+		export: String! @cost(value: 50)
+	}
+
+	type Mutation {
+		rename(name: String!): User!
+	}
+
+	interface Node {
+		id: ID!
+	}
+
+	type User implements Node {
+		id: ID!
+		name: String!
+		friends(limit: Int = 10): [User!]!
+	}
+`
+
+func newTestAnalyzer() *Analyzer {
+	return NewAnalyzer(graphql.MustParseSchema(testSchema, nil).ASTSchema(), 5)
+}
+
+func TestAnalyze(t *testing.T) {
+	a := newTestAnalyzer()
+
+	tests := []struct {
+		title            string
+		query            string
+		operationName    string
+		variables        map[string]interface{}
+		expectComplexity int
+	}{
+		{
+			title:            "should count each field",
+			query:            `{ me { id name } }`,
+			expectComplexity: 3,
+		},
+		{
+			title:            "should multiply list by limit",
+			query:            `{ users(limit: 100) { id name } }`,
+			expectComplexity: 1 + 100*2,
+		},
+		{
+			title:            "should multiply list by default limit",
+			query:            `{ users { id friends { id } } }`,
+			expectComplexity: 1 + 20*(1+1+10*1),
+		},
+		{
+			title:            "should multiply list by limit variable",
+			query:            `query ($n: Int = 3) { users(limit: $n) { id } }`,
+			variables:        map[string]interface{}{"n": float64(50)},
+			expectComplexity: 1 + 50,
+		},
+		{
+			title:            "should multiply list by variable default value",
+			query:            `query ($n: Int = 3) { users(limit: $n) { id } }`,
+			expectComplexity: 1 + 3,
+		},
+		{
+			title:            "should multiply list by the given multiplier",
+			query:            `{ search(first: 2) { id } }`,
+			expectComplexity: 1 + 2,
+		},
+		{
+			title:            "should multiply list by default list size",
+			query:            `{ search(last: null) { id } }`,
+			expectComplexity: 1 + 5,
+		},
+		{
+			title:            "should multiply list by default list size for empty page",
+			query:            `{ search(first: 0) { id } }`,
+			expectComplexity: 1 + 5,
+		},
+		{
+			title:            "should multiply list by default list size for negative zero",
+			query:            `{ search(first: -0) { id } }`,
+			expectComplexity: 1 + 5,
+		},
+		{
+			title:            "should multiply list by length of multiplier argument",
+			query:            `{ nodes(ids: ["1", "2", "3"]) { id ... on User { name } } }`,
+			expectComplexity: 1 + 3*2,
+		},
+		{
+			title:            "should use cost directive",
+			query:            `{ export }`,
+			expectComplexity: 50,
+		},
+		{
+			title: "should count fragments",
+			query: `
+				query Users { users(limit: 2) { ...user } }
+				query Me { me { ...user } }
+				fragment user on User { id friends(limit: 3) { ...name } }
+				fragment name on User { name, __typename }`,
+			operationName:    "Users",
+			expectComplexity: 1 + 2*(1+1+3*1),
+		},
+		{
+			title:            "should ignore introspection",
+			query:            `{ __schema { types { name fields { name type { ofType { ofType { name } } } } } } me { __typename id } }`,
+			expectComplexity: 2,
+		},
+		{
+			title:            "should analyze mutations",
+			query:            `mutation { rename(name: "name") { id } }`,
+			expectComplexity: 2,
+		},
+		{
+			title:            "should saturate huge costs",
+			query:            `{ users(limit: 1000000) { friends(limit: 1000000) { friends(limit: 1000000) { id } } } }`,
+			expectComplexity: maxCost,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			got, err := a.Analyze(tt.query, tt.operationName, tt.variables)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Complexity != tt.expectComplexity {
+				t.Fatalf("got complexity: %d, expect: %d", got.Complexity, tt.expectComplexity)
+			}
+		})
+	}
+}
+
+// TestAnalyzeParse checks that the queries are parsed like the schema parses them: the
+// queries valid against the schema are analyzed, the others are refused.
+func TestAnalyzeParse(t *testing.T) {
+	s := graphql.MustParseSchema(testSchema, nil)
+	a := NewAnalyzer(s.ASTSchema(), 5)
+
+	tests := []struct {
+		title string
+		query string
+	}{
+		{title: "should parse escaped quote", query: `mutation { rename(name: "a \"b\" c") { id } }`},
+		{title: "should parse unicode escape", query: `mutation { rename(name: "caf\u00e9") { id } }`},
+		{title: "should parse escaped solidus", query: `mutation { rename(name: "a\/b") { id } }`},
+		{title: "should parse escaped backslash", query: `mutation { rename(name: "a\\b") { id } }`},
+		{title: "should parse block string", query: `mutation { rename(name: """a "b" c""") { id } }`},
+		{title: "should parse multiline block string", query: "mutation { rename(name: \"\"\"\n  a\n  b\n\"\"\") { id } }"},
+		{title: "should parse unterminated block string", query: `mutation { rename(name: """a) { id } }`},
+		{title: "should parse negative zero", query: `{ search(first: -0) { id } }`},
+		{title: "should parse string in comment", query: "{ me { id # \"\"\" \"\n } }"},
+		{title: "should parse variable description", query: `query ($"count" n: Int) { search(first: $n) { id } }`},
+		{title: "should parse variable block string description", query: `query ($"""count""" n: Int) { search(first: $n) { id } }`},
+		{title: "should parse misplaced variable description", query: `query ("count" $n: Int) { search(first: $n) { id } }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			errs := s.Validate(tt.query)
+			_, err := a.Analyze(tt.query, "", nil)
+
+			if (len(errs) == 0) != (err == nil) {
+				t.Fatalf("got analyze error: %v, schema errors: %v", err, errs)
+			}
+		})
+	}
+}
+
+func TestAnalyzeInvalidQuery(t *testing.T) {
+	a := newTestAnalyzer()
+
+	tests := []struct {
+		title  string
+		query  string
+		expect error
+	}{
+		{title: "should refuse syntax error", query: `{ me { id }`},
+		{title: "should refuse unknown field", query: `{ me { password } }`},
+		{title: "should refuse unknown fragment", query: `{ me { ...user } }`, expect: ErrUnknownFragment},
+		{title: "should refuse fragment cycle", query: `{ me { ...a } } fragment a on User { ...b } fragment b on User { ...a }`, expect: ErrFragmentCycle},
+		{title: "should refuse operations without name", query: `query A { me { id } } query B { me { id } }`, expect: ErrUnknownOperation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			_, err := a.Analyze(tt.query, "", nil)
+			if err == nil {
+				t.Fatal("got nil error, expect query refused")
+			}
+
+			if tt.expect != nil && !errors.Is(err, tt.expect) {
+				t.Fatalf("got error: %v, expect: %v", err, tt.expect)
+			}
+		})
+	}
+}
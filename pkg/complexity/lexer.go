@@ -0,0 +1,291 @@
+package complexity
+
+// The lexer and the parser of the queries are ported from the internal packages common
+// and query of github.com/graph-gophers/graphql-go v1.3.0, so the analyzer accepts and
+// reads the queries exactly like the schema executing them. They build the AST of the
+// exported package types of graphql-go.
+//
+// Copyright (c) 2016 Richard Musiol. All rights reserved.
+// Use of this source code is governed by the BSD 2-Clause license of graphql-go.
+
+import (
+	"fmt"
+	"strings"
+	"text/scanner"
+
+	"github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+type syntaxError string
+
+type lexer struct {
+	sc   *scanner.Scanner
+	next rune
+}
+
+func newLexer(s string) *lexer {
+	sc := &scanner.Scanner{
+		Mode: scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats | scanner.ScanStrings,
+	}
+	sc.Init(strings.NewReader(s))
+
+	l := lexer{sc: sc}
+	l.sc.Error = func(_ *scanner.Scanner, msg string) { l.syntaxError(msg) }
+
+	return &l
+}
+
+func (l *lexer) catchSyntaxError(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if msg, ok := r.(syntaxError); ok {
+				loc := l.location()
+				err = fmt.Errorf("syntax error at %d:%d: %s", loc.Line, loc.Column, msg)
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	f()
+	return
+}
+
+func (l *lexer) peek() rune {
+	return l.next
+}
+
+// consumeWhitespace consumes whitespace and tokens equivalent to whitespace: commas and
+// comments.
+func (l *lexer) consumeWhitespace() {
+	for {
+		l.next = l.sc.Scan()
+
+		if l.next == ',' {
+			continue
+		}
+
+		if l.next == '#' {
+			l.consumeComment()
+			continue
+		}
+
+		break
+	}
+}
+
+// consumeDescription consumes the description of an input value if any, a string or a
+// block string. Descriptions are read by graphql-go before the name of the variables.
+func (l *lexer) consumeDescription() {
+	if l.next != scanner.String {
+		return
+	}
+
+	// a block string is scanned as an empty string followed by an open quote, a string
+	// is already scanned
+	if l.sc.Peek() == '"' {
+		l.consumeTripleQuoteComment()
+	}
+	l.consumeWhitespace()
+}
+
+func (l *lexer) consumeIdent() string {
+	name := l.sc.TokenText()
+	l.consumeToken(scanner.Ident)
+	return name
+}
+
+func (l *lexer) consumeIdentWithLoc() types.Ident {
+	loc := l.location()
+	name := l.sc.TokenText()
+	l.consumeToken(scanner.Ident)
+	return types.Ident{Name: name, Loc: loc}
+}
+
+func (l *lexer) consumeKeyword(keyword string) {
+	if l.next != scanner.Ident || l.sc.TokenText() != keyword {
+		l.syntaxError(fmt.Sprintf("unexpected %q, expecting %q", l.sc.TokenText(), keyword))
+	}
+	l.consumeWhitespace()
+}
+
+func (l *lexer) consumeLiteral() *types.PrimitiveValue {
+	lit := &types.PrimitiveValue{Type: l.next, Text: l.sc.TokenText()}
+	l.consumeWhitespace()
+	return lit
+}
+
+func (l *lexer) consumeToken(expected rune) {
+	if l.next != expected {
+		l.syntaxError(fmt.Sprintf("unexpected %q, expecting %s", l.sc.TokenText(), scanner.TokenString(expected)))
+	}
+	l.consumeWhitespace()
+}
+
+func (l *lexer) syntaxError(message string) {
+	panic(syntaxError(message))
+}
+
+func (l *lexer) location() errors.Location {
+	return errors.Location{
+		Line:   l.sc.Line,
+		Column: l.sc.Column,
+	}
+}
+
+func (l *lexer) consumeTripleQuoteComment() {
+	l.next = l.sc.Next()
+	if l.next != '"' {
+		panic("consumeTripleQuoteComment used in wrong context: no third quote?")
+	}
+
+	var numQuotes int
+	for {
+		l.next = l.sc.Next()
+		if l.next == '"' {
+			numQuotes++
+		} else {
+			numQuotes = 0
+		}
+		if numQuotes == 3 || l.next == scanner.EOF {
+			break
+		}
+	}
+}
+
+// consumeComment consumes all characters from `#` to the first line terminator.
+func (l *lexer) consumeComment() {
+	if l.next != '#' {
+		panic("consumeComment used in wrong context")
+	}
+
+	if l.sc.Peek() == ' ' {
+		l.sc.Next()
+	}
+
+	for {
+		next := l.sc.Next()
+		if next == '\r' || next == '\n' || next == scanner.EOF {
+			break
+		}
+	}
+}
+
+func parseInputValue(l *lexer) *types.InputValueDefinition {
+	p := &types.InputValueDefinition{}
+	p.Loc = l.location()
+	l.consumeDescription()
+	p.Name = l.consumeIdentWithLoc()
+	l.consumeToken(':')
+	p.TypeLoc = l.location()
+	p.Type = parseType(l)
+	if l.peek() == '=' {
+		l.consumeToken('=')
+		p.Default = parseLiteral(l, true)
+	}
+	p.Directives = parseDirectives(l)
+	return p
+}
+
+func parseArgumentList(l *lexer) types.ArgumentList {
+	var args types.ArgumentList
+	l.consumeToken('(')
+	for l.peek() != ')' {
+		name := l.consumeIdentWithLoc()
+		l.consumeToken(':')
+		value := parseLiteral(l, false)
+		args = append(args, &types.Argument{
+			Name:  name,
+			Value: value,
+		})
+	}
+	l.consumeToken(')')
+	return args
+}
+
+func parseLiteral(l *lexer, constOnly bool) types.Value {
+	loc := l.location()
+	switch l.peek() {
+	case '$':
+		if constOnly {
+			l.syntaxError("variable not allowed")
+			panic("unreachable")
+		}
+		l.consumeToken('$')
+		return &types.Variable{Name: l.consumeIdent(), Loc: loc}
+
+	case scanner.Int, scanner.Float, scanner.String, scanner.Ident:
+		lit := l.consumeLiteral()
+		if lit.Type == scanner.Ident && lit.Text == "null" {
+			return &types.NullValue{Loc: loc}
+		}
+		lit.Loc = loc
+		return lit
+	case '-':
+		l.consumeToken('-')
+		lit := l.consumeLiteral()
+		lit.Text = "-" + lit.Text
+		lit.Loc = loc
+		return lit
+	case '[':
+		l.consumeToken('[')
+		var list []types.Value
+		for l.peek() != ']' {
+			list = append(list, parseLiteral(l, constOnly))
+		}
+		l.consumeToken(']')
+		return &types.ListValue{Values: list, Loc: loc}
+
+	case '{':
+		l.consumeToken('{')
+		var fields []*types.ObjectField
+		for l.peek() != '}' {
+			name := l.consumeIdentWithLoc()
+			l.consumeToken(':')
+			value := parseLiteral(l, constOnly)
+			fields = append(fields, &types.ObjectField{Name: name, Value: value})
+		}
+		l.consumeToken('}')
+		return &types.ObjectValue{Fields: fields, Loc: loc}
+
+	default:
+		l.syntaxError("invalid value")
+		panic("unreachable")
+	}
+}
+
+func parseType(l *lexer) types.Type {
+	t := parseNullType(l)
+	if l.peek() == '!' {
+		l.consumeToken('!')
+		return &types.NonNull{OfType: t}
+	}
+	return t
+}
+
+func parseNullType(l *lexer) types.Type {
+	if l.peek() == '[' {
+		l.consumeToken('[')
+		ofType := parseType(l)
+		l.consumeToken(']')
+		return &types.List{OfType: ofType}
+	}
+
+	return &types.TypeName{Ident: l.consumeIdentWithLoc()}
+}
+
+func parseDirectives(l *lexer) types.DirectiveList {
+	var directives types.DirectiveList
+	for l.peek() == '@' {
+		l.consumeToken('@')
+		d := &types.Directive{}
+		d.Name = l.consumeIdentWithLoc()
+		d.Name.Loc.Column--
+		if l.peek() == '(' {
+			d.Arguments = parseArgumentList(l)
+		}
+		directives = append(directives, d)
+	}
+	return directives
+}
@@ -0,0 +1,156 @@
+package complexity
+
+import (
+	"fmt"
+	"text/scanner"
+
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+// Types of the operations, as named by graphql-go.
+const (
+	queryOperation        types.OperationType = "QUERY"
+	mutationOperation     types.OperationType = "MUTATION"
+	subscriptionOperation types.OperationType = "SUBSCRIPTION"
+)
+
+// parse reads a query like graphql-go does, see lexer.go.
+func parse(queryString string) (*types.ExecutableDefinition, error) {
+	l := newLexer(queryString)
+
+	var execDef *types.ExecutableDefinition
+	err := l.catchSyntaxError(func() { execDef = parseExecutableDefinition(l) })
+	if err != nil {
+		return nil, err
+	}
+
+	return execDef, nil
+}
+
+func parseExecutableDefinition(l *lexer) *types.ExecutableDefinition {
+	ed := &types.ExecutableDefinition{}
+	l.consumeWhitespace()
+	for l.peek() != scanner.EOF {
+		if l.peek() == '{' {
+			op := &types.OperationDefinition{Type: queryOperation, Loc: l.location()}
+			op.Selections = parseSelectionSet(l)
+			ed.Operations = append(ed.Operations, op)
+			continue
+		}
+
+		loc := l.location()
+		switch x := l.consumeIdent(); x {
+		case "query":
+			op := parseOperation(l, queryOperation)
+			op.Loc = loc
+			ed.Operations = append(ed.Operations, op)
+
+		case "mutation":
+			ed.Operations = append(ed.Operations, parseOperation(l, mutationOperation))
+
+		case "subscription":
+			ed.Operations = append(ed.Operations, parseOperation(l, subscriptionOperation))
+
+		case "fragment":
+			frag := parseFragment(l)
+			frag.Loc = loc
+			ed.Fragments = append(ed.Fragments, frag)
+
+		default:
+			l.syntaxError(fmt.Sprintf(`unexpected %q, expecting "fragment"`, x))
+		}
+	}
+	return ed
+}
+
+func parseOperation(l *lexer, opType types.OperationType) *types.OperationDefinition {
+	op := &types.OperationDefinition{Type: opType}
+	op.Name.Loc = l.location()
+	if l.peek() == scanner.Ident {
+		op.Name = l.consumeIdentWithLoc()
+	}
+	op.Directives = parseDirectives(l)
+	if l.peek() == '(' {
+		l.consumeToken('(')
+		for l.peek() != ')' {
+			loc := l.location()
+			l.consumeToken('$')
+			iv := parseInputValue(l)
+			iv.Loc = loc
+			op.Vars = append(op.Vars, iv)
+		}
+		l.consumeToken(')')
+	}
+	op.Selections = parseSelectionSet(l)
+	return op
+}
+
+func parseFragment(l *lexer) *types.FragmentDefinition {
+	f := &types.FragmentDefinition{}
+	f.Name = l.consumeIdentWithLoc()
+	l.consumeKeyword("on")
+	f.On = types.TypeName{Ident: l.consumeIdentWithLoc()}
+	f.Directives = parseDirectives(l)
+	f.Selections = parseSelectionSet(l)
+	return f
+}
+
+func parseSelectionSet(l *lexer) []types.Selection {
+	var sels []types.Selection
+	l.consumeToken('{')
+	for l.peek() != '}' {
+		sels = append(sels, parseSelection(l))
+	}
+	l.consumeToken('}')
+	return sels
+}
+
+func parseSelection(l *lexer) types.Selection {
+	if l.peek() == '.' {
+		return parseSpread(l)
+	}
+	return parseFieldDef(l)
+}
+
+func parseFieldDef(l *lexer) *types.Field {
+	f := &types.Field{}
+	f.Alias = l.consumeIdentWithLoc()
+	f.Name = f.Alias
+	if l.peek() == ':' {
+		l.consumeToken(':')
+		f.Name = l.consumeIdentWithLoc()
+	}
+	if l.peek() == '(' {
+		f.Arguments = parseArgumentList(l)
+	}
+	f.Directives = parseDirectives(l)
+	if l.peek() == '{' {
+		f.SelectionSetLoc = l.location()
+		f.SelectionSet = parseSelectionSet(l)
+	}
+	return f
+}
+
+func parseSpread(l *lexer) types.Selection {
+	loc := l.location()
+	l.consumeToken('.')
+	l.consumeToken('.')
+	l.consumeToken('.')
+
+	f := &types.InlineFragment{Loc: loc}
+	if l.peek() == scanner.Ident {
+		ident := l.consumeIdentWithLoc()
+		if ident.Name != "on" {
+			fs := &types.FragmentSpread{
+				Name: ident,
+				Loc:  loc,
+			}
+			fs.Directives = parseDirectives(l)
+			return fs
+		}
+		f.On = types.TypeName{Ident: l.consumeIdentWithLoc()}
+	}
+	f.Directives = parseDirectives(l)
+	f.Selections = parseSelectionSet(l)
+	return f
+}
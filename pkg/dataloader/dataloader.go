@@ -0,0 +1,134 @@
+// Package dataloader batches the loads of values by key made concurrently, like the
+// resolvers of the items of a GraphQL list, into a single fetch, and caches the values.
+package dataloader
+
+import (
+	"sync"
+	"time"
+)
+
+// BatchFunc fetches the values of the keys. Keys without value are left out of the map.
+type BatchFunc func(keys []string) (map[string]interface{}, error)
+
+// Loader collects the keys loaded during the wait duration, then fetches them at once.
+// A loader caches every value it loads, it must only live as long as a request.
+type Loader struct {
+	fetch    BatchFunc
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	cache map[string]*call
+	// batch collects the keys to fetch, nil when no key is waiting.
+	batch *batch
+}
+
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+type batch struct {
+	keys    []string
+	calls   []*call
+	started bool
+}
+
+// New returns a loader fetching the keys loaded during wait, at most maxBatch at once.
+func New(fetch BatchFunc, wait time.Duration, maxBatch int) *Loader {
+	return &Loader{
+		fetch:    fetch,
+		wait:     wait,
+		maxBatch: maxBatch,
+		cache:    map[string]*call{},
+	}
+}
+
+// Load returns the value of the key, nil if it has no value. The value is fetched with
+// the other keys loaded meanwhile, unless it's already cached.
+func (l *Loader) Load(key string) (interface{}, error) {
+	l.mu.Lock()
+
+	c, ok := l.cache[key]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		l.cache[key] = c
+		l.enqueue(key, c)
+	}
+
+	l.mu.Unlock()
+
+	<-c.done
+	return c.value, c.err
+}
+
+// Prime caches the value of the key, when it has been loaded by other means.
+func (l *Loader) Prime(key string, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+
+	c := &call{done: make(chan struct{}), value: value}
+	close(c.done)
+	l.cache[key] = c
+}
+
+// enqueue adds the key to the current batch, it must be called with the lock held.
+func (l *Loader) enqueue(key string, c *call) {
+	if l.batch == nil {
+		b := &batch{}
+		l.batch = b
+		time.AfterFunc(l.wait, func() { l.dispatch(b) })
+	}
+
+	b := l.batch
+	b.keys = append(b.keys, key)
+	b.calls = append(b.calls, c)
+
+	if len(b.keys) >= l.maxBatch {
+		b.started = true
+		l.batch = nil
+		go l.run(b)
+	}
+}
+
+// dispatch fetches the batch once its wait is over, unless it was full before.
+func (l *Loader) dispatch(b *batch) {
+	l.mu.Lock()
+
+	if b.started {
+		l.mu.Unlock()
+		return
+	}
+
+	b.started = true
+	if l.batch == b {
+		l.batch = nil
+	}
+
+	l.mu.Unlock()
+
+	l.run(b)
+}
+
+func (l *Loader) run(b *batch) {
+	values, err := l.fetch(b.keys)
+
+	if err != nil {
+		// a failed fetch isn't cached, a later load fetches the key again
+		l.mu.Lock()
+		for _, key := range b.keys {
+			delete(l.cache, key)
+		}
+		l.mu.Unlock()
+	}
+
+	for i, c := range b.calls {
+		c.value, c.err = values[b.keys[i]], err
+		close(c.done)
+	}
+}
@@ -0,0 +1,122 @@
+package dataloader
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder fetches the keys ending with "!" as missing, and records the batches.
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+}
+
+func (r *recorder) fetch(keys []string) (map[string]interface{}, error) {
+	r.mu.Lock()
+	batch := append([]string(nil), keys...)
+	sort.Strings(batch)
+	r.batches = append(r.batches, batch)
+	r.mu.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	values := map[string]interface{}{}
+	for _, k := range keys {
+		if k[len(k)-1] != '!' {
+			values[k] = "value of " + k
+		}
+	}
+
+	return values, nil
+}
+
+func loadAll(l *Loader, keys ...string) ([]interface{}, []error) {
+	values := make([]interface{}, len(keys))
+	errs := make([]error, len(keys))
+
+	var wg sync.WaitGroup
+	for i, k := range keys {
+		wg.Add(1)
+		go func(i int, k string) {
+			defer wg.Done()
+			values[i], errs[i] = l.Load(k)
+		}(i, k)
+	}
+	wg.Wait()
+
+	return values, errs
+}
+
+func TestLoaderBatches(t *testing.T) {
+	r := &recorder{}
+	l := New(r.fetch, 50*time.Millisecond, 100)
+
+	values, errs := loadAll(l, "a", "b", "a", "missing!")
+
+	for i, expect := range []interface{}{"value of a", "value of b", "value of a", nil} {
+		if values[i] != expect || errs[i] != nil {
+			t.Errorf("load %d: got %v %v, expect %v", i, values[i], errs[i], expect)
+		}
+	}
+
+	if len(r.batches) != 1 || len(r.batches[0]) != 3 {
+		t.Fatalf("got batches: %v, expect one batch of the 3 keys", r.batches)
+	}
+
+	// cached values aren't fetched again
+	if v, err := l.Load("b"); v != "value of b" || err != nil {
+		t.Fatalf("got %v %v, expect cached value", v, err)
+	}
+	if len(r.batches) != 1 {
+		t.Fatalf("got batches: %v, expect cached keys not fetched", r.batches)
+	}
+}
+
+func TestLoaderMaxBatch(t *testing.T) {
+	r := &recorder{}
+	l := New(r.fetch, time.Hour, 2)
+
+	// full batches are fetched without waiting
+	if _, errs := loadAll(l, "a", "b", "c", "d"); errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+
+	if len(r.batches) != 2 {
+		t.Fatalf("got batches: %v, expect 2 batches", r.batches)
+	}
+}
+
+func TestLoaderError(t *testing.T) {
+	r := &recorder{err: errors.New("fetch failed")}
+	l := New(r.fetch, time.Millisecond, 100)
+
+	if _, err := l.Load("a"); err != r.err {
+		t.Fatalf("got error: %v, expect: %v", err, r.err)
+	}
+
+	// failed fetches aren't cached
+	r.err = nil
+	if v, err := l.Load("a"); v != "value of a" || err != nil {
+		t.Fatalf("got %v %v, expect value fetched again", v, err)
+	}
+}
+
+func TestLoaderPrime(t *testing.T) {
+	r := &recorder{}
+	l := New(r.fetch, time.Millisecond, 100)
+
+	l.Prime("a", "primed")
+
+	if v, err := l.Load("a"); v != "primed" || err != nil {
+		t.Fatalf("got %v %v, expect primed value", v, err)
+	}
+	if len(r.batches) != 0 {
+		t.Fatalf("got batches: %v, expect primed key not fetched", r.batches)
+	}
+}
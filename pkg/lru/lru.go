@@ -0,0 +1,77 @@
+// Package lru provides a fixed size cache evicting the least recently used entries.
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache is safe for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type entry struct {
+	key   string
+	value interface{}
+}
+
+// New returns a cache of at most size entries, size must be positive.
+func New(size int) *Cache {
+	if size < 1 {
+		panic("lru: size must be positive")
+	}
+
+	return &Cache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// Get returns the value of the key and marks it as the most recently used.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+
+	return e.Value.(*entry).value, true
+}
+
+// Add sets the value of the key, evicting the least recently used entry when the cache
+// is full.
+func (c *Cache) Add(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*entry).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, value: value})
+
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
+
+// Len returns the number of entries.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
@@ -0,0 +1,69 @@
+package lru
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New(2)
+
+	c.Add("a", 1)
+	c.Add("b", 2)
+
+	// a becomes the most recently used, b is evicted
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("got %v %v, expect 1", v, ok)
+	}
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("got b, expect evicted")
+	}
+	for key, expect := range map[string]int{"a": 1, "c": 3} {
+		if v, ok := c.Get(key); !ok || v != expect {
+			t.Fatalf("got %s: %v %v, expect %d", key, v, ok, expect)
+		}
+	}
+
+	if c.Len() != 2 {
+		t.Fatalf("got len: %d, expect 2", c.Len())
+	}
+}
+
+func TestCacheUpdatesValue(t *testing.T) {
+	c := New(2)
+
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Add("a", 10)
+	c.Add("c", 3)
+
+	if v, ok := c.Get("a"); !ok || v != 10 {
+		t.Fatalf("got %v %v, expect updated value kept", v, ok)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("got b, expect evicted")
+	}
+}
+
+func TestCacheConcurrentUse(t *testing.T) {
+	c := New(10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := strconv.Itoa(i % 20)
+			c.Add(key, i)
+			c.Get(key)
+		}(i)
+	}
+	wg.Wait()
+
+	if c.Len() != 10 {
+		t.Fatalf("got len: %d, expect 10", c.Len())
+	}
+}
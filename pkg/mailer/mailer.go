@@ -0,0 +1,97 @@
+// Package mailer queues emails through pluggable implementations.
+package mailer
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Subject  string    `json:"subject"`
+	Body     string    `json:"body"`
+	QueuedAt time.Time `json:"queuedAt"`
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Memory keeps messages in memory, it's intended for tests and development.
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg.QueuedAt = time.Now()
+	m.messages = append(m.messages, msg)
+
+	return nil
+}
+
+// Messages returns a copy of the sent messages.
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
+
+// Last returns the last sent message to the recipient.
+func (m *Memory) Last(to string) (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i], true
+		}
+	}
+
+	return Message{}, false
+}
+
+// File appends messages as JSON lines to an outbox file, to be delivered by another process.
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (f *File) Send(msg Message) error {
+	msg.QueuedAt = time.Now()
+
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,71 @@
+package mailer
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+
+	for _, to := range []string{"a@test.com", "b@test.com", "a@test.com"} {
+		if err := m.Send(Message{To: to, Subject: to}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := len(m.Messages()); got != 3 {
+		t.Fatalf("got %d messages, expect 3", got)
+	}
+
+	t.Run("should return last message of recipient", func(t *testing.T) {
+		msg, ok := m.Last("b@test.com")
+		if !ok {
+			t.Fatal("expect message, got none")
+		}
+
+		if msg.QueuedAt.IsZero() {
+			t.Fatal("expect queued date")
+		}
+	})
+
+	t.Run("should not find message of unknown recipient", func(t *testing.T) {
+		if _, ok := m.Last("c@test.com"); ok {
+			t.Fatal("expect no message")
+		}
+	})
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	m := NewFile(path)
+
+	for _, subject := range []string{"first", "second"} {
+		if err := m.Send(Message{To: "a@test.com", Subject: subject}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var subjects []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		subjects = append(subjects, msg.Subject)
+	}
+
+	if len(subjects) != 2 || subjects[0] != "first" || subjects[1] != "second" {
+		t.Fatalf("got subjects: %v, expect: [first second]", subjects)
+	}
+}
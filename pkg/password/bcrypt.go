@@ -0,0 +1,54 @@
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt hashes passwords with bcrypt, encoded as $2a$<cost>$<salt and key>.
+type Bcrypt struct {
+	Cost int
+}
+
+func (h Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (h Bcrypt) NeedsRehash(encoded string) bool {
+	if !isBcrypt(encoded) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(encoded))
+
+	return err != nil || cost != h.Cost
+}
+
+func verifyBcrypt(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func isBcrypt(encoded string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
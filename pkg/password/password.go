@@ -0,0 +1,36 @@
+// Package password hashes passwords with argon2id or bcrypt. Hashes are encoded with
+// their algorithm and parameters, in the PHC string format for argon2id and in the
+// modular crypt format for bcrypt, so they can be verified after a change of hasher.
+package password
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("password: unknown hash algorithm")
+	ErrInvalidHash      = errors.New("password: invalid encoded hash")
+)
+
+// Hasher creates password hashes, Verify checks a password against the hash of any hasher.
+type Hasher interface {
+	// Hash returns the encoded hash of the password, salted with random bytes.
+	Hash(password string) (string, error)
+	// NeedsRehash checks if an encoded hash was created by another algorithm or with
+	// other parameters, the password should then be hashed again.
+	NeedsRehash(encoded string) bool
+}
+
+// Verify checks if a password matches an encoded hash, whatever the algorithm and
+// parameters used to create it.
+func Verify(password, encoded string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, argon2idPrefix):
+		return verifyArgon2id(password, encoded)
+	case isBcrypt(encoded):
+		return verifyBcrypt(password, encoded)
+	default:
+		return false, ErrUnknownAlgorithm
+	}
+}
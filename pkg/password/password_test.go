@@ -0,0 +1,125 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters keeping the tests fast.
+var (
+	testArgon2id = Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	testBcrypt   = Bcrypt{Cost: bcrypt.MinCost}
+)
+
+func TestVerify(t *testing.T) {
+	for _, h := range []Hasher{testArgon2id, testBcrypt} {
+		encoded, err := h.Hash("Test123!")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			title    string
+			password string
+			expect   bool
+		}{
+			{title: "should accept password", password: "Test123!", expect: true},
+			{title: "should refuse wrong password", password: "Test123?", expect: false},
+		}
+
+		for _, tt := range tests {
+			got, err := Verify(tt.password, encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.expect {
+				t.Errorf("%T %s: got %t, expect %t", h, tt.title, got, tt.expect)
+			}
+		}
+	}
+}
+
+func TestVerifyKnownHash(t *testing.T) {
+	// argon2id hash of "password" with the salt "somesalt", from the reference implementation.
+	const encoded = "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+
+	if ok, err := Verify("password", encoded); err != nil || !ok {
+		t.Fatalf("got: %t %v, expect password verified", ok, err)
+	}
+}
+
+func TestVerifyInvalidHash(t *testing.T) {
+	tests := []struct {
+		title   string
+		encoded string
+		expect  error
+	}{
+		{title: "should refuse unknown algorithm", encoded: "$md5$hash", expect: ErrUnknownAlgorithm},
+		{title: "should refuse truncated argon2id hash", encoded: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA", expect: ErrInvalidHash},
+		{title: "should refuse argon2id hash without parameters", encoded: "$argon2id$v=19$$c2FsdA$a2V5", expect: ErrInvalidHash},
+	}
+
+	for _, tt := range tests {
+		if _, err := Verify("password", tt.encoded); err != tt.expect {
+			t.Errorf("%s: got %v, expect %v", tt.title, err, tt.expect)
+		}
+	}
+}
+
+func TestArgon2idEncoding(t *testing.T) {
+	encoded, err := testArgon2id.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("got hash: %s, expect PHC string with the parameters", encoded)
+	}
+
+	other, err := testArgon2id.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if encoded == other {
+		t.Fatal("hashes of the same password should be salted differently")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon2idHash, err := testArgon2id.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bcryptHash, err := testBcrypt.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stronger := testArgon2id
+	stronger.Iterations++
+
+	tests := []struct {
+		title   string
+		hasher  Hasher
+		encoded string
+		expect  bool
+	}{
+		{title: "should keep argon2id hash of same parameters", hasher: testArgon2id, encoded: argon2idHash, expect: false},
+		{title: "should rehash argon2id hash of other parameters", hasher: stronger, encoded: argon2idHash, expect: true},
+		{title: "should rehash bcrypt hash with argon2id", hasher: testArgon2id, encoded: bcryptHash, expect: true},
+		{title: "should keep bcrypt hash of same cost", hasher: testBcrypt, encoded: bcryptHash, expect: false},
+		{title: "should rehash bcrypt hash of other cost", hasher: Bcrypt{Cost: bcrypt.MinCost + 1}, encoded: bcryptHash, expect: true},
+		{title: "should rehash argon2id hash with bcrypt", hasher: testBcrypt, encoded: argon2idHash, expect: true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.hasher.NeedsRehash(tt.encoded); got != tt.expect {
+			t.Errorf("%s: got %t, expect %t", tt.title, got, tt.expect)
+		}
+	}
+}
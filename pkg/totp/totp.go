@@ -0,0 +1,116 @@
+// Package totp implements the time-based one-time passwords described by RFC 6238,
+// compatible with authenticator applications.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Period is the validity duration of a code.
+	Period = 30 * time.Second
+	// Digits is the length of a code.
+	Digits = 6
+	// Skew is the number of periods accepted before and after the current one,
+	// to tolerate clock drifts between the server and the authenticator.
+	Skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret creates a random base32 encoded secret of 160 bits.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+// Code returns the code of the secret at the given time.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	return code(key, uint64(t.Unix()/int64(Period/time.Second))), nil
+}
+
+// Validate checks the code against the secret at the given time.
+func Validate(passcode string, secret string, t time.Time) bool {
+	_, ok := ValidateStep(passcode, secret, t)
+	return ok
+}
+
+// ValidateStep checks the code against the secret at the given time, and returns the time
+// step of the code. A code stays valid during several steps, callers refuse the codes
+// whose step isn't newer than the last accepted one so codes can't be used twice.
+func ValidateStep(passcode string, secret string, t time.Time) (int64, bool) {
+	if len(passcode) != Digits {
+		return 0, false
+	}
+
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	counter := t.Unix() / int64(Period/time.Second)
+	for i := int64(-Skew); i <= Skew; i++ {
+		if subtle.ConstantTimeCompare([]byte(code(key, uint64(counter+i))), []byte(passcode)) == 1 {
+			return counter + i, true
+		}
+	}
+
+	return 0, false
+}
+
+// ProvisioningURI returns the otpauth URI to encode in a QR code for authenticator applications.
+func ProvisioningURI(issuer string, account string, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(int(Period/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: v.Encode(),
+	}
+
+	return u.String()
+}
+
+// code computes the HOTP value of the counter as described by RFC 4226.
+func code(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%mod)
+}
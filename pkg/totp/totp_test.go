@@ -0,0 +1,119 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// secret of the RFC 6238 SHA1 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// RFC 6238 expected values truncated to 6 digits.
+	tests := []struct {
+		time   int64
+		expect string
+	}{
+		{time: 59, expect: "287082"},
+		{time: 1111111109, expect: "081804"},
+		{time: 1111111111, expect: "050471"},
+		{time: 1234567890, expect: "005924"},
+		{time: 2000000000, expect: "279037"},
+		{time: 20000000000, expect: "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, time.Unix(tt.time, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != tt.expect {
+			t.Errorf("at %d got code: %s, expect: %s", tt.time, got, tt.expect)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	code, err := Code(rfcSecret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		title  string
+		code   string
+		time   time.Time
+		expect bool
+	}{
+		{title: "should accept current code", code: code, time: now, expect: true},
+		{title: "should accept code of previous period", code: code, time: now.Add(Period), expect: true},
+		{title: "should accept code of next period", code: code, time: now.Add(-Period), expect: true},
+		{title: "should refuse outdated code", code: code, time: now.Add(3 * Period), expect: false},
+		{title: "should refuse wrong code", code: "000000", time: now, expect: false},
+		{title: "should refuse code of wrong length", code: "5924", time: now, expect: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := Validate(tt.code, rfcSecret, tt.time); got != tt.expect {
+				t.Fatalf("got: %t, expect: %t", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestValidateStep(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / int64(Period/time.Second)
+
+	code, err := Code(rfcSecret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the step is the one of the code, not the one of the validation time
+	for _, at := range []time.Time{now.Add(-Period), now, now.Add(Period)} {
+		got, ok := ValidateStep(code, rfcSecret, at)
+		if !ok || got != step {
+			t.Fatalf("at %d got step: %d %t, expect: %d", at.Unix(), got, ok, step)
+		}
+	}
+
+	if _, ok := ValidateStep("000000", rfcSecret, now); ok {
+		t.Fatal("expect wrong code refused")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(secret) != 32 {
+		t.Fatalf("got secret length: %d, expect: 32", len(secret))
+	}
+
+	if _, err := Code(secret, time.Now()); err != nil {
+		t.Fatalf("generated secret should be decodable: %s", err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("My App", "test@test.com", "SECRET")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/My%20App:test@test.com?") {
+		t.Fatalf("got unexpected uri: %s", uri)
+	}
+
+	for _, param := range []string{"secret=SECRET", "issuer=My+App", "digits=6", "period=30"} {
+		if !strings.Contains(uri, param) {
+			t.Errorf("uri %s should contain %s", uri, param)
+		}
+	}
+}
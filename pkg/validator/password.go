@@ -0,0 +1,167 @@
+package validator
+
+import (
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// PasswordList tells if a password is part of a list of passwords to refuse.
+type PasswordList interface {
+	Contains(password string) (bool, error)
+}
+
+// PasswordLists checks a password against several lists.
+type PasswordLists []PasswordList
+
+func (ls PasswordLists) Contains(password string) (bool, error) {
+	for _, l := range ls {
+		if found, err := l.Contains(password); err != nil || found {
+			return found, err
+		}
+	}
+
+	return false, nil
+}
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// CommonPasswords is a built-in list of the most used passwords and base words. It ignores
+// the case and the digits and special characters ending a password, since they are the
+// usual way to satisfy a password policy: "Password1!" is found.
+var CommonPasswords PasswordList = commonPasswords(strings.Fields(commonPasswordsFile))
+
+type commonPasswords []string
+
+func (l commonPasswords) Contains(password string) (bool, error) {
+	pass := strings.ToLower(strings.TrimSpace(password))
+	base := strings.TrimRightFunc(pass, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	for _, p := range l {
+		if p == pass || p == base {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// HashFile is a list of breached passwords read from a file of uppercase hexadecimal
+// SHA-1 hashes sorted in ascending order, one per line and optionally followed by
+// ":count", like the "ordered by hash" dump of Have I Been Pwned. The file is searched
+// without being loaded in memory, it can be tens of gigabytes.
+type HashFile struct {
+	file *os.File
+	size int64
+}
+
+// OpenHashFile opens a sorted SHA-1 hash file, see HashFile.
+func OpenHashFile(path string) (*HashFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &HashFile{file: f, size: info.Size()}, nil
+}
+
+func (h *HashFile) Close() error {
+	return h.file.Close()
+}
+
+// Contains binary searches the SHA-1 hash of the password in the file.
+func (h *HashFile) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := []byte(strings.ToUpper(hex.EncodeToString(sum[:])))
+
+	// the line of the target, if any, starts between lo and hi
+	lo, hi := int64(0), h.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, err := h.lineStart(mid)
+		if err != nil {
+			return false, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		line, err := h.readLine(start)
+		if err != nil {
+			return false, err
+		}
+
+		hash := line
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			hash = line[:i]
+		}
+
+		switch bytes.Compare(target, hash) {
+		case 0:
+			return true, nil
+		case 1:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = mid
+		}
+	}
+
+	return false, nil
+}
+
+// hashFileBufferSize holds a whole line of the file, a hash and its count.
+const hashFileBufferSize = 128
+
+// lineStart returns the offset of the first line starting at off or after.
+func (h *HashFile) lineStart(off int64) (int64, error) {
+	if off == 0 {
+		return 0, nil
+	}
+
+	buf := make([]byte, hashFileBufferSize)
+
+	n, err := h.file.ReadAt(buf, off-1)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	i := bytes.IndexByte(buf[:n], '\n')
+	if i < 0 {
+		return h.size, nil
+	}
+
+	return off + int64(i), nil
+}
+
+// readLine returns the line starting at off, without its line ending.
+func (h *HashFile) readLine(off int64) ([]byte, error) {
+	buf := make([]byte, hashFileBufferSize)
+
+	n, err := h.file.ReadAt(buf, off)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	line := buf[:n]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+
+	return bytes.TrimRight(line, "\r"), nil
+}
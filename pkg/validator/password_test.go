@@ -0,0 +1,95 @@
+package validator
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCommonPasswords(t *testing.T) {
+	tests := []struct {
+		password string
+		expect   bool
+	}{
+		{password: "password", expect: true},
+		{password: "Password1!", expect: true},
+		{password: "QWERTY123?", expect: true},
+		{password: "123456789", expect: true},
+		{password: "Tr0ub4dor&3x", expect: false},
+		{password: "correct horse battery staple", expect: false},
+	}
+
+	for _, tt := range tests {
+		got, err := CommonPasswords.Contains(tt.password)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != tt.expect {
+			t.Errorf("%q: got %t, expect %t", tt.password, got, tt.expect)
+		}
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	breached := []string{"Password1!", "hunter2", "Summer2021!", "letmein", "a", "zzz"}
+
+	var lines []string
+	for i, p := range breached {
+		sum := sha1.Sum([]byte(p))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	sort.Strings(lines)
+
+	for _, sep := range []string{"\n", "\r\n"} {
+		path := filepath.Join(t.TempDir(), "hashes.txt")
+		if err := ioutil.WriteFile(path, []byte(strings.Join(lines, sep)+sep), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := OpenHashFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		for _, p := range breached {
+			if found, err := f.Contains(p); err != nil || !found {
+				t.Errorf("%q: got %t %v, expect found", p, found, err)
+			}
+		}
+
+		for _, p := range []string{"Tr0ub4dor&3x", "password1!", ""} {
+			if found, err := f.Contains(p); err != nil || found {
+				t.Errorf("%q: got %t %v, expect not found", p, found, err)
+			}
+		}
+	}
+}
+
+func TestPasswordLists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashes.txt")
+	sum := sha1.Sum([]byte("Tr0ub4dor&3x"))
+	if err := ioutil.WriteFile(path, []byte(strings.ToUpper(hex.EncodeToString(sum[:]))+":3\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenHashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	lists := PasswordLists{CommonPasswords, f}
+
+	for _, p := range []string{"Password1!", "Tr0ub4dor&3x"} {
+		if found, err := lists.Contains(p); err != nil || !found {
+			t.Errorf("%q: got %t %v, expect found", p, found, err)
+		}
+	}
+}
@@ -34,6 +34,8 @@ var (
 		minstr, maxstr := setMinMaxStr(min, max)
 		return regexp.MustCompile(fmt.Sprintf("^(.*?[A-Z]){%s,%s}.*$", minstr, maxstr))
 	}
+	// NameRX matches person names: words of letters separated by a space, a hyphen or an apostrophe.
+	NameRX  = regexp.MustCompile(`^\p{L}+(?:[ '\-]\p{L}+)*$`)
 	EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 )
 
@@ -70,6 +70,23 @@ func TestEmailRX(t *testing.T) {
 	})
 }
 
+func TestNameRX(t *testing.T) {
+	badstr := "John  Doe2"
+	goodstr := "Jean-Éric d'Arc"
+
+	t.Run("shouldn't match", func(t *testing.T) {
+		if NameRX.MatchString(badstr) {
+			t.Fatalf("regex shouldn't match: %s", badstr)
+		}
+	})
+
+	t.Run("should match", func(t *testing.T) {
+		if !NameRX.MatchString(goodstr) {
+			t.Fatalf("regex should match: %s", goodstr)
+		}
+	})
+}
+
 func TestSpecialCharRX(t *testing.T) {
 	badstr := "not special"
 	goodstr := `.!@#$%^&:;<>,./\?()[]{}*~-_+=`